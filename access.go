@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	accessOpen      = ""
+	accessPrivate   = "private"
+	accessModerated = "moderated"
+)
+
+var (
+	btnJoinApprove = tele.Btn{Unique: "join_ok"}
+	btnJoinDeny    = tele.Btn{Unique: "join_no"}
+)
+
+func visibleTo(tag *Tag, userID int64) bool {
+	if tag.Access != accessPrivate {
+		return true
+	}
+	return tag.CreatorID == userID || isModerator(tag, userID) || isSubscribed(tag, userID)
+}
+
+func needsApproval(tag *Tag) bool {
+	return tag.Access == accessPrivate || tag.Access == accessModerated
+}
+
+func hasRequest(tag *Tag, userID int64) bool {
+	for _, r := range tag.Requests {
+		if r.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func removeRequest(tag *Tag, userID int64) bool {
+	reqs := []Subscriber{}
+	for _, r := range tag.Requests {
+		if r.ID != userID {
+			reqs = append(reqs, r)
+		}
+	}
+	removed := len(reqs) != len(tag.Requests)
+	tag.Requests = reqs
+	return removed
+}
+
+func requestJoin(c tele.Context, tag *Tag, user Subscriber) error {
+	if hasRequest(tag, user.ID) {
+		return c.Send(T(c, "join.already_requested"))
+	}
+	tag.Requests = append(tag.Requests, user)
+	saveTag("update", tag)
+
+	payload := fmt.Sprintf("%d|%s|%d", tag.ChatID, tag.Name, user.ID)
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(T(c, "join.approve"), btnJoinApprove.Unique, payload),
+		markup.Data(T(c, "join.deny"), btnJoinDeny.Unique, payload),
+	))
+	text := T(c, "join.request", esc(user.Username), esc(tag.Name))
+	sendQueue.sendThen(c, &tele.User{ID: tag.CreatorID}, text, func(_ *tele.Message, err error) error {
+		if err == nil {
+			return c.Send(T(c, "join.sent", esc(tag.Name)))
+		}
+		slog.Warn("Не удалось отправить запрос создателю тега", "tag", tag.Name, "err", err)
+		if tag.ChatID == 0 {
+			return c.Send(T(c, "join.creator_unreachable"))
+		}
+		sendQueue.sendThen(c, &tele.Chat{ID: tag.ChatID}, text, func(_ *tele.Message, err error) error {
+			if err != nil {
+				return err
+			}
+			return c.Send(T(c, "join.sent", esc(tag.Name)))
+		}, markup)
+		return nil
+	}, markup)
+	return nil
+}
+
+func parseJoinPayload(c tele.Context) (*Tag, int64, bool) {
+	parts := strings.SplitN(c.Callback().Data, "|", 3)
+	if len(parts) != 3 {
+		return nil, 0, false
+	}
+	chatID, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, 0, false
+	}
+	userID, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return nil, 0, false
+	}
+	tag := tagService.Find(chatID, parts[1])
+	if tag == nil {
+		c.Respond(&tele.CallbackResponse{Text: T(c, "tag_not_found_short")})
+		return nil, 0, false
+	}
+	if !canManage(c, tag) {
+		c.Respond(&tele.CallbackResponse{Text: T(c, "join.not_allowed")})
+		return nil, 0, false
+	}
+	return tag, userID, true
+}
+
+func onJoinApprove(c tele.Context) error {
+	tag, userID, ok := parseJoinPayload(c)
+	if !ok {
+		return nil
+	}
+	var user Subscriber
+	for _, r := range tag.Requests {
+		if r.ID == userID {
+			user = r
+		}
+	}
+	if !removeRequest(tag, userID) {
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "join.already_handled")})
+	}
+	if !isSubscribed(tag, userID) {
+		tagService.Subscribe(tag, user)
+		emitEvent(c.Chat().ID, eventSubscriberJoin, tag, &user, "")
+		notifyMembership(traceContext(c), tag, user, true)
+		welcomeSubscriber(traceContext(c), tag, user)
+	}
+	saveTag("subscribe", tag)
+	c.Respond()
+	sendQueue.send(traceContext(c), &tele.User{ID: userID}, T(c, "join.approved_dm", esc(tag.Name)))
+	return c.Edit(T(c, "join.approved", esc(user.Username), esc(tag.Name)))
+}
+
+func onJoinDeny(c tele.Context) error {
+	tag, userID, ok := parseJoinPayload(c)
+	if !ok {
+		return nil
+	}
+	if !removeRequest(tag, userID) {
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "join.already_handled")})
+	}
+	saveTag("update", tag)
+	c.Respond()
+	sendQueue.send(traceContext(c), &tele.User{ID: userID}, T(c, "join.denied_dm", esc(tag.Name)))
+	return c.Edit(T(c, "join.denied", esc(tag.Name)))
+}
+
+func handleAccess(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) < 2 {
+		return c.Send(T(c, "access.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	switch strings.ToLower(args[1]) {
+	case "open":
+		tag.Access = accessOpen
+	case accessModerated:
+		tag.Access = accessModerated
+	case accessPrivate:
+		tag.Access = accessPrivate
+	default:
+		return c.Send(T(c, "access.bad_mode"))
+	}
+	if tag.Access == accessOpen {
+		for _, r := range tag.Requests {
+			if !isSubscribed(tag, r.ID) {
+				tagService.Subscribe(tag, r)
+				emitEvent(c.Chat().ID, eventSubscriberJoin, tag, &r, "")
+				notifyMembership(traceContext(c), tag, r, true)
+				welcomeSubscriber(traceContext(c), tag, r)
+			}
+		}
+		tag.Requests = nil
+	}
+	saveTag("update", tag)
+	switch tag.Access {
+	case accessPrivate:
+		return c.Send(T(c, "access.private", esc(tag.Name)))
+	case accessModerated:
+		return c.Send(T(c, "access.moderated", esc(tag.Name)))
+	}
+	return c.Send(T(c, "access.open", esc(tag.Name)))
+}
+
+func handleInvite(c tele.Context) error {
+	tag, user, err := parseTagAndUser(c, T(c, "invite.usage"))
+	if tag == nil {
+		return err
+	}
+	if isBanned(tag, user.ID) {
+		return c.Send(T(c, "invite.banned"))
+	}
+	if isSubscribed(tag, user.ID) {
+		return c.Send(T(c, "invite.already"))
+	}
+	removeRequest(tag, user.ID)
+	tagService.Subscribe(tag, user)
+	saveTag("subscribe", tag)
+	emitEvent(c.Chat().ID, eventSubscriberJoin, tag, &user, "")
+	notifyMembership(traceContext(c), tag, user, true)
+	welcomeSubscriber(traceContext(c), tag, user)
+	return c.Send(T(c, "invite.done", esc(user.Username), esc(tag.Name)))
+}
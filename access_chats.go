@@ -0,0 +1,107 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	ownerID      int64
+	allowedChats map[int64]bool
+)
+
+func isBotOwner(c tele.Context) bool {
+	return ownerID != 0 && c.Sender() != nil && c.Sender().ID == ownerID
+}
+
+func isDenied(chatID int64) bool {
+	for _, id := range data.DeniedChats {
+		if id == chatID {
+			return true
+		}
+	}
+	return false
+}
+
+func chatAllowed(chat *tele.Chat) bool {
+	if chat == nil || chat.Type == tele.ChatPrivate {
+		return true
+	}
+	if isDenied(chat.ID) {
+		return false
+	}
+	return allowedChats == nil || allowedChats[chat.ID]
+}
+
+func leaveIfUnauthorized(c tele.Context) bool {
+	chat := c.Chat()
+	if chatAllowed(chat) {
+		return false
+	}
+	slog.Info("🚪 Покидаю неразрешённый чат", "chat_id", chat.ID, "title", chat.Title)
+	if err := c.Bot().Leave(chat); err != nil {
+		slog.Warn("Не удалось покинуть чат", "chat_id", chat.ID, "err", err)
+	}
+	return true
+}
+
+func chatGuard(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if leaveIfUnauthorized(c) {
+			return nil
+		}
+		return next(c)
+	}
+}
+
+func onAddedToGroup(c tele.Context) error {
+	if leaveIfUnauthorized(c) {
+		return nil
+	}
+	slog.Info("➕ Меня добавили в чат", "chat_id", c.Chat().ID, "title", c.Chat().Title)
+	readdChat(c.Chat().ID)
+	return sendWelcome(c)
+}
+
+func parseChatArg(c tele.Context) (int64, bool) {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return 0, false
+	}
+	id, err := strconv.ParseInt(args[0], 10, 64)
+	return id, err == nil
+}
+
+func handleDenyChat(c tele.Context) error {
+	chatID, ok := parseChatArg(c)
+	if !ok {
+		return c.Send(T(c, "denychat.usage"))
+	}
+	if !isDenied(chatID) {
+		data.DeniedChats = append(data.DeniedChats, chatID)
+		saveData()
+	}
+	if err := c.Bot().Leave(&tele.Chat{ID: chatID}); err != nil {
+		slog.Warn("Не удалось покинуть чат", "chat_id", chatID, "err", err)
+	}
+	return c.Send(T(c, "denychat.done", chatID))
+}
+
+func handleAllowChat(c tele.Context) error {
+	chatID, ok := parseChatArg(c)
+	if !ok {
+		return c.Send(T(c, "allowchat.usage"))
+	}
+	kept := []int64{}
+	for _, id := range data.DeniedChats {
+		if id != chatID {
+			kept = append(kept, id)
+		}
+	}
+	data.DeniedChats = kept
+	saveData()
+	return c.Send(T(c, "allowchat.done", chatID))
+}
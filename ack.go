@@ -0,0 +1,149 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	nudgeDelay = 10 * time.Minute
+
+	btnAck = tele.Btn{Unique: "ack"}
+)
+
+func ackMarkup(c tele.Context, tags ...*Tag) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	rows := []tele.Row{markup.Row(markup.Data(T(c, "ack.button"), btnAck.Unique))}
+	if len(tags) > 0 {
+		rows = append(rows, snoozeRow(c, markup, tags))
+	}
+	markup.Inline(rows...)
+	return markup
+}
+
+func isSubscribed(tag *Tag, userID int64) bool {
+	for _, sub := range tag.Subscribers {
+		if sub.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func onAckButton(c tele.Context) error {
+	msg := c.Message()
+	if msg == nil {
+		return c.Respond()
+	}
+	userID := c.Sender().ID
+	subscribed, recorded := false, false
+	for _, tag := range data.Tags {
+		ping := tag.LastPing
+		if ping == nil || !ping.Matches(msg.Chat.ID, msg.ID) {
+			continue
+		}
+		if !isSubscribed(tag, userID) {
+			continue
+		}
+		subscribed = true
+		if !ping.Acked(userID) {
+			ping.Acks = append(ping.Acks, userID)
+			recorded = true
+		}
+	}
+	if !subscribed {
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "ack.not_subscribed")})
+	}
+	if recorded {
+		saveStats()
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "ack.recorded")})
+	}
+	return c.Respond(&tele.CallbackResponse{Text: T(c, "ack.already")})
+}
+
+func handleAck(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "ack.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	ping := tag.LastPing
+	if ping == nil {
+		return c.Send(T(c, "ack.never_pinged"))
+	}
+	if ping.PingerID != c.Sender().ID && tag.CreatorID != c.Sender().ID {
+		return c.Send(T(c, "ack.denied"))
+	}
+	var here, silent []string
+	for _, sub := range tag.Subscribers {
+		if ping.Acked(sub.ID) {
+			here = append(here, esc(sub.Username))
+		} else {
+			silent = append(silent, esc(sub.Username))
+		}
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "ack.header", esc(tag.Name), ping.At.In(zoneOf(c)).Format("02.01 15:04")))
+	b.WriteString(T(c, "ack.here", len(here), joinOrDash(here)))
+	b.WriteString(T(c, "ack.silent", len(silent), joinOrDash(silent)))
+	return c.Send(b.String())
+}
+
+func joinOrDash(items []string) string {
+	if len(items) == 0 {
+		return "—"
+	}
+	return strings.Join(items, " ")
+}
+
+func handleNudge(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "nudge.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	ping := tag.LastPing
+	if ping == nil || ping.ChatID != c.Chat().ID {
+		return c.Send(T(c, "nudge.never_pinged"))
+	}
+	if ping.PingerID != c.Sender().ID && tag.CreatorID != c.Sender().ID {
+		return c.Send(T(c, "nudge.denied"))
+	}
+	last := ping.At
+	if ping.NudgedAt.After(last) {
+		last = ping.NudgedAt
+	}
+	if wait := time.Until(last.Add(nudgeDelay)); wait > 0 {
+		return c.Send(T(c, "nudge.wait", int(wait.Minutes())+1))
+	}
+	var mentions []string
+	for _, sub := range tag.Subscribers {
+		if !ping.Acked(sub.ID) {
+			mentions = append(mentions, mentionHTML(sub))
+		}
+	}
+	if len(mentions) == 0 {
+		return c.Send(T(c, "nudge.all_acked"))
+	}
+	text := T(c, "nudge.text", strings.Join(mentions, " "), esc(tag.Name))
+	opts := sendOptions(c)
+	opts.ReplyMarkup = ackMarkup(c, tag)
+	sendQueue.sendThen(c, c.Chat(), text, func(msg *tele.Message, err error) error {
+		if err != nil {
+			return err
+		}
+		ping.NudgeIDs = append(ping.NudgeIDs, msg.ID)
+		ping.NudgedAt = time.Now()
+		saveStats()
+		return nil
+	}, opts)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	tele "gopkg.in/telebot.v3"
+)
+
+var adminCacheTTL = 10 * time.Minute
+
+type adminEntry struct {
+	ids     map[int64]bool
+	fetched time.Time
+}
+
+var (
+	adminMu    sync.Mutex
+	adminCache = map[int64]adminEntry{}
+)
+
+func chatAdmins(ctx context.Context, b *tele.Bot, chat *tele.Chat) (map[int64]bool, error) {
+	adminMu.Lock()
+	entry, ok := adminCache[chat.ID]
+	adminMu.Unlock()
+	if ok && time.Since(entry.fetched) < adminCacheTTL {
+		return entry.ids, nil
+	}
+	_, span := tracer.Start(ctx, "admins.fetch", trace.WithAttributes(attribute.Int64("chat_id", chat.ID)))
+	admins, err := b.AdminsOf(chat)
+	endSpan(span, err)
+	span.End()
+	if err != nil {
+		return nil, err
+	}
+	ids := make(map[int64]bool, len(admins))
+	for _, admin := range admins {
+		ids[admin.User.ID] = true
+	}
+	adminMu.Lock()
+	adminCache[chat.ID] = adminEntry{ids: ids, fetched: time.Now()}
+	adminMu.Unlock()
+	return ids, nil
+}
+
+func invalidateAdmins(chatID int64) {
+	adminMu.Lock()
+	delete(adminCache, chatID)
+	adminMu.Unlock()
+}
+
+func isAdminRole(m *tele.ChatMember) bool {
+	return m != nil && (m.Role == tele.Creator || m.Role == tele.Administrator)
+}
+
+func onChatMemberUpdate(c tele.Context) error {
+	upd := c.ChatMember()
+	if upd == nil || upd.Chat == nil {
+		return nil
+	}
+	if isAdminRole(upd.OldChatMember) != isAdminRole(upd.NewChatMember) {
+		invalidateAdmins(upd.Chat.ID)
+	}
+	onBotMembership(c, upd)
+	return nil
+}
@@ -0,0 +1,364 @@
+package main
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+type apiTag struct {
+	Name        string     `json:"name"`
+	ChatID      int64      `json:"chat_id"`
+	Description string     `json:"description"`
+	Category    string     `json:"category,omitempty"`
+	Access      string     `json:"access,omitempty"`
+	Silent      bool       `json:"silent"`
+	PingPolicy  string     `json:"ping_policy"`
+	Subscribers int        `json:"subscribers"`
+	Mentions    int        `json:"mentions"`
+	LastUsed    *time.Time `json:"last_used,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+type apiError struct {
+	Error string `json:"error"`
+}
+
+var apiOwnerToken string
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newToken() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func toAPITag(tag *Tag) apiTag {
+	out := apiTag{
+		Name:        tag.Name,
+		ChatID:      tag.ChatID,
+		Description: tag.Description,
+		Category:    tag.Category,
+		Access:      tag.Access,
+		Silent:      tag.Silent,
+		PingPolicy:  policyName(tag.PingPolicy),
+		Subscribers: len(tag.Subscribers),
+		Mentions:    tag.Stats.Mentions,
+		CreatedAt:   tag.CreatedAt,
+	}
+	if !tag.Stats.LastUsed.IsZero() {
+		out.LastUsed = &tag.Stats.LastUsed
+	}
+	return out
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, apiError{Error: msg})
+}
+
+func bearerToken(r *http.Request) string {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		return ""
+	}
+	return token
+}
+
+func apiAuthorized(r *http.Request, chatID int64, webAdmin bool) bool {
+	token := bearerToken(r)
+	if token == "" {
+		return webAdmin
+	}
+	if apiOwnerToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(apiOwnerToken)) == 1 {
+		return true
+	}
+	s := data.Chats[chatID]
+	return s != nil && s.APITokenHash != "" &&
+		subtle.ConstantTimeCompare([]byte(hashToken(token)), []byte(s.APITokenHash)) == 1
+}
+
+func apiActor(r *http.Request, chatID int64) int64 {
+	if session, ok := sessionOf(r); ok && r.Header.Get("Authorization") == "" {
+		return session.UserID
+	}
+	if s := data.Chats[chatID]; s != nil {
+		return s.APITokenBy
+	}
+	return 0
+}
+
+func chatTags(chatID int64) []*Tag {
+	if chatID < 0 {
+		return tagService.InChat(chatID)
+	}
+	var tags []*Tag
+	for _, tag := range tagService.Chat(chatID) {
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func chatTag(chatID int64, name string) *Tag {
+	if chatID < 0 {
+		return tagService.Find(chatID, name)
+	}
+	return tagService.Local(chatID, name)
+}
+
+type apiHandler func(w http.ResponseWriter, r *http.Request, chatID int64)
+
+func apiRoute(h apiHandler) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		chatID, err := strconv.ParseInt(r.PathValue("chat"), 10, 64)
+		if err != nil {
+			writeError(w, http.StatusBadRequest, "bad chat id")
+			return
+		}
+		webAdmin := false
+		if bearerToken(r) == "" {
+			session, ok := sessionOf(r)
+			webAdmin = ok && isWebAdmin(r.Context(), session, chatID)
+		}
+		dataMu.Lock()
+		defer dataMu.Unlock()
+		if err := loadChats(chatID); err != nil {
+			writeError(w, http.StatusInternalServerError, "storage error")
+			return
+		}
+		if !apiAuthorized(r, chatID, webAdmin) {
+			writeError(w, http.StatusUnauthorized, "unauthorized")
+			return
+		}
+		if readOnly && r.Method != http.MethodGet {
+			writeError(w, http.StatusServiceUnavailable, "read-only mode")
+			return
+		}
+		h(w, r, chatID)
+	}
+}
+
+func apiListTags(w http.ResponseWriter, r *http.Request, chatID int64) {
+	out := []apiTag{}
+	for _, tag := range chatTags(chatID) {
+		out = append(out, toAPITag(tag))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+func apiCreateTag(w http.ResponseWriter, r *http.Request, chatID int64) {
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+		Category    string `json:"category"`
+		PingPolicy  string `json:"ping_policy"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil ||
+		req.Name == "" || strings.ContainsAny(req.Name, " \t\n#:") {
+		writeError(w, http.StatusBadRequest, "bad tag name")
+		return
+	}
+	if chatTag(chatID, req.Name) != nil {
+		writeError(w, http.StatusConflict, "tag exists")
+		return
+	}
+	if blockedWord(chatID, req.Name, req.Category, req.Description) != "" {
+		writeError(w, http.StatusUnprocessableEntity, "blocked word")
+		return
+	}
+	if req.PingPolicy == "anyone" {
+		req.PingPolicy = pingAnyone
+	}
+	if !validPingPolicy(req.PingPolicy) {
+		writeError(w, http.StatusBadRequest, "bad ping policy")
+		return
+	}
+	if existing := findCategory(chatID, req.Category); existing != "" {
+		req.Category = existing
+	}
+	tag := &Tag{
+		Name:        req.Name,
+		ChatID:      chatID,
+		CreatorID:   apiActor(r, chatID),
+		Description: req.Description,
+		Category:    req.Category,
+		PingPolicy:  req.PingPolicy,
+		Subscribers: []Subscriber{},
+		CreatedAt:   time.Now(),
+	}
+	data.Tags = append(data.Tags, tag)
+	tagService.Add(tag)
+	saveTag("create", tag)
+	emitEvent(chatID, eventTagCreated, tag, nil, tag.Description)
+	writeJSON(w, http.StatusCreated, toAPITag(tag))
+}
+
+func apiDeleteTag(w http.ResponseWriter, r *http.Request, chatID int64) {
+	tag := chatTag(chatID, r.PathValue("name"))
+	if tag == nil {
+		writeError(w, http.StatusNotFound, "tag not found")
+		return
+	}
+	notifySubscribers(r.Context(), chatID, tag, apiActor(r, chatID),
+		tr(chatLang(chatID), "farewell.deleted", esc(tag.Name), int(deletedRetention.Hours()/24)))
+	now := time.Now()
+	tagService.Remove(tag)
+	tag.DeletedAt = &now
+	tag.DeletedBy = apiActor(r, chatID)
+	saveAction("delete", tag, tag.DeletedBy, nil)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiListSubscribers(w http.ResponseWriter, r *http.Request, chatID int64) {
+	tag := chatTag(chatID, r.PathValue("name"))
+	if tag == nil {
+		writeError(w, http.StatusNotFound, "tag not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, tag.Subscribers)
+}
+
+func apiAddSubscriber(w http.ResponseWriter, r *http.Request, chatID int64) {
+	tag := chatTag(chatID, r.PathValue("name"))
+	if tag == nil {
+		writeError(w, http.StatusNotFound, "tag not found")
+		return
+	}
+	var sub Subscriber
+	if err := json.NewDecoder(r.Body).Decode(&sub); err != nil || sub.ID == 0 {
+		writeError(w, http.StatusBadRequest, "bad subscriber")
+		return
+	}
+	if isBanned(tag, sub.ID) {
+		writeError(w, http.StatusForbidden, "user is banned")
+		return
+	}
+	if isSubscribed(tag, sub.ID) {
+		writeError(w, http.StatusConflict, "already subscribed")
+		return
+	}
+	if sub.Username == "" {
+		sub.Username = fmt.Sprintf("User%d", sub.ID)
+	}
+	sub = Subscriber{ID: sub.ID, Username: sub.Username}
+	tagService.Subscribe(tag, sub)
+	saveTag("subscribe", tag)
+	emitEvent(chatID, eventSubscriberJoin, tag, &sub, "")
+	notifyMembership(r.Context(), tag, sub, true)
+	welcomeSubscriber(r.Context(), tag, sub)
+	writeJSON(w, http.StatusCreated, tag.Subscribers)
+}
+
+func apiRemoveSubscriber(w http.ResponseWriter, r *http.Request, chatID int64) {
+	tag := chatTag(chatID, r.PathValue("name"))
+	if tag == nil {
+		writeError(w, http.StatusNotFound, "tag not found")
+		return
+	}
+	userID, err := strconv.ParseInt(r.PathValue("user"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad user id")
+		return
+	}
+	sub, ok := subscriberOf(tag, userID)
+	if !ok || !tagService.Unsubscribe(tag, userID) {
+		writeError(w, http.StatusNotFound, "not subscribed")
+		return
+	}
+	saveAction("unsubscribe", tag, apiActor(r, chatID), &sub)
+	notifyMembership(r.Context(), tag, sub, false)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiStats(w http.ResponseWriter, r *http.Request, chatID int64) {
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	loc := chatLocation(chatID)
+	week := map[string]int{}
+	byDay := map[string]int{}
+	for _, ev := range data.Mentions {
+		if ev.ChatID == chatID && ev.At.After(since) {
+			week[ev.Tag]++
+			byDay[ev.At.In(loc).Format("2006-01-02")]++
+		}
+	}
+	tags := chatTags(chatID)
+	subs := 0
+	for _, tag := range tags {
+		subs += len(tag.Subscribers)
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"tags":          len(tags),
+		"subscriptions": subs,
+		"mentions_week": week,
+		"mentions_days": byDay,
+	})
+}
+
+func apiRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /api/chats/{chat}/tags", apiRoute(apiListTags))
+	mux.HandleFunc("POST /api/chats/{chat}/tags", apiRoute(apiCreateTag))
+	mux.HandleFunc("DELETE /api/chats/{chat}/tags/{name}", apiRoute(apiDeleteTag))
+	mux.HandleFunc("GET /api/chats/{chat}/tags/{name}/subscribers", apiRoute(apiListSubscribers))
+	mux.HandleFunc("POST /api/chats/{chat}/tags/{name}/subscribers", apiRoute(apiAddSubscriber))
+	mux.HandleFunc("DELETE /api/chats/{chat}/tags/{name}/subscribers/{user}", apiRoute(apiRemoveSubscriber))
+	mux.HandleFunc("GET /api/chats/{chat}/stats", apiRoute(apiStats))
+}
+
+func startAPIServer(b *tele.Bot) {
+	addr := cfg.HTTP.APIAddr
+	if addr == "" {
+		return
+	}
+	apiOwnerToken = cfg.HTTP.APIToken
+	webBot, botToken = b, b.Token
+	mux := http.NewServeMux()
+	apiRoutes(mux)
+	webRoutes(mux)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			slog.Error("HTTP API остановлен", "addr", addr, "err", err)
+		}
+	}()
+	slog.Info("🌐 HTTP API и панель управления доступны", "addr", addr)
+}
+
+func handleAPIToken(c tele.Context) error {
+	if c.Chat().Type != tele.ChatPrivate && !isChatAdmin(c, c.Chat().ID) {
+		return c.Send(T(c, "apitoken.denied"))
+	}
+	token := newToken()
+	sendQueue.sendThen(c, c.Sender(), T(c, "apitoken.dm", c.Chat().ID, token), func(_ *tele.Message, err error) error {
+		if err != nil {
+			return c.Send(T(c, "dm_failed"))
+		}
+		s := chatSettings(c.Chat().ID)
+		s.APITokenHash = hashToken(token)
+		s.APITokenBy = c.Sender().ID
+		saveData()
+		if c.Chat().Type == tele.ChatPrivate {
+			return nil
+		}
+		return c.Send(T(c, "apitoken.sent"))
+	})
+	return nil
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const emptyGrace = time.Hour
+
+var archiveRetention = 30 * 24 * time.Hour
+
+func archiveEmptyTags(b *tele.Bot) {
+	now := time.Now()
+	changed := false
+	for _, tag := range data.Tags {
+		if !tag.Active() || tag.KeepEmpty || len(tag.Subscribers) > 0 || now.Sub(tag.CreatedAt) < emptyGrace {
+			continue
+		}
+		tagService.Remove(tag)
+		tag.ArchivedAt = &now
+		journalTag("archive", tag)
+		notifyCreator(b, tag, tr(chatLang(tag.ChatID), "archive.empty", esc(tag.Name), int(archiveRetention.Hours()/24)))
+		changed = true
+	}
+	if changed {
+		saveData()
+	}
+}
+
+func purgeArchivedTags(b *tele.Bot) {
+	cutoff := time.Now().Add(-archiveRetention)
+	kept := []*Tag{}
+	for _, tag := range data.Tags {
+		if tag.ArchivedAt == nil || tag.DeletedAt != nil || tag.ArchivedAt.After(cutoff) {
+			kept = append(kept, tag)
+		} else {
+			journalTag("purge", tag)
+		}
+	}
+	if len(kept) != len(data.Tags) {
+		data.Tags = kept
+		saveData()
+	}
+}
+
+func archivedIn(chatID int64) []*Tag {
+	var tags []*Tag
+	for _, tag := range data.Tags {
+		if tag.ArchivedAt != nil && tag.DeletedAt == nil && (tag.ChatID == chatID || (chatID < 0 && tag.ChatID == 0)) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+	return tags
+}
+
+func listArchived(c tele.Context) error {
+	var b strings.Builder
+	for _, tag := range archivedIn(c.Chat().ID) {
+		if !visibleTo(tag, c.Sender().ID) {
+			continue
+		}
+		purge := tag.ArchivedAt.Add(archiveRetention).In(zoneOf(c)).Format("02.01.2006")
+		b.WriteString(T(c, "archive.line", esc(tag.Name), len(tag.Subscribers), purge))
+	}
+	if b.Len() == 0 {
+		return c.Send(T(c, "archive.none"))
+	}
+	return c.Send(T(c, "archive.header") + b.String() + T(c, "archive.footer"))
+}
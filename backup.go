@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+const (
+	backupPrefix    = "tags-"
+	encryptedSuffix = ".enc"
+)
+
+var (
+	backupDir       = "backups"
+	backupInterval  = 24 * time.Hour
+	backupRetention = 14 * 24 * time.Hour
+)
+
+func encodeBackup() ([]byte, error) {
+	full, err := allData()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	err = json.NewEncoder(zw).Encode(full)
+	if closeErr := zw.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func writeBackup(raw []byte) (string, int64, error) {
+	if err := os.MkdirAll(backupDir, 0755); err != nil {
+		return "", 0, err
+	}
+	name := backupPrefix + time.Now().UTC().Format("20060102-150405") + ".json.gz"
+	if storage.Encrypting() {
+		name += encryptedSuffix
+	}
+	path := filepath.Join(backupDir, name)
+	file, err := storage.Seal(raw)
+	if err != nil {
+		return "", 0, err
+	}
+	if err := os.WriteFile(path, file, 0644); err != nil {
+		os.Remove(path)
+		return "", 0, err
+	}
+	pruneBackups()
+	return path, int64(len(file)), nil
+}
+
+func pruneBackups() {
+	entries, err := os.ReadDir(backupDir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-backupRetention)
+	var names []string
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), backupPrefix) && (strings.HasSuffix(e.Name(), ".json.gz") || strings.HasSuffix(e.Name(), ".json.gz"+encryptedSuffix)) {
+			names = append(names, e.Name())
+		}
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		if i == len(names)-1 {
+			break
+		}
+		info, err := os.Stat(filepath.Join(backupDir, name))
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(backupDir, name)); err != nil {
+			slog.Warn("Не удалось удалить старую копию", "file", name, "err", err)
+		}
+	}
+}
+
+func runBackup(b *tele.Bot) {
+	raw, err := encodeBackup()
+	if err != nil {
+		slog.Error("Не удалось создать резервную копию", "err", err)
+		return
+	}
+	go func() {
+		path, size, err := writeBackup(raw)
+		if err != nil {
+			slog.Error("Не удалось создать резервную копию", "err", err)
+			return
+		}
+		slog.Info("💾 Резервная копия создана", "file", path, "bytes", size)
+		uploadBackup(path)
+	}()
+}
+
+func handleBackupNow(c tele.Context) error {
+	raw, err := encodeBackup()
+	if err != nil {
+		return c.Send(T(c, "backup.failed", esc(err.Error())))
+	}
+	ctx, to, lang := traceContext(c), c.Recipient(), langOf(c)
+	go func() {
+		path, size, err := writeBackup(raw)
+		if err != nil {
+			sendQueue.send(ctx, to, tr(lang, "backup.failed", esc(err.Error())))
+			return
+		}
+		sendQueue.send(ctx, to, tr(lang, "backup.done", esc(path), size/1024))
+		uploadBackup(path)
+	}()
+	return nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+const s3UploadTimeout = 5 * time.Minute
+
+var (
+	s3Client *minio.Client
+	s3Bucket string
+	s3Prefix string
+)
+
+func loadS3Config() error {
+	s3 := cfg.Backup.S3
+	if s3.Endpoint == "" || s3.Bucket == "" {
+		return nil
+	}
+	s3Bucket, s3Prefix = s3.Bucket, s3.Prefix
+	client, err := minio.New(s3.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(s3.AccessKey, s3.SecretKey, ""),
+		Secure: !s3.Insecure,
+		Region: s3.Region,
+	})
+	if err != nil {
+		return err
+	}
+	s3Client = client
+	return nil
+}
+
+func uploadBackup(file string) {
+	if s3Client == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3UploadTimeout)
+	defer cancel()
+	key := path.Join(s3Prefix, filepath.Base(file))
+	contentType := "application/gzip"
+	if strings.HasSuffix(file, encryptedSuffix) {
+		contentType = "application/octet-stream"
+	}
+	_, err := s3Client.FPutObject(ctx, s3Bucket, key, file, minio.PutObjectOptions{ContentType: contentType})
+	if err != nil {
+		slog.Error("Не удалось выгрузить резервную копию в S3", "bucket", s3Bucket, "key", key, "err", err)
+		return
+	}
+	slog.Info("☁️ Резервная копия выгружена в S3", "bucket", s3Bucket, "key", key)
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+func BenchmarkFindTag(b *testing.B) {
+	setupHarness(b)
+	g := newLoadGen(1, 100, 1000, 5, 10000)
+	chatID := g.chat()
+	names := g.tags[chatID]
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if tagService.Find(chatID, names[i%len(names)]) == nil {
+			b.Fatal("tag not found")
+		}
+	}
+}
+
+func BenchmarkFindTagMiss(b *testing.B) {
+	setupHarness(b)
+	g := newLoadGen(1, 100, 1000, 5, 10000)
+	chatID := g.chat()
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tagService.Find(chatID, "missing")
+	}
+}
+
+func BenchmarkRebuildIndex(b *testing.B) {
+	setupHarness(b)
+	newLoadGen(1, 100, 100, 20, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		tagService.Rebuild()
+	}
+}
+
+func BenchmarkMentionPipeline(b *testing.B) {
+	bot := setupHarness(b)
+	g := newLoadGen(2, 20, 500, 30, 5000)
+	msgs := make([]*tele.Message, 1024)
+	for i := range msgs {
+		msgs[i] = g.message(3)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c := bot.NewContext(tele.Update{Message: msgs[i%len(msgs)]})
+		if err := pingHashtags(c, map[string]bool{}); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMentionPipelineParallel(b *testing.B) {
+	bot := setupHarness(b)
+	g := newLoadGen(3, 50, 200, 30, 5000)
+	msgs := make([]*tele.Message, 4096)
+	for i := range msgs {
+		msgs[i] = g.message(3)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := 0
+		for pb.Next() {
+			c := bot.NewContext(tele.Update{Message: msgs[i%len(msgs)]})
+			i++
+			dataMu.Lock()
+			err := pingHashtags(c, map[string]bool{})
+			dataMu.Unlock()
+			if err != nil {
+				b.Error(err)
+				return
+			}
+		}
+	})
+}
+
+func BenchmarkRecordMention(b *testing.B) {
+	bot := setupHarness(b)
+	g := newLoadGen(4, 10, 100, 5, 1000)
+	msg := g.message(0)
+	tag := tagService.Find(msg.Chat.ID, g.tagName(msg.Chat.ID))
+	c := bot.NewContext(tele.Update{Message: msg})
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		recordMention(tag, c)
+	}
+}
+
+func BenchmarkSnapshotData(b *testing.B) {
+	setupHarness(b)
+	newLoadGen(5, 100, 100, 20, 10000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := snapshotData(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSaveFile(b *testing.B) {
+	setupHarness(b)
+	newLoadGen(6, 100, 100, 20, 10000)
+	s := storage.NewFile(filepath.Join(b.TempDir(), "tags.json"))
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := s.Save(&data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkSaveShards(b *testing.B) {
+	setupHarness(b)
+	g := newLoadGen(7, 100, 100, 20, 10000)
+	dir := b.TempDir()
+	s, err := storage.OpenShards(filepath.Join(dir, "shards"), filepath.Join(dir, "tags.json"), 0)
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := s.Save(&data); err != nil {
+		b.Fatal(err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		chatID := g.chat()
+		tagService.Find(chatID, g.tagName(chatID)).Stats.Mentions++
+		if err := s.Save(&data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
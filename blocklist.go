@@ -0,0 +1,98 @@
+package main
+
+import (
+	"bufio"
+	"log/slog"
+	"os"
+	"sort"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var blockedWords []string
+
+func loadBlocklist() {
+	path := cfg.Blocklist
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return
+	}
+	if err != nil {
+		slog.Warn("Не удалось прочитать список запрещённых слов", "path", path, "err", err)
+		return
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		word := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if word != "" && !strings.HasPrefix(word, "#") {
+			blockedWords = append(blockedWords, word)
+		}
+	}
+	slog.Info("🚫 Загружен список запрещённых слов", "path", path, "words", len(blockedWords))
+}
+
+func blockedWord(chatID int64, texts ...string) string {
+	words := blockedWords
+	if s := data.Chats[chatID]; s != nil {
+		words = append(words[:len(words):len(words)], s.BlockedWords...)
+	}
+	for _, text := range texts {
+		text = strings.ToLower(text)
+		for _, word := range words {
+			if strings.Contains(text, word) {
+				return word
+			}
+		}
+	}
+	return ""
+}
+
+func handleBlockWord(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 || strings.ToLower(args[0]) == "list" {
+		var words []string
+		if s := data.Chats[c.Chat().ID]; s != nil {
+			words = s.BlockedWords
+		}
+		if len(words) == 0 {
+			return c.Send(T(c, "blockword.empty"))
+		}
+		return c.Send(T(c, "blockword.list", esc(strings.Join(words, ", "))))
+	}
+	if !canAdminChat(c) {
+		return c.Send(T(c, "blockword.denied"))
+	}
+	if len(args) < 2 {
+		return c.Send(T(c, "blockword.usage"))
+	}
+	s := chatSettings(c.Chat().ID)
+	word := strings.ToLower(strings.Join(args[1:], " "))
+	switch strings.ToLower(args[0]) {
+	case "add":
+		for _, w := range s.BlockedWords {
+			if w == word {
+				return c.Send(T(c, "blockword.added", esc(word)))
+			}
+		}
+		s.BlockedWords = append(s.BlockedWords, word)
+		sort.Strings(s.BlockedWords)
+		saveData()
+		return c.Send(T(c, "blockword.added", esc(word)))
+	case "del":
+		kept := s.BlockedWords[:0]
+		for _, w := range s.BlockedWords {
+			if w != word {
+				kept = append(kept, w)
+			}
+		}
+		if len(kept) == len(s.BlockedWords) {
+			return c.Send(T(c, "blockword.not_found", esc(word)))
+		}
+		s.BlockedWords = kept
+		saveData()
+		return c.Send(T(c, "blockword.removed", esc(word)))
+	}
+	return c.Send(T(c, "blockword.usage"))
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"sort"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const noCategory = ""
+
+func splitCategory(arg string) (category, name string) {
+	if i := strings.Index(arg, ":"); i > 0 {
+		return strings.TrimSpace(arg[:i]), arg[i+1:]
+	}
+	return "", arg
+}
+
+func findCategory(chatID int64, name string) string {
+	name = strings.ToLower(name)
+	for _, tag := range tagService.InChat(chatID) {
+		if strings.ToLower(tag.Category) == name {
+			return tag.Category
+		}
+	}
+	return ""
+}
+
+func groupByCategory(tags []*Tag) ([]string, map[string][]*Tag) {
+	groups := map[string][]*Tag{}
+	for _, tag := range tags {
+		cat := tag.Category
+		if cat == "" {
+			cat = noCategory
+		}
+		groups[cat] = append(groups[cat], tag)
+	}
+	names := make([]string, 0, len(groups))
+	for cat := range groups {
+		if cat != noCategory {
+			names = append(names, cat)
+		}
+	}
+	sort.Slice(names, func(i, j int) bool {
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	if _, ok := groups[noCategory]; ok {
+		names = append(names, noCategory)
+	}
+	return names, groups
+}
+
+func handleCategory(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "category.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	category := strings.Join(args[1:], " ")
+	if existing := findCategory(tag.ChatID, category); existing != "" {
+		category = existing
+	}
+	tag.Category = category
+	saveTag("update", tag)
+	if category == "" {
+		return c.Send(T(c, "category.removed", esc(tag.Name)))
+	}
+	return c.Send(T(c, "category.set", esc(tag.Name), esc(category)))
+}
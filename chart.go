@@ -0,0 +1,199 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"image/color"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/fogleman/gg"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	chartWidth   = 900
+	chartHeight  = 450
+	chartDays    = 30
+	subsKeepDays = 90
+)
+
+var (
+	chartFontOnce sync.Once
+	chartFont     *opentype.Font
+
+	chartInk  = color.RGBA{0x33, 0x33, 0x33, 0xff}
+	chartGrid = color.RGBA{0xe0, 0xe0, 0xe0, 0xff}
+	chartFill = color.RGBA{0x4c, 0x8b, 0xf5, 0xff}
+)
+
+func chartFace(size float64) font.Face {
+	chartFontOnce.Do(func() {
+		chartFont, _ = opentype.Parse(goregular.TTF)
+	})
+	face, err := opentype.NewFace(chartFont, &opentype.FaceOptions{Size: size, DPI: 72, Hinting: font.HintingFull})
+	if err != nil {
+		return nil
+	}
+	return face
+}
+
+func niceCeil(v float64) float64 {
+	if v <= 5 {
+		return 5
+	}
+	step := math.Pow(10, math.Floor(math.Log10(v)))
+	for _, m := range []float64{1, 2, 2.5, 5, 10} {
+		if m*step >= v {
+			return m * step
+		}
+	}
+	return 10 * step
+}
+
+func renderChart(title string, labels []string, values []int, line bool) ([]byte, error) {
+	const left, right, top, bottom = 60.0, 20.0, 50.0, 50.0
+	dc := gg.NewContext(chartWidth, chartHeight)
+	dc.SetColor(color.White)
+	dc.Clear()
+	if face := chartFace(20); face != nil {
+		dc.SetFontFace(face)
+	}
+	dc.SetColor(chartInk)
+	dc.DrawStringAnchored(title, chartWidth/2, top/2, 0.5, 0.5)
+
+	peak := 0
+	for _, v := range values {
+		peak = max(peak, v)
+	}
+	ceil := niceCeil(float64(peak))
+	plotW, plotH := chartWidth-left-right, chartHeight-top-bottom
+	y := func(v float64) float64 { return top + plotH - v/ceil*plotH }
+
+	if face := chartFace(13); face != nil {
+		dc.SetFontFace(face)
+	}
+	dc.SetLineWidth(1)
+	for i := 0; i <= 5; i++ {
+		v := ceil * float64(i) / 5
+		dc.SetColor(chartGrid)
+		dc.DrawLine(left, y(v), chartWidth-right, y(v))
+		dc.Stroke()
+		dc.SetColor(chartInk)
+		dc.DrawStringAnchored(fmt.Sprintf("%g", v), left-8, y(v), 1, 0.5)
+	}
+
+	n := len(values)
+	slot := plotW / float64(max(n, 1))
+	every := max(1, n/10)
+	dc.SetColor(chartFill)
+	for i, v := range values {
+		x := left + slot*float64(i) + slot/2
+		if line {
+			if i > 0 {
+				dc.DrawLine(x-slot, y(float64(values[i-1])), x, y(float64(v)))
+				dc.SetLineWidth(3)
+				dc.Stroke()
+			}
+			dc.DrawCircle(x, y(float64(v)), 3.5)
+			dc.Fill()
+		} else if v > 0 {
+			dc.DrawRectangle(x-slot*0.35, y(float64(v)), slot*0.7, float64(v)/ceil*plotH)
+			dc.Fill()
+		}
+	}
+	dc.SetColor(chartInk)
+	for i, label := range labels {
+		if (n-1-i)%every == 0 {
+			dc.DrawStringAnchored(label, left+slot*float64(i)+slot/2, chartHeight-bottom+18, 0.5, 0.5)
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := dc.EncodePNG(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func mentionsPerDay(chatID int64, now time.Time) ([]string, []int) {
+	loc := chatLocation(chatID)
+	today := now.In(loc)
+	labels := make([]string, chartDays)
+	values := make([]int, chartDays)
+	index := map[string]int{}
+	for i := 0; i < chartDays; i++ {
+		day := today.AddDate(0, 0, i-chartDays+1)
+		labels[i] = day.Format("02.01")
+		index[day.Format("2006-01-02")] = i
+	}
+	for _, ev := range data.Mentions {
+		if ev.ChatID != chatID {
+			continue
+		}
+		if i, ok := index[ev.At.In(loc).Format("2006-01-02")]; ok {
+			values[i]++
+		}
+	}
+	return labels, values
+}
+
+func recordSubscriberHistory(b *tele.Bot) {
+	for chatID, s := range data.Chats {
+		if chatID >= 0 || s.RemovedAt != nil {
+			continue
+		}
+		day := time.Now().In(chatLocation(chatID)).Format("2006-01-02")
+		n := len(s.SubsHistory)
+		var count int
+		switch {
+		case chatResident(chatID):
+			count = chatSubscriptions(chatID)
+		case n > 0:
+			count = s.SubsHistory[n-1].Count
+		default:
+			continue
+		}
+		if n > 0 && s.SubsHistory[n-1].Day == day {
+			s.SubsHistory[n-1].Count = count
+		} else {
+			s.SubsHistory = append(s.SubsHistory, SubsPoint{Day: day, Count: count})
+		}
+		if len(s.SubsHistory) > subsKeepDays {
+			s.SubsHistory = s.SubsHistory[len(s.SubsHistory)-subsKeepDays:]
+		}
+	}
+	saveData()
+}
+
+func statsCharts(c tele.Context) error {
+	chatID := c.Chat().ID
+	labels, values := mentionsPerDay(chatID, time.Now())
+	png, err := renderChart(T(c, "stats.chart_mentions", chartDays), labels, values, false)
+	if err != nil {
+		return err
+	}
+	album := tele.Album{&tele.Photo{File: tele.FromReader(bytes.NewReader(png)), Caption: T(c, "stats.chart_caption")}}
+
+	if s := data.Chats[chatID]; s != nil && len(s.SubsHistory) > 1 {
+		labels, values = nil, nil
+		for _, p := range s.SubsHistory {
+			day, _ := time.Parse("2006-01-02", p.Day)
+			labels = append(labels, day.Format("02.01"))
+			values = append(values, p.Count)
+		}
+		png, err := renderChart(T(c, "stats.chart_subs"), labels, values, true)
+		if err != nil {
+			return err
+		}
+		album = append(album, &tele.Photo{File: tele.FromReader(bytes.NewReader(png))})
+	}
+	if len(album) == 1 {
+		return c.Send(album[0], sendOptions(c))
+	}
+	return c.SendAlbum(album, sendOptions(c))
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func onlyHashtags(text string) bool {
+	return strings.TrimSpace(hashtagPattern.ReplaceAllString(text, "")) == ""
+}
+
+func deleteTrigger(c tele.Context) {
+	s := data.Chats[c.Chat().ID]
+	if s == nil || !s.DeleteTriggers || !onlyHashtags(c.Text()) {
+		return
+	}
+	go func() {
+		if err := c.Delete(); err != nil {
+			slog.Warn("Не удалось удалить сообщение с тегом", "chat_id", c.Chat().ID, "message_id", c.Message().ID, "err", err)
+		}
+	}()
+}
+
+func handleCleanTrigger(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "cleantrigger.groups_only"))
+	}
+	args := strings.Fields(c.Text())[1:]
+	s := chatSettings(c.Chat().ID)
+	if len(args) == 0 {
+		return c.Send(T(c, "cleantrigger.current", onOff(c, s.DeleteTriggers)))
+	}
+	switch strings.ToLower(args[0]) {
+	case "on":
+		s.DeleteTriggers = true
+	case "off":
+		s.DeleteTriggers = false
+	default:
+		return c.Send(T(c, "cleantrigger.usage"))
+	}
+	saveData()
+	if s.DeleteTriggers {
+		return c.Send(T(c, "cleantrigger.enabled"))
+	}
+	return c.Send(T(c, "cleantrigger.disabled"))
+}
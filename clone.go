@@ -0,0 +1,100 @@
+package main
+
+import (
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const cloneSubsFlag = "--subs"
+
+func isAdminOf(c tele.Context, chatID int64) bool {
+	if isBotOwner(c) {
+		return true
+	}
+	admins, err := chatAdmins(traceContext(c), c.Bot(), &tele.Chat{ID: chatID})
+	return err == nil && admins[c.Sender().ID]
+}
+
+func isMemberOf(b *tele.Bot, chatID, userID int64) bool {
+	member, err := b.ChatMemberOf(&tele.Chat{ID: chatID}, &tele.User{ID: userID})
+	if err != nil {
+		return false
+	}
+	return member.Role != tele.Left && member.Role != tele.Kicked
+}
+
+func handleClone(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "clone.groups_only"))
+	}
+	args, withSubs := withoutFlag(strings.Fields(c.Text())[1:], cloneSubsFlag)
+	if len(args) != 2 {
+		return c.Send(T(c, "clone.usage"))
+	}
+	target, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil || target >= 0 {
+		return c.Send(T(c, "clone.usage"))
+	}
+	source := tagService.Find(c.Chat().ID, strings.TrimPrefix(args[0], "#"))
+	if source == nil {
+		return tagNotFound(c, strings.TrimPrefix(args[0], "#"))
+	}
+	if target == c.Chat().ID {
+		return c.Send(T(c, "clone.same_chat"))
+	}
+	s := data.Chats[target]
+	if s == nil || s.RemovedAt != nil || !chatAllowed(&tele.Chat{ID: target, Type: tele.ChatSuperGroup}) {
+		return c.Send(T(c, "clone.unknown_chat", target))
+	}
+	if !isAdminOf(c, target) {
+		return c.Send(T(c, "clone.denied_target", esc(s.Title)))
+	}
+	if err := loadChats(target); err != nil {
+		return err
+	}
+	if tagService.Find(target, source.Name) != nil {
+		return c.Send(T(c, "clone.exists", esc(source.Name), esc(s.Title)))
+	}
+	if blockedWord(target, source.Name, source.Category, source.Description) != "" {
+		return c.Send(T(c, "blockword.rejected"))
+	}
+
+	tag := &Tag{
+		Name:        source.Name,
+		ChatID:      target,
+		CreatorID:   c.Sender().ID,
+		CreatorName: c.Sender().Username,
+		Description: source.Description,
+		Category:    source.Category,
+		Access:      source.Access,
+		Silent:      source.Silent,
+		PingPolicy:  source.PingPolicy,
+		Subscribers: []Subscriber{},
+		CreatedAt:   time.Now(),
+	}
+	if withSubs {
+		for _, sub := range source.Subscribers {
+			if isMemberOf(c.Bot(), target, sub.ID) {
+				tag.Subscribers = append(tag.Subscribers, sub)
+			}
+		}
+	}
+	data.Tags = append(data.Tags, tag)
+	tagService.Add(tag)
+	saveTag("create", tag)
+	emitEvent(target, eventTagCreated, tag, &Subscriber{ID: c.Sender().ID, Username: c.Sender().Username}, tag.Description)
+	sendQueue.send(traceContext(c), &tele.Chat{ID: target}, tr(chatLang(target), "clone.notice", esc(tag.Name), esc(c.Chat().Title), len(tag.Subscribers)))
+	slog.Info("Тег скопирован в другой чат", "tag", tag.Name, "from", c.Chat().ID, "to", target, "subscribers", len(tag.Subscribers))
+
+	text := T(c, "clone.done", esc(tag.Name), esc(s.Title))
+	if withSubs {
+		text += T(c, "clone.subs", len(tag.Subscribers), len(source.Subscribers))
+	} else {
+		text += T(c, "clone.no_subs", cloneSubsFlag)
+	}
+	return c.Send(text)
+}
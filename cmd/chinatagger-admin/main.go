@@ -0,0 +1,211 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+
+	"tagger/storage"
+)
+
+const usage = `Usage: chinatagger-admin [flags] <command> [args]
+
+Commands:
+  list [chatID]        list tags, grouped by chat
+  remove-user <userID> erase a user's data, as /forgetme does
+  reindex              repair what validate reports as safely fixable
+  migrate              upgrade the data file to the current schema
+  validate             check the data store for integrity problems
+
+The bot must be stopped while any command runs: loading the store can migrate
+and rewrite it.
+
+Flags:
+`
+
+func main() {
+	dataFile := flag.String("data", "tags.json", "path to the JSON data file or per-chat shard directory")
+	databaseURL := flag.String("db", os.Getenv("DATABASE_URL"), "PostgreSQL URL (overrides -data)")
+	journalFile := flag.String("journal", "journal.jsonl", "journal to replay on load (empty to skip)")
+	dataKey := flag.String("key", os.Getenv("DATA_KEY"), "secret the data store is encrypted with")
+	flag.Usage = func() {
+		fmt.Fprint(os.Stderr, usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+
+	if *databaseURL == "" {
+		lock, err := storage.AcquireLock(*dataFile)
+		if err != nil {
+			fail(fmt.Errorf("%w: stop the bot first", err))
+		}
+		defer lock.Release()
+	}
+	if err := storage.UseKey(*dataKey); err != nil {
+		fail(err)
+	}
+	store, err := storage.Open(*databaseURL, *dataFile)
+	if err != nil {
+		fail(err)
+	}
+	defer store.Close()
+	if pg, ok := store.(*storage.Postgres); ok {
+		if err := pg.Lock(false); err != nil {
+			fail(fmt.Errorf("%w: stop the bot first", err))
+		}
+	}
+	var data storage.Data
+	if err := store.Load(&data); err != nil {
+		fail(err)
+	}
+	if lazy, ok := store.(storage.Lazy); ok {
+		if err := lazy.LoadAll(&data); err != nil {
+			fail(err)
+		}
+	}
+	replayed, err := replay(&data, *journalFile)
+	if err != nil {
+		fail(err)
+	}
+
+	args := flag.Args()[1:]
+	changed := false
+	switch flag.Arg(0) {
+	case "list":
+		err = list(&data, args)
+	case "remove-user":
+		changed, err = removeUser(&data, args)
+		changed = changed || replayed > 0
+	case "reindex":
+		changed = reindex(&data) > 0 || replayed > 0
+	case "migrate":
+		fmt.Printf("schema version %d\n", data.SchemaVersion)
+		changed = true
+	case "validate":
+		if problems := validate(&data); len(problems) > 0 {
+			for _, p := range problems {
+				fmt.Println(p)
+			}
+			os.Exit(1)
+		}
+		fmt.Println("ok")
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		fail(err)
+	}
+	if changed {
+		if err := store.Save(&data); err != nil {
+			fail(err)
+		}
+		if err := store.SaveStats(storage.StatsOf(&data)); err != nil {
+			fail(err)
+		}
+		if *journalFile != "" && replayed > 0 {
+			os.Remove(*journalFile)
+		}
+	}
+}
+
+func fail(err error) {
+	fmt.Fprintln(os.Stderr, "error:", err)
+	os.Exit(1)
+}
+
+func replay(data *storage.Data, path string) (int, error) {
+	if path == "" {
+		return 0, nil
+	}
+	entries, err := storage.ReadJournal(path)
+	if err != nil {
+		return 0, err
+	}
+	replayed := 0
+	for _, entry := range entries {
+		if entry.Seq > data.JournalSeq {
+			storage.ApplyJournal(data, entry)
+			data.JournalSeq = entry.Seq
+			replayed++
+		}
+	}
+	return replayed, nil
+}
+
+func list(data *storage.Data, args []string) error {
+	var only *int64
+	if len(args) > 0 {
+		id, err := strconv.ParseInt(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("bad chat ID %q", args[0])
+		}
+		only = &id
+	}
+	byChat := map[int64][]*storage.Tag{}
+	for _, tag := range data.Tags {
+		if only == nil || tag.ChatID == *only {
+			byChat[tag.ChatID] = append(byChat[tag.ChatID], tag)
+		}
+	}
+	chats := make([]int64, 0, len(byChat))
+	for id := range byChat {
+		chats = append(chats, id)
+	}
+	sort.Slice(chats, func(i, j int) bool { return chats[i] < chats[j] })
+	for _, id := range chats {
+		title := ""
+		if s := data.Chats[id]; s != nil && s.Title != "" {
+			title = " " + s.Title
+		}
+		fmt.Printf("chat %d%s\n", id, title)
+		for _, tag := range byChat[id] {
+			state := ""
+			switch {
+			case tag.DeletedAt != nil:
+				state = " [deleted]"
+			case tag.ArchivedAt != nil:
+				state = " [archived]"
+			}
+			fmt.Printf("  #%s%s — %d subscribers, %d mentions\n", tag.Name, state, len(tag.Subscribers), tag.Stats.Mentions)
+		}
+	}
+	return nil
+}
+
+func removeUser(data *storage.Data, args []string) (bool, error) {
+	if len(args) == 0 {
+		return false, fmt.Errorf("remove-user needs a user ID")
+	}
+	userID, err := strconv.ParseInt(args[0], 10, 64)
+	if err != nil {
+		return false, fmt.Errorf("bad user ID %q", args[0])
+	}
+	res := storage.ForgetUser(data, userID, "", nil)
+	fmt.Printf("removed user %d from %d tags (%d subscriptions, %d tags transferred, %d anonymized)\n",
+		userID, res.Tags, res.Subscriptions, res.Transferred, res.Anonymized)
+	return true, nil
+}
+
+func reindex(data *storage.Data) int {
+	fixed, problems := storage.Verify(data, true)
+	for _, msg := range fixed {
+		fmt.Println("fixed:", msg)
+	}
+	for _, msg := range problems {
+		fmt.Println("needs attention:", msg)
+	}
+	fmt.Printf("%d fixes\n", len(fixed))
+	return len(fixed)
+}
+
+func validate(data *storage.Data) []string {
+	_, problems := storage.Verify(data, false)
+	return problems
+}
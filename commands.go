@@ -0,0 +1,128 @@
+package main
+
+import (
+	"log/slog"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+type commandInfo struct {
+	name   string
+	manage bool
+	owner  bool
+}
+
+var commandList = []commandInfo{
+	{name: "start"},
+	{name: "ct"},
+	{name: "st"},
+	{name: "lt"},
+	{name: "mt"},
+	{name: "info"},
+	{name: "ping"},
+	{name: "stats"},
+	{name: "trending"},
+	{name: "top"},
+	{name: "history"},
+	{name: "ack"},
+	{name: "nudge"},
+	{name: "notifyme"},
+	{name: "lang"},
+	{name: "tz"},
+	{name: "prefs"},
+	{name: "summary"},
+	{name: "menu"},
+	{name: "mydata"},
+	{name: "forgetme"},
+	{name: "et", manage: true},
+	{name: "rt", manage: true},
+	{name: "dt", manage: true},
+	{name: "restore", manage: true},
+	{name: "clone", manage: true},
+	{name: "global", manage: true},
+	{name: "undo"},
+	{name: "preview"},
+	{name: "event"},
+	{name: "poll"},
+	{name: "tagfrompoll"},
+	{name: "export", manage: true},
+	{name: "import", manage: true},
+	{name: "apitoken", manage: true},
+	{name: "webhook", manage: true},
+	{name: "cat", manage: true},
+	{name: "silent", manage: true},
+	{name: "pingperm", manage: true},
+	{name: "template", manage: true},
+	{name: "setwelcome", manage: true},
+	{name: "antispam", manage: true},
+	{name: "weekly", manage: true},
+	{name: "blockword", manage: true},
+	{name: "tagcap", manage: true},
+	{name: "hashtags", manage: true},
+	{name: "cleantrigger", manage: true},
+	{name: "directory", manage: true},
+	{name: "rule", manage: true},
+	{name: "watchmembers", manage: true},
+	{name: "topic", manage: true},
+	{name: "access", manage: true},
+	{name: "invite", manage: true},
+	{name: "addmod", manage: true},
+	{name: "delmod", manage: true},
+	{name: "kickfrom", manage: true},
+	{name: "banfrom", manage: true},
+	{name: "unbanfrom", manage: true},
+	{name: "chats", owner: true},
+	{name: "leavechat", owner: true},
+	{name: "globalstats", owner: true},
+	{name: "broadcast", owner: true},
+	{name: "denychat", owner: true},
+	{name: "allowchat", owner: true},
+	{name: "readonly", owner: true},
+	{name: "backupnow", owner: true},
+	{name: "reload", owner: true},
+	{name: "version", owner: true},
+	{name: "uptime", owner: true},
+	{name: "botstats", owner: true},
+	{name: "link", owner: true},
+	{name: "unlink", owner: true},
+	{name: "communities", owner: true},
+}
+
+func buildCommands(lang string, withManage, withOwner bool) []tele.Command {
+	var cmds []tele.Command
+	for _, info := range commandList {
+		if (info.manage && !withManage) || (info.owner && !withOwner) {
+			continue
+		}
+		cmds = append(cmds, tele.Command{Text: info.name, Description: tr(lang, "cmd."+info.name)})
+	}
+	return cmds
+}
+
+func registerCommands(b *tele.Bot) {
+	type scopeInfo struct {
+		scope  tele.CommandScope
+		manage bool
+		owner  bool
+	}
+	scopes := []scopeInfo{
+		{tele.CommandScope{Type: tele.CommandScopeDefault}, false, false},
+		{tele.CommandScope{Type: tele.CommandScopeAllGroupChats}, false, false},
+		{tele.CommandScope{Type: tele.CommandScopeAllChatAdmin}, true, false},
+		{tele.CommandScope{Type: tele.CommandScopeAllPrivateChats}, true, false},
+	}
+	if ownerID != 0 {
+		scopes = append(scopes, scopeInfo{tele.CommandScope{Type: tele.CommandScopeChat, ChatID: ownerID}, true, true})
+	}
+	for _, s := range scopes {
+		for _, lang := range languages() {
+			code := lang
+			if lang == defaultLang {
+				code = ""
+			}
+			if err := b.SetCommands(buildCommands(lang, s.manage, s.owner), s.scope, code); err != nil {
+				slog.Warn("Не удалось зарегистрировать команды", "scope", s.scope.Type, "lang", lang, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func communityOf(chatID int64) string {
+	if s := data.Chats[chatID]; s != nil {
+		return s.Community
+	}
+	return ""
+}
+
+func handleLink(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) < 2 {
+		return c.Send(T(c, "community.link_usage"))
+	}
+	name := strings.ToLower(args[0])
+	var linked []string
+	for _, arg := range args[1:] {
+		id, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || id >= 0 || data.Chats[id] == nil {
+			return c.Send(T(c, "community.unknown_chat", esc(arg)))
+		}
+		if other := communityOf(id); other != "" && other != name {
+			return c.Send(T(c, "community.already", id, esc(other)))
+		}
+		linked = append(linked, fmt.Sprintf("%s (<code>%d</code>)", esc(data.Chats[id].Title), id))
+	}
+	for _, arg := range args[1:] {
+		id, _ := strconv.ParseInt(arg, 10, 64)
+		chatSettings(id).Community = name
+	}
+	tagService.Relink()
+	saveData()
+	return c.Send(T(c, "community.linked", esc(name), strings.Join(linked, "\n")))
+}
+
+func handleUnlink(c tele.Context) error {
+	chatID, ok := parseChatArg(c)
+	if !ok {
+		return c.Send(T(c, "community.unlink_usage"))
+	}
+	name := communityOf(chatID)
+	if name == "" {
+		return c.Send(T(c, "community.not_linked", chatID))
+	}
+	data.Chats[chatID].Community = ""
+	tagService.Relink()
+	saveData()
+	return c.Send(T(c, "community.unlinked", chatID, esc(name)))
+}
+
+func handleCommunities(c tele.Context) error {
+	members := map[string][]int64{}
+	for id, s := range data.Chats {
+		if s.Community != "" {
+			members[s.Community] = append(members[s.Community], id)
+		}
+	}
+	if len(members) == 0 {
+		return c.Send(T(c, "community.none"))
+	}
+	names := make([]string, 0, len(members))
+	for name := range members {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var b strings.Builder
+	b.WriteString(T(c, "community.header"))
+	for _, name := range names {
+		global := 0
+		b.WriteString(fmt.Sprintf("\n🔗 <b>%s</b>\n", esc(name)))
+		for _, id := range members[name] {
+			for _, tag := range tagService.Chat(id) {
+				if tag.Global {
+					global++
+				}
+			}
+			b.WriteString(fmt.Sprintf("  %s <code>%d</code>\n", esc(data.Chats[id].Title), id))
+		}
+		b.WriteString(T(c, "community.global_count", global))
+	}
+	return c.Send(b.String())
+}
+
+func handleGlobal(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 || len(args) > 2 {
+		return c.Send(T(c, "global.usage"))
+	}
+	chatID := c.Chat().ID
+	if communityOf(chatID) == "" {
+		return c.Send(T(c, "global.not_linked"))
+	}
+	tag := tagService.Local(chatID, strings.TrimPrefix(args[0], "#"))
+	if tag == nil {
+		return c.Send(T(c, "tag_not_found"))
+	}
+	on := !tag.Global
+	if len(args) == 2 {
+		switch strings.ToLower(args[1]) {
+		case "on":
+			on = true
+		case "off":
+			on = false
+		default:
+			return c.Send(T(c, "global.usage"))
+		}
+	}
+	if on {
+		for _, peer := range tagService.Linked(chatID) {
+			if tagService.Local(peer, tag.Name) != nil {
+				return c.Send(T(c, "global.conflict", esc(tag.Name), esc(data.Chats[peer].Title)))
+			}
+		}
+	}
+	tag.Global = on
+	saveTag("update", tag)
+	if on {
+		return c.Send(T(c, "global.on", esc(tag.Name), esc(communityOf(chatID))))
+	}
+	return c.Send(T(c, "global.off", esc(tag.Name)))
+}
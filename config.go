@@ -0,0 +1,327 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const defaultConfigFile = "config.yaml"
+
+type Config struct {
+	Token        string  `yaml:"token" env:"TELEGRAM_BOT_TOKEN"`
+	OwnerID      int64   `yaml:"owner_id" env:"BOT_OWNER_ID"`
+	AllowedChats []int64 `yaml:"allowed_chats" env:"ALLOWED_CHAT_IDS"`
+	ErrorsChatID int64   `yaml:"errors_chat_id" env:"ERRORS_CHAT_ID"`
+	LogLevel     string  `yaml:"log_level" env:"LOG_LEVEL"`
+	RequestLog   string  `yaml:"request_log_level" env:"REQUEST_LOG_LEVEL"`
+	ReadOnly     bool    `yaml:"read_only" env:"READ_ONLY"`
+	Blocklist    string  `yaml:"blocklist_file" env:"BLOCKLIST_FILE"`
+
+	Locales   localesConfig   `yaml:"locales"`
+	Storage   storageConfig   `yaml:"storage"`
+	Limits    limitsConfig    `yaml:"limits"`
+	Webhooks  webhooksConfig  `yaml:"webhooks"`
+	Backup    backupConfig    `yaml:"backup"`
+	HTTP      httpConfig      `yaml:"http"`
+	Telemetry telemetryConfig `yaml:"telemetry"`
+
+	File string `yaml:"-"`
+}
+
+type localesConfig struct {
+	Default string `yaml:"default" env:"DEFAULT_LANG"`
+	Dir     string `yaml:"dir" env:"LOCALES_DIR"`
+}
+
+type storageConfig struct {
+	File        string `yaml:"file" env:"DATA_FILE"`
+	Dir         string `yaml:"dir" env:"DATA_DIR"`
+	DatabaseURL string `yaml:"database_url" env:"DATABASE_URL"`
+	Key         string `yaml:"key" env:"DATA_KEY"`
+	ShardCache  int    `yaml:"shard_cache" env:"SHARD_CACHE"`
+	Journal     string `yaml:"journal" env:"JOURNAL_FILE"`
+	RedisURL    string `yaml:"redis_url" env:"REDIS_URL"`
+	StatsFlush  int    `yaml:"stats_flush_seconds" env:"STATS_FLUSH_SECONDS"`
+}
+
+type limitsConfig struct {
+	NudgeDelayMinutes      int `yaml:"nudge_delay_minutes" env:"NUDGE_DELAY_MINUTES"`
+	StaleTagDays           int `yaml:"stale_tag_days" env:"STALE_TAG_DAYS"`
+	StaleGraceDays         int `yaml:"stale_grace_days" env:"STALE_GRACE_DAYS"`
+	DeleteConfirmThreshold int `yaml:"delete_confirm_threshold" env:"DELETE_CONFIRM_THRESHOLD"`
+	DigestHours            int `yaml:"digest_hours" env:"DIGEST_HOURS"`
+	AdminCacheMinutes      int `yaml:"admin_cache_minutes" env:"ADMIN_CACHE_MINUTES"`
+	CreateCooldownSeconds  int `yaml:"create_cooldown_seconds" env:"CREATE_COOLDOWN_SECONDS"`
+	CreateDailyCap         int `yaml:"create_daily_cap" env:"CREATE_DAILY_CAP"`
+	RemovedChatGraceDays   int `yaml:"removed_chat_grace_days" env:"REMOVED_CHAT_GRACE_DAYS"`
+	ArchiveRetentionDays   int `yaml:"archive_retention_days" env:"ARCHIVE_RETENTION_DAYS"`
+	ReactionWindowHours    int `yaml:"reaction_window_hours" env:"REACTION_WINDOW_HOURS"`
+	SpamPings              int `yaml:"spam_pings" env:"SPAM_PINGS"`
+	SpamWindowMinutes      int `yaml:"spam_window_minutes" env:"SPAM_WINDOW_MINUTES"`
+	SpamMuteMinutes        int `yaml:"spam_mute_minutes" env:"SPAM_MUTE_MINUTES"`
+	UserCommandsPerMinute  int `yaml:"user_commands_per_minute" env:"USER_COMMANDS_PER_MINUTE"`
+}
+
+type webhooksConfig struct {
+	Attempts       int `yaml:"attempts" env:"WEBHOOK_ATTEMPTS"`
+	TimeoutSeconds int `yaml:"timeout_seconds" env:"WEBHOOK_TIMEOUT_SECONDS"`
+}
+
+type backupConfig struct {
+	Dir           string `yaml:"dir" env:"BACKUP_DIR"`
+	Hours         int    `yaml:"hours" env:"BACKUP_HOURS"`
+	RetentionDays int    `yaml:"retention_days" env:"BACKUP_RETENTION_DAYS"`
+	S3            struct {
+		Endpoint  string `yaml:"endpoint" env:"BACKUP_S3_ENDPOINT"`
+		Bucket    string `yaml:"bucket" env:"BACKUP_S3_BUCKET"`
+		Prefix    string `yaml:"prefix" env:"BACKUP_S3_PREFIX"`
+		AccessKey string `yaml:"access_key" env:"BACKUP_S3_ACCESS_KEY"`
+		SecretKey string `yaml:"secret_key" env:"BACKUP_S3_SECRET_KEY"`
+		Region    string `yaml:"region" env:"BACKUP_S3_REGION"`
+		Insecure  bool   `yaml:"insecure" env:"BACKUP_S3_INSECURE"`
+	} `yaml:"s3"`
+}
+
+type httpConfig struct {
+	HealthAddr string `yaml:"health_addr" env:"HEALTH_ADDR"`
+	APIAddr    string `yaml:"api_addr" env:"API_ADDR"`
+	APIToken   string `yaml:"api_token" env:"API_TOKEN"`
+	DebugAddr  string `yaml:"debug_addr" env:"DEBUG_ADDR"`
+}
+
+type telemetryConfig struct {
+	SentryDSN         string `yaml:"sentry_dsn" env:"SENTRY_DSN"`
+	SentryEnvironment string `yaml:"sentry_environment" env:"SENTRY_ENVIRONMENT"`
+	SentryRelease     string `yaml:"sentry_release" env:"SENTRY_RELEASE"`
+	OTLPEndpoint      string `yaml:"otlp_endpoint" env:"OTEL_EXPORTER_OTLP_ENDPOINT"`
+	OTLPHeaders       string `yaml:"otlp_headers" env:"OTEL_EXPORTER_OTLP_HEADERS"`
+	ServiceName       string `yaml:"service_name" env:"OTEL_SERVICE_NAME"`
+}
+
+var cfg = defaultConfig()
+
+func defaultConfig() Config {
+	c := Config{
+		Blocklist: "blocklist.txt",
+		Locales:   localesConfig{Default: "ru"},
+		Storage:   storageConfig{File: "tags.json", ShardCache: 256, Journal: "journal.jsonl", StatsFlush: 60},
+		Limits: limitsConfig{
+			NudgeDelayMinutes:      10,
+			StaleTagDays:           30,
+			StaleGraceDays:         7,
+			DeleteConfirmThreshold: 20,
+			DigestHours:            4,
+			AdminCacheMinutes:      10,
+			CreateCooldownSeconds:  30,
+			CreateDailyCap:         20,
+			RemovedChatGraceDays:   30,
+			ArchiveRetentionDays:   30,
+			ReactionWindowHours:    24,
+			SpamPings:              10,
+			SpamWindowMinutes:      5,
+			SpamMuteMinutes:        30,
+			UserCommandsPerMinute:  20,
+		},
+		Webhooks:  webhooksConfig{Attempts: 3, TimeoutSeconds: 10},
+		Telemetry: telemetryConfig{ServiceName: "chinatagger"},
+	}
+	c.Backup.Dir = "backups"
+	c.Backup.Hours = 24
+	c.Backup.RetentionDays = 14
+	return c
+}
+
+func loadConfig() (Config, error) {
+	_ = godotenv.Load()
+	c := defaultConfig()
+	path, explicit := os.LookupEnv("CONFIG_FILE")
+	if !explicit {
+		path = defaultConfigFile
+	}
+	raw, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		dec := yaml.NewDecoder(bytes.NewReader(raw))
+		dec.KnownFields(true)
+		if err := dec.Decode(&c); err != nil && !errors.Is(err, io.EOF) {
+			return c, fmt.Errorf("%s: %w", path, err)
+		}
+		c.File = path
+	case explicit || !os.IsNotExist(err):
+		return c, err
+	}
+	var problems []string
+	walkConfig(reflect.ValueOf(&c).Elem(), "", func(field reflect.Value, name, env string) {
+		value, ok := os.LookupEnv(env)
+		if !ok {
+			return
+		}
+		if err := setField(field, value); err != nil {
+			problems = append(problems, fmt.Sprintf("%s: %v", env, err))
+		}
+	})
+	problems = append(problems, c.validate()...)
+	if len(problems) > 0 {
+		return c, fmt.Errorf("invalid configuration:\n  - %s", strings.Join(problems, "\n  - "))
+	}
+	return c, nil
+}
+
+func walkConfig(v reflect.Value, prefix string, fn func(field reflect.Value, name, env string)) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := strings.Split(f.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if prefix != "" {
+			name = prefix + "." + name
+		}
+		if f.Type.Kind() == reflect.Struct {
+			walkConfig(v.Field(i), name, fn)
+			continue
+		}
+		fn(v.Field(i), name, f.Tag.Get("env"))
+	}
+}
+
+func setField(field reflect.Value, value string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(value)
+	case reflect.Bool:
+		switch strings.ToLower(strings.TrimSpace(value)) {
+		case "1", "true", "yes", "on":
+			field.SetBool(true)
+		case "", "0", "false", "no", "off":
+			field.SetBool(false)
+		default:
+			return fmt.Errorf("expected true/false, got %q", value)
+		}
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return fmt.Errorf("expected a number, got %q", value)
+		}
+		field.SetInt(n)
+	case reflect.Slice:
+		var ids []int64
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part == "" {
+				continue
+			}
+			id, err := strconv.ParseInt(part, 10, 64)
+			if err != nil {
+				return fmt.Errorf("expected comma-separated chat IDs, got %q", part)
+			}
+			ids = append(ids, id)
+		}
+		field.Set(reflect.ValueOf(ids))
+	}
+	return nil
+}
+
+func (c Config) validate() []string {
+	var problems []string
+	bad := func(name, env, format string, args ...interface{}) {
+		problems = append(problems, fmt.Sprintf("%s (%s): %s", name, env, fmt.Sprintf(format, args...)))
+	}
+	walkConfig(reflect.ValueOf(&c).Elem(), "", func(field reflect.Value, name, env string) {
+		if field.Kind() == reflect.Int && field.Int() <= 0 {
+			bad(name, env, "must be a positive number, got %d", field.Int())
+		}
+	})
+	if c.Token == "" {
+		bad("token", "TELEGRAM_BOT_TOKEN", "is required")
+	}
+	for _, level := range []struct{ name, env, value string }{
+		{"log_level", "LOG_LEVEL", c.LogLevel},
+		{"request_log_level", "REQUEST_LOG_LEVEL", c.RequestLog},
+	} {
+		switch strings.ToLower(level.value) {
+		case "", "debug", "info", "warn", "warning", "error":
+		default:
+			bad(level.name, level.env, "expected debug, info, warn or error, got %q", level.value)
+		}
+	}
+	if c.Storage.Dir != "" && c.Storage.DatabaseURL != "" {
+		bad("storage.dir", "DATA_DIR", "cannot be combined with storage.database_url")
+	}
+	if u := c.Storage.DatabaseURL; u != "" && !strings.HasPrefix(u, "postgres://") && !strings.HasPrefix(u, "postgresql://") {
+		bad("storage.database_url", "DATABASE_URL", "expected a postgres:// URL")
+	}
+	if u := c.Storage.RedisURL; u != "" && !strings.HasPrefix(u, "redis://") && !strings.HasPrefix(u, "rediss://") {
+		bad("storage.redis_url", "REDIS_URL", "expected a redis:// URL")
+	}
+	for _, a := range []struct{ name, env, addr string }{
+		{"http.health_addr", "HEALTH_ADDR", c.HTTP.HealthAddr},
+		{"http.api_addr", "API_ADDR", c.HTTP.APIAddr},
+		{"http.debug_addr", "DEBUG_ADDR", c.HTTP.DebugAddr},
+	} {
+		if a.addr == "" {
+			continue
+		}
+		if _, _, err := net.SplitHostPort(a.addr); err != nil {
+			bad(a.name, a.env, "expected host:port, got %q", a.addr)
+		}
+	}
+	if e := c.Telemetry.OTLPEndpoint; e != "" {
+		if u, err := url.Parse(e); err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			bad("telemetry.otlp_endpoint", "OTEL_EXPORTER_OTLP_ENDPOINT", "expected an http(s) URL, got %q", e)
+		}
+	}
+	if s3 := c.Backup.S3; (s3.Endpoint == "") != (s3.Bucket == "") {
+		bad("backup.s3", "BACKUP_S3_ENDPOINT/BACKUP_S3_BUCKET", "endpoint and bucket must be set together")
+	}
+	return problems
+}
+
+func applyConfig() {
+	ownerID = cfg.OwnerID
+	allowedChats = nil
+	for _, id := range cfg.AllowedChats {
+		if allowedChats == nil {
+			allowedChats = map[int64]bool{}
+		}
+		allowedChats[id] = true
+	}
+	errorsChatID = cfg.ErrorsChatID
+	requestLogLevel = parseLevel(cfg.RequestLog)
+	defaultLang = cfg.Locales.Default
+	statsFlushInterval = time.Duration(cfg.Storage.StatsFlush) * time.Second
+
+	l := cfg.Limits
+	nudgeDelay = time.Duration(l.NudgeDelayMinutes) * time.Minute
+	staleAfter = time.Duration(l.StaleTagDays) * 24 * time.Hour
+	staleGrace = time.Duration(l.StaleGraceDays) * 24 * time.Hour
+	deleteConfirmThreshold = l.DeleteConfirmThreshold
+	digestInterval = time.Duration(l.DigestHours) * time.Hour
+	adminCacheTTL = time.Duration(l.AdminCacheMinutes) * time.Minute
+	createCooldown = time.Duration(l.CreateCooldownSeconds) * time.Second
+	defaultDailyTagCap = l.CreateDailyCap
+	removedChatGrace = time.Duration(l.RemovedChatGraceDays) * 24 * time.Hour
+	archiveRetention = time.Duration(l.ArchiveRetentionDays) * 24 * time.Hour
+	reactionWindow = time.Duration(l.ReactionWindowHours) * time.Hour
+	defaultSpamLimits = SpamLimits{Pings: l.SpamPings, Window: l.SpamWindowMinutes, Mute: l.SpamMuteMinutes}
+	userRateLimit = l.UserCommandsPerMinute
+
+	webhookAttempts = cfg.Webhooks.Attempts
+	webhookClient.Timeout = time.Duration(cfg.Webhooks.TimeoutSeconds) * time.Second
+
+	backupDir = cfg.Backup.Dir
+	backupInterval = time.Duration(cfg.Backup.Hours) * time.Hour
+	backupRetention = time.Duration(cfg.Backup.RetentionDays) * 24 * time.Hour
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"math/rand"
+	"strconv"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+type Confirmation struct {
+	Action  string    `json:"action"`
+	Arg     string    `json:"arg"`
+	UserID  int64     `json:"user_id"`
+	Expires time.Time `json:"expires"`
+}
+
+const confirmTTL = 5 * time.Minute
+
+var (
+	deleteConfirmThreshold = 20
+
+	btnConfirmYes = tele.Btn{Unique: "confirm_yes"}
+	btnConfirmNo  = tele.Btn{Unique: "confirm_no"}
+
+	confirmActions = map[string]func(c tele.Context, arg string) error{}
+)
+
+func askConfirmation(c tele.Context, text, action, arg string) error {
+	token := strconv.FormatInt(rand.Int63(), 36)
+	conf, _ := json.Marshal(Confirmation{
+		Action:  action,
+		Arg:     arg,
+		UserID:  c.Sender().ID,
+		Expires: time.Now().Add(confirmTTL),
+	})
+	if err := kv.Set("confirm:"+token, conf, confirmTTL); err != nil {
+		return err
+	}
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(T(c, "confirm.yes"), btnConfirmYes.Unique, token),
+		markup.Data(T(c, "confirm.no"), btnConfirmNo.Unique, token),
+	))
+	return c.Send(text, markup)
+}
+
+func takeConfirmation(c tele.Context) (Confirmation, bool) {
+	key := "confirm:" + c.Callback().Data
+	var conf Confirmation
+	raw, ok, err := kv.Get(key)
+	if err == nil && ok {
+		err = json.Unmarshal(raw, &conf)
+	}
+	if err != nil || !ok || time.Now().After(conf.Expires) {
+		kv.Delete(key)
+		c.Respond(&tele.CallbackResponse{Text: T(c, "confirm.expired_short")})
+		c.Edit(T(c, "confirm.expired"))
+		return conf, false
+	}
+	if conf.UserID != c.Sender().ID {
+		c.Respond(&tele.CallbackResponse{Text: T(c, "confirm.not_yours")})
+		return conf, false
+	}
+	kv.Delete(key)
+	c.Respond()
+	return conf, true
+}
+
+func onConfirmYes(c tele.Context) error {
+	conf, ok := takeConfirmation(c)
+	if !ok {
+		return nil
+	}
+	c.Delete()
+	action, ok := confirmActions[conf.Action]
+	if !ok {
+		return nil
+	}
+	return action(c, conf.Arg)
+}
+
+func onConfirmNo(c tele.Context) error {
+	if _, ok := takeConfirmation(c); !ok {
+		return nil
+	}
+	return c.Edit(T(c, "confirm.cancelled"))
+}
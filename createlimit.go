@@ -0,0 +1,61 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	createCooldown     = 30 * time.Second
+	defaultDailyTagCap = 20
+)
+
+func dailyTagCap(chatID int64) int {
+	if s := data.Chats[chatID]; s != nil && s.DailyTagCap != 0 {
+		return s.DailyTagCap
+	}
+	return defaultDailyTagCap
+}
+
+func createdToday(chatID int64) int {
+	now := time.Now().In(chatLocation(chatID))
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	count := 0
+	for _, tag := range data.Tags {
+		if tag.ChatID == chatID && !tag.CreatedAt.Before(start) {
+			count++
+		}
+	}
+	return count
+}
+
+func handleTagCap(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "tagcap.groups_only"))
+	}
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "tagcap.current", createdToday(c.Chat().ID), capText(c, dailyTagCap(c.Chat().ID))))
+	}
+	limit := -1
+	if strings.ToLower(args[0]) != "off" {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n <= 0 {
+			return c.Send(T(c, "tagcap.usage"))
+		}
+		limit = n
+	}
+	chatSettings(c.Chat().ID).DailyTagCap = limit
+	saveData()
+	return c.Send(T(c, "tagcap.set", capText(c, limit)))
+}
+
+func capText(c tele.Context, limit int) string {
+	if limit <= 0 {
+		return T(c, "tagcap.unlimited")
+	}
+	return strconv.Itoa(limit)
+}
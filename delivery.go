@@ -0,0 +1,89 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const deliveryDM = "dm"
+
+type forwardedMessage struct {
+	msg tele.Editable
+}
+
+func (f forwardedMessage) Send(b *tele.Bot, to tele.Recipient, opts *tele.SendOptions) (*tele.Message, error) {
+	return b.Forward(to, f.msg, opts)
+}
+
+func deliverDM(c tele.Context, sub Subscriber, silent bool) {
+	ctx, to, chat := traceContext(c), &tele.User{ID: sub.ID}, c.Chat()
+	source := ""
+	if link := triggerLink(c); link != "" {
+		source = tr(langOf(c), "ping.source", link)
+	}
+	mention, fallback := mentionHTML(sub), sendOptions(c)
+	fallback.ReplyTo = c.Message()
+	fallback.DisableNotification = silent
+	sendQueue.enqueue(ctx, to, forwardedMessage{c.Message()}, func(msg *tele.Message, err error) {
+		if err != nil {
+			slog.Warn("Не удалось переслать упоминание в личку", "user_id", sub.ID, "err", err)
+			sendQueue.send(ctx, chat, mention, fallback)
+			return
+		}
+		if source != "" {
+			sendQueue.send(ctx, to, source,
+				&tele.SendOptions{ReplyTo: msg, DisableNotification: true, DisableWebPagePreview: true, ParseMode: tele.ModeHTML})
+		}
+	}, &tele.SendOptions{DisableNotification: silent})
+}
+
+func handleNotifyMe(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "notify.usage"))
+	}
+	var mode string
+	switch strings.ToLower(args[0]) {
+	case deliveryDM:
+		mode = deliveryDM
+	case deliveryDigest:
+		mode = deliveryDigest
+	case "chat":
+		mode = ""
+	default:
+		return c.Send(T(c, "notify.usage"))
+	}
+	var only *Tag
+	if len(args) > 1 {
+		if only = tagService.Find(c.Chat().ID, args[1]); only == nil {
+			return tagNotFound(c, args[1])
+		}
+	}
+	userID := c.Sender().ID
+	changed := 0
+	for _, tag := range tagService.Subscriptions(userID) {
+		if only != nil && tag != only {
+			continue
+		}
+		for j := range tag.Subscribers {
+			if tag.Subscribers[j].ID == userID {
+				tag.Subscribers[j].Delivery = mode
+				journalTag("update", tag)
+				changed++
+			}
+		}
+	}
+	if changed == 0 {
+		return c.Send(T(c, "notify.no_subscriptions"))
+	}
+	saveData()
+	switch mode {
+	case deliveryDM:
+		return c.Send(T(c, "notify.dm"))
+	case deliveryDigest:
+		return c.Send(T(c, "notify.digest"))
+	}
+	return c.Send(T(c, "notify.chat"))
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const deliveryDigest = "digest"
+
+var (
+	digestInterval = 4 * time.Hour
+	digestsSending = map[int64]bool{}
+)
+
+func queueDigest(c tele.Context, sub Subscriber, tag *Tag) {
+	if data.Digests == nil {
+		data.Digests = map[int64][]DigestItem{}
+	}
+	data.Digests[sub.ID] = append(data.Digests[sub.ID], DigestItem{
+		Tag:       tag.Name,
+		ChatID:    c.Chat().ID,
+		ChatTitle: c.Chat().Title,
+		Link:      messageLink(c.Chat(), c.Message().ID),
+		From:      c.Sender().Username,
+		At:        time.Now(),
+	})
+	saveStats()
+}
+
+type digestKey struct {
+	chatID int64
+	at     int64
+}
+
+func sendDigests(b *tele.Bot) {
+	if len(data.Digests) == 0 {
+		return
+	}
+	for userID, items := range data.Digests {
+		if digestsSending[userID] {
+			continue
+		}
+		lang := chatLang(items[0].ChatID)
+		var sb strings.Builder
+		sb.WriteString(tr(lang, "digest.header", len(items)))
+		for _, item := range items {
+			line := fmt.Sprintf("%s <code>#%s</code> — %s", item.At.In(userLocation(userID)).Format("02.01 15:04"), esc(item.Tag), esc(item.From))
+			if item.ChatTitle != "" {
+				line += fmt.Sprintf(" (%s)", esc(item.ChatTitle))
+			}
+			if item.Link != "" {
+				line += fmt.Sprintf(` <a href="%s">%s</a>`, item.Link, tr(lang, "digest.open"))
+			}
+			sb.WriteString(line + "\n")
+		}
+		digestsSending[userID] = true
+		sent := map[digestKey]bool{}
+		for _, item := range items {
+			sent[digestKey{item.ChatID, item.At.UnixNano()}] = true
+		}
+		sendQueue.enqueue(context.Background(), &tele.User{ID: userID}, sb.String(), func(_ *tele.Message, err error) {
+			dataMu.Lock()
+			defer dataMu.Unlock()
+			delete(digestsSending, userID)
+			if err != nil {
+				slog.Warn("Не удалось отправить дайджест", "user_id", userID, "err", err)
+				return
+			}
+			var left []DigestItem
+			for _, item := range data.Digests[userID] {
+				if !sent[digestKey{item.ChatID, item.At.UnixNano()}] {
+					left = append(left, item)
+				}
+			}
+			if len(left) == 0 {
+				delete(data.Digests, userID)
+			} else {
+				data.Digests[userID] = left
+			}
+			saveStats()
+		}, tele.NoPreview)
+	}
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	directoryDirty = map[int64]bool{}
+	directoryStale = true
+)
+
+func writeTagList(b *strings.Builder, lang string, tags []*Tag) {
+	categories, groups := groupByCategory(tags)
+	for _, category := range categories {
+		if len(categories) > 1 || category != noCategory {
+			title := category
+			if title == noCategory {
+				title = tr(lang, "category.none")
+			}
+			b.WriteString(fmt.Sprintf("\n📂 <b>%s</b>\n", esc(title)))
+		}
+		for _, tag := range groups[category] {
+			marker := tagIcon(tag)
+			if tag.Global {
+				marker = "🌐 " + marker
+			}
+			b.WriteString(fmt.Sprintf("%s<code>#%s</code> (%d): %s\n", marker, esc(tag.Name), len(tag.Subscribers), esc(tag.Description)))
+		}
+	}
+}
+
+func markDirectory(tag *Tag) {
+	if tag == nil || tag.ChatID == 0 || tag.Global {
+		directoryStale = true
+		return
+	}
+	directoryDirty[tag.ChatID] = true
+}
+
+func directoryText(chatID int64) string {
+	lang := chatLang(chatID)
+	var tags []*Tag
+	for _, tag := range tagService.InChat(chatID) {
+		if visibleTo(tag, 0) {
+			tags = append(tags, tag)
+		}
+	}
+	var b strings.Builder
+	b.WriteString(tr(lang, "directory.header"))
+	if len(tags) == 0 {
+		b.WriteString(tr(lang, "directory.empty"))
+	}
+	writeTagList(&b, lang, tags)
+	b.WriteString(tr(lang, "directory.footer", time.Now().In(chatLocation(chatID)).Format("02.01.2006 15:04")))
+	return b.String()
+}
+
+func refreshDirectories(b *tele.Bot) {
+	for chatID, s := range data.Chats {
+		if s.Directory == 0 || !(directoryStale || directoryDirty[chatID]) {
+			continue
+		}
+		if err := loadChats(chatID); err != nil {
+			slog.Warn("Не удалось обновить каталог тегов", "chat_id", chatID, "err", err)
+			continue
+		}
+		msg := &tele.StoredMessage{MessageID: fmt.Sprint(s.Directory), ChatID: chatID}
+		text := directoryText(chatID)
+		go func(chatID int64, messageID int) {
+			_, err := b.Edit(msg, text, tele.ModeHTML)
+			if err == nil || errors.Is(err, tele.ErrMessageNotModified) || errors.Is(err, tele.ErrSameMessageContent) {
+				return
+			}
+			slog.Warn("Не удалось обновить каталог тегов", "chat_id", chatID, "err", err)
+			if strings.Contains(err.Error(), "message to edit not found") {
+				dataMu.Lock()
+				if s := data.Chats[chatID]; s != nil && s.Directory == messageID {
+					s.Directory = 0
+					saveData()
+				}
+				dataMu.Unlock()
+			}
+		}(chatID, s.Directory)
+	}
+	directoryStale = false
+	directoryDirty = map[int64]bool{}
+}
+
+func handleDirectory(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "directory.groups_only"))
+	}
+	if !isChatAdmin(c, c.Chat().ID) {
+		return c.Send(T(c, "directory.denied"))
+	}
+	s := chatSettings(c.Chat().ID)
+	switch strings.ToLower(strings.TrimSpace(c.Message().Payload)) {
+	case "", "on":
+		sendQueue.enqueue(traceContext(c), c.Chat(), directoryText(c.Chat().ID), func(msg *tele.Message, err error) {
+			if err != nil {
+				onBotError(err, c)
+				return
+			}
+			if err := c.Bot().Pin(msg, tele.Silent); err != nil {
+				slog.Warn("Не удалось закрепить каталог тегов", "chat_id", c.Chat().ID, "err", err)
+				c.Send(T(c, "directory.pin_failed"))
+				return
+			}
+			dataMu.Lock()
+			old := s.Directory
+			s.Directory = msg.ID
+			saveData()
+			dataMu.Unlock()
+			if old != 0 {
+				c.Bot().Unpin(c.Chat(), old)
+			}
+		}, sendOptions(c))
+		return nil
+	case "off":
+		if s.Directory == 0 {
+			return c.Send(T(c, "directory.none"))
+		}
+		go c.Bot().Unpin(c.Chat(), s.Directory)
+		s.Directory = 0
+		saveData()
+		return c.Send(T(c, "directory.removed"))
+	}
+	return c.Send(T(c, "directory.usage"))
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func handleEditTag(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "edit.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	if len(args) == 3 && strings.EqualFold(args[1], "emoji") {
+		return setTagEmoji(c, tag, args[2])
+	}
+	description := strings.Join(args[1:], " ")
+	if word := blockedWord(c.Chat().ID, description); word != "" {
+		return c.Send(T(c, "blockword.rejected"))
+	}
+	tag.Description = description
+	saveTag("update", tag)
+	return c.Send(T(c, "edit.done", esc(tag.Name), esc(description)))
+}
+
+func setTagEmoji(c tele.Context, tag *Tag, emoji string) error {
+	if strings.EqualFold(emoji, "off") {
+		tag.Emoji = ""
+		saveTag("update", tag)
+		return c.Send(T(c, "edit.emoji_removed", esc(tag.Name)))
+	}
+	if !isEmoji(emoji) {
+		return c.Send(T(c, "edit.emoji_invalid"))
+	}
+	tag.Emoji = emoji
+	saveTag("update", tag)
+	return c.Send(T(c, "edit.emoji_set", esc(tag.Name), emoji))
+}
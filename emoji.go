@@ -0,0 +1,32 @@
+package main
+
+import (
+	"unicode"
+)
+
+const maxEmojiRunes = 8
+
+func isEmoji(s string) bool {
+	runes := []rune(s)
+	if len(runes) == 0 || len(runes) > maxEmojiRunes {
+		return false
+	}
+	symbol := false
+	for _, r := range runes {
+		switch {
+		case unicode.Is(unicode.So, r):
+			symbol = true
+		case r == '\u200d' || r == '\u20e3' || unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Sk, r):
+		default:
+			return false
+		}
+	}
+	return symbol
+}
+
+func tagIcon(tag *Tag) string {
+	if tag.Emoji == "" {
+		return ""
+	}
+	return tag.Emoji + " "
+}
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const redisTimeout = 3 * time.Second
+
+type Ephemeral interface {
+	Set(key string, value []byte, ttl time.Duration) error
+	SetNX(key string, value []byte, ttl time.Duration) (bool, error)
+	Get(key string) ([]byte, bool, error)
+	Delete(key string) error
+}
+
+var kv Ephemeral = newMemoryEphemeral()
+
+func openEphemeral() (Ephemeral, error) {
+	url := cfg.Storage.RedisURL
+	if url == "" {
+		return newMemoryEphemeral(), nil
+	}
+	opts, err := redis.ParseURL(url)
+	if err != nil {
+		return nil, err
+	}
+	client := redis.NewClient(opts)
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, err
+	}
+	return &redisEphemeral{client: client, prefix: "tagger:"}, nil
+}
+
+func onCooldown(key string, ttl time.Duration) bool {
+	ok, err := kv.SetNX("cooldown:"+key, []byte{1}, ttl)
+	return err == nil && !ok
+}
+
+type memoryItem struct {
+	value   []byte
+	expires time.Time
+}
+
+type memoryEphemeral struct {
+	mu    sync.Mutex
+	items map[string]memoryItem
+}
+
+func newMemoryEphemeral() *memoryEphemeral {
+	return &memoryEphemeral{items: map[string]memoryItem{}}
+}
+
+func (m *memoryEphemeral) live(key string) (memoryItem, bool) {
+	item, ok := m.items[key]
+	if ok && time.Now().After(item.expires) {
+		delete(m.items, key)
+		return item, false
+	}
+	return item, ok
+}
+
+func (m *memoryEphemeral) Set(key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.items[key] = memoryItem{value: value, expires: time.Now().Add(ttl)}
+	return nil
+}
+
+func (m *memoryEphemeral) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.live(key); ok {
+		return false, nil
+	}
+	m.items[key] = memoryItem{value: value, expires: time.Now().Add(ttl)}
+	return true, nil
+}
+
+func (m *memoryEphemeral) Get(key string) ([]byte, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.live(key)
+	return item.value, ok, nil
+}
+
+func (m *memoryEphemeral) Delete(key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.items, key)
+	return nil
+}
+
+type redisEphemeral struct {
+	client *redis.Client
+	prefix string
+}
+
+func (r *redisEphemeral) Set(key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.client.Set(ctx, r.prefix+key, value, ttl).Err()
+}
+
+func (r *redisEphemeral) SetNX(key string, value []byte, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.client.SetNX(ctx, r.prefix+key, value, ttl).Result()
+}
+
+func (r *redisEphemeral) Get(key string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	value, err := r.client.Get(ctx, r.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return value, true, nil
+}
+
+func (r *redisEphemeral) Delete(key string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	return r.client.Del(ctx, r.prefix+key).Err()
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	rsvpGoing = "going"
+	rsvpMaybe = "maybe"
+	rsvpNo    = "no"
+
+	eventReminder = 15 * time.Minute
+	eventKeep     = 24 * time.Hour
+)
+
+var (
+	btnRSVP     = tele.Btn{Unique: "rsvp"}
+	rsvpAnswers = []string{rsvpGoing, rsvpMaybe, rsvpNo}
+)
+
+func parseEventTime(args []string, loc *time.Location, now time.Time) (time.Time, int, bool) {
+	now = now.In(loc)
+	if len(args) > 1 {
+		if t, err := time.ParseInLocation("02.01 15:04", args[0]+" "+args[1], loc); err == nil {
+			start := time.Date(now.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			if start.Before(now) {
+				start = start.AddDate(1, 0, 0)
+			}
+			return start, 2, true
+		}
+	}
+	if len(args) > 0 {
+		if t, err := time.ParseInLocation("15:04", args[0], loc); err == nil {
+			start := time.Date(now.Year(), now.Month(), now.Day(), t.Hour(), t.Minute(), 0, 0, loc)
+			if start.Before(now) {
+				start = start.AddDate(0, 0, 1)
+			}
+			return start, 1, true
+		}
+	}
+	return time.Time{}, 0, false
+}
+
+func findEvent(chatID int64, id int) *Event {
+	if s := data.Chats[chatID]; s != nil {
+		for _, ev := range s.Events {
+			if ev.ID == id {
+				return ev
+			}
+		}
+	}
+	return nil
+}
+
+func eventAttendees(ev *Event, answer string) []string {
+	var names []string
+	for _, r := range ev.Responses {
+		if r.Answer == answer {
+			names = append(names, esc(displayName(r.UserID, r.Username)))
+		}
+	}
+	return names
+}
+
+func eventCard(lang string, ev *Event, loc *time.Location) string {
+	var b strings.Builder
+	b.WriteString(tr(lang, "event.card", esc(ev.Title), ev.Start.In(loc).Format("02.01 15:04"), esc(ev.Tag)))
+	for _, answer := range rsvpAnswers {
+		names := eventAttendees(ev, answer)
+		list := "—"
+		if len(names) > 0 {
+			list = strings.Join(names, ", ")
+		}
+		b.WriteString(tr(lang, "event."+answer, len(names), list))
+	}
+	return b.String()
+}
+
+func eventMarkup(lang string, ev *Event) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{}
+	var btns []tele.Btn
+	for _, answer := range rsvpAnswers {
+		btns = append(btns, markup.Data(tr(lang, "event.btn_"+answer), btnRSVP.Unique, fmt.Sprintf("%d|%s", ev.ID, answer)))
+	}
+	markup.Inline(markup.Row(btns...))
+	return markup
+}
+
+func handleEvent(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "event.groups_only"))
+	}
+	args := strings.Fields(c.Message().Payload)
+	if len(args) < 3 {
+		return c.Send(T(c, "event.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, strings.TrimPrefix(args[0], "#"))
+	if tag == nil || !visibleTo(tag, c.Sender().ID) {
+		return tagNotFound(c, strings.TrimPrefix(args[0], "#"))
+	}
+	if !canPing(c, tag) {
+		return c.Send(T(c, "ping.denied"))
+	}
+	loc := chatLocation(c.Chat().ID)
+	start, used, ok := parseEventTime(args[1:], loc, time.Now())
+	if !ok || len(args) <= 1+used {
+		return c.Send(T(c, "event.usage"))
+	}
+	title := strings.Join(args[1+used:], " ")
+	if word := blockedWord(c.Chat().ID, title); word != "" {
+		return c.Send(T(c, "blockword.rejected"))
+	}
+	s := chatSettings(c.Chat().ID)
+	ev := &Event{ID: 1, Tag: tag.Name, Title: title, Start: start, CreatorID: c.Sender().ID}
+	for _, other := range s.Events {
+		if other.ID >= ev.ID {
+			ev.ID = other.ID + 1
+		}
+	}
+	recordMention(tag, c)
+	mentions, allQuiet := collectMentions(c, tag, tag.Silent, map[int64]bool{})
+	lang := langOf(c)
+	opts := sendOptions(c)
+	opts.ReplyMarkup = eventMarkup(lang, ev)
+	opts.DisableNotification = allQuiet && len(mentions) > 0
+	text := eventCard(lang, ev, loc)
+	if len(mentions) > 0 {
+		text = strings.Join(mentions, " ") + "\n\n" + text
+	}
+	s.Events = append(s.Events, ev)
+	sendQueue.sendThen(c, c.Chat(), text, func(msg *tele.Message, err error) error {
+		if err != nil {
+			kept := s.Events[:0]
+			for _, other := range s.Events {
+				if other != ev {
+					kept = append(kept, other)
+				}
+			}
+			s.Events = kept
+			return err
+		}
+		ev.MessageID = msg.ID
+		saveData()
+		return nil
+	}, opts)
+	return nil
+}
+
+func onRSVP(c tele.Context) error {
+	id, answer, _ := strings.Cut(c.Callback().Data, "|")
+	n, _ := strconv.Atoi(id)
+	ev := findEvent(c.Chat().ID, n)
+	if ev == nil || ev.Closed {
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "event.closed")})
+	}
+	user := c.Sender()
+	found := false
+	for i := range ev.Responses {
+		if ev.Responses[i].UserID == user.ID {
+			ev.Responses[i].Answer = answer
+			found = true
+		}
+	}
+	if !found {
+		ev.Responses = append(ev.Responses, EventResponse{UserID: user.ID, Username: user.Username, Answer: answer})
+	}
+	saveData()
+	c.Respond(&tele.CallbackResponse{Text: T(c, "event.answered_"+answer)})
+	lang := chatLang(c.Chat().ID)
+	return c.Edit(eventCard(lang, ev, chatLocation(c.Chat().ID)), eventMarkup(lang, ev))
+}
+
+func eventMentions(ev *Event) []string {
+	var mentions []string
+	for _, r := range ev.Responses {
+		if r.Answer != rsvpNo {
+			mentions = append(mentions, mentionHTML(Subscriber{ID: r.UserID, Username: displayName(r.UserID, r.Username)}))
+		}
+	}
+	return mentions
+}
+
+func runEvents(b *tele.Bot) {
+	now := time.Now()
+	changed := false
+	for chatID, s := range data.Chats {
+		if len(s.Events) == 0 {
+			continue
+		}
+		lang := chatLang(chatID)
+		loc := chatLocation(chatID)
+		chat := &tele.Chat{ID: chatID}
+		kept := s.Events[:0]
+		for _, ev := range s.Events {
+			reply := &tele.SendOptions{ReplyTo: &tele.Message{ID: ev.MessageID, Chat: chat}}
+			if !ev.Reminded && !ev.Closed && now.After(ev.Start.Add(-eventReminder)) && now.Before(ev.Start) {
+				ev.Reminded = true
+				changed = true
+				text := tr(lang, "event.reminder", esc(ev.Title), int(time.Until(ev.Start).Minutes())+1)
+				if mentions := eventMentions(ev); len(mentions) > 0 {
+					text = strings.Join(mentions, " ") + "\n" + text
+				}
+				sendQueue.send(context.Background(), chat, text, reply)
+			}
+			if !ev.Closed && !now.Before(ev.Start) {
+				ev.Closed = true
+				changed = true
+				going := eventAttendees(ev, rsvpGoing)
+				list := tr(lang, "event.nobody")
+				if len(going) > 0 {
+					list = strings.Join(going, "\n")
+				}
+				sendQueue.send(context.Background(), chat, tr(lang, "event.final", esc(ev.Title), len(going), len(eventAttendees(ev, rsvpMaybe)), list), reply)
+				msg := &tele.StoredMessage{MessageID: strconv.Itoa(ev.MessageID), ChatID: chatID}
+				go b.Edit(msg, eventCard(lang, ev, loc), tele.ModeHTML)
+			}
+			if ev.Closed && now.Sub(ev.Start) > eventKeep {
+				changed = true
+				continue
+			}
+			kept = append(kept, ev)
+		}
+		s.Events = kept
+	}
+	if changed {
+		saveData()
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const maxImportSize = 5 << 20
+
+type ExportedTag struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Category    string       `json:"category,omitempty"`
+	Access      string       `json:"access,omitempty"`
+	Silent      bool         `json:"silent,omitempty"`
+	PingPolicy  string       `json:"ping_policy,omitempty"`
+	Subscribers []Subscriber `json:"subscribers"`
+	Moderators  []Subscriber `json:"moderators,omitempty"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+var csvHeader = []string{"name", "description", "category", "access", "silent", "subscribers"}
+
+func canAdminChat(c tele.Context) bool {
+	return c.Chat().Type == tele.ChatPrivate || isChatAdmin(c, c.Chat().ID)
+}
+
+func exportTags(chatID int64) []ExportedTag {
+	var out []ExportedTag
+	for _, tag := range data.Tags {
+		if !tag.Active() || tag.ChatID != chatID {
+			continue
+		}
+		out = append(out, ExportedTag{
+			Name:        tag.Name,
+			Description: tag.Description,
+			Category:    tag.Category,
+			Access:      tag.Access,
+			Silent:      tag.Silent,
+			PingPolicy:  tag.PingPolicy,
+			Subscribers: tag.Subscribers,
+			Moderators:  tag.Moderators,
+			CreatedAt:   tag.CreatedAt,
+		})
+	}
+	return out
+}
+
+func encodeSubscribers(subs []Subscriber) string {
+	parts := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		parts = append(parts, fmt.Sprintf("%d:%s", sub.ID, sub.Username))
+	}
+	return strings.Join(parts, " ")
+}
+
+func decodeSubscribers(s string) []Subscriber {
+	subs := []Subscriber{}
+	for _, part := range strings.Fields(s) {
+		id, name, _ := strings.Cut(part, ":")
+		if userID, err := strconv.ParseInt(id, 10, 64); err == nil {
+			subs = append(subs, Subscriber{ID: userID, Username: name})
+		}
+	}
+	return subs
+}
+
+func handleExport(c tele.Context) error {
+	if !canAdminChat(c) {
+		return c.Send(T(c, "export.denied"))
+	}
+	format := "json"
+	if args := strings.Fields(c.Text())[1:]; len(args) > 0 {
+		format = strings.ToLower(args[0])
+	}
+	tags := exportTags(c.Chat().ID)
+	if len(tags) == 0 {
+		return c.Send(T(c, "export.empty"))
+	}
+	var buf bytes.Buffer
+	switch format {
+	case "json":
+		enc := json.NewEncoder(&buf)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(tags); err != nil {
+			return err
+		}
+	case "csv":
+		w := csv.NewWriter(&buf)
+		w.Write(csvHeader)
+		for _, tag := range tags {
+			w.Write([]string{tag.Name, tag.Description, tag.Category, tag.Access,
+				strconv.FormatBool(tag.Silent), encodeSubscribers(tag.Subscribers)})
+		}
+		w.Flush()
+		if err := w.Error(); err != nil {
+			return err
+		}
+	default:
+		return c.Send(T(c, "export.usage"))
+	}
+	doc := &tele.Document{
+		File:     tele.FromReader(bytes.NewReader(buf.Bytes())),
+		FileName: fmt.Sprintf("tags-%d.%s", c.Chat().ID, format),
+		Caption:  T(c, "export.caption", len(tags)),
+	}
+	return c.Send(doc)
+}
+
+func parseImport(name string, raw []byte) ([]ExportedTag, error) {
+	if strings.EqualFold(filepath.Ext(name), ".csv") {
+		rows, err := csv.NewReader(bytes.NewReader(raw)).ReadAll()
+		if err != nil {
+			return nil, err
+		}
+		var tags []ExportedTag
+		for i, row := range rows {
+			if i == 0 && len(row) > 0 && row[0] == csvHeader[0] {
+				continue
+			}
+			if len(row) < len(csvHeader) {
+				return nil, fmt.Errorf("row %d: expected %d columns", i+1, len(csvHeader))
+			}
+			silent, _ := strconv.ParseBool(row[4])
+			tags = append(tags, ExportedTag{
+				Name:        row[0],
+				Description: row[1],
+				Category:    row[2],
+				Access:      row[3],
+				Silent:      silent,
+				Subscribers: decodeSubscribers(row[5]),
+			})
+		}
+		return tags, nil
+	}
+	var tags []ExportedTag
+	err := json.Unmarshal(raw, &tags)
+	return tags, err
+}
+
+func importDocument(c tele.Context) *tele.Document {
+	if doc := c.Message().Document; doc != nil {
+		return doc
+	}
+	if reply := c.Message().ReplyTo; reply != nil {
+		return reply.Document
+	}
+	return nil
+}
+
+func validAccess(access string) bool {
+	return access == accessOpen || access == accessPrivate || access == accessModerated
+}
+
+func downloadImport(b *tele.Bot, doc *tele.Document, chatID int64) ([]ExportedTag, error) {
+	reader, err := b.File(&doc.File)
+	if err != nil {
+		return nil, err
+	}
+	raw, err := io.ReadAll(io.LimitReader(reader, maxImportSize))
+	reader.Close()
+	if err != nil {
+		return nil, err
+	}
+	tags, err := parseImport(doc.FileName, raw)
+	if err != nil {
+		return nil, err
+	}
+	members := map[int64]bool{}
+	for i := range tags {
+		var kept []Subscriber
+		for _, mod := range tags[i].Moderators {
+			if _, ok := members[mod.ID]; !ok {
+				members[mod.ID] = isMemberOf(b, chatID, mod.ID)
+			}
+			if members[mod.ID] {
+				kept = append(kept, mod)
+			}
+		}
+		tags[i].Moderators = kept
+	}
+	return tags, nil
+}
+
+func handleImport(c tele.Context) error {
+	if readOnly {
+		return c.Send(T(c, "readonly.rejected"))
+	}
+	if !canAdminChat(c) {
+		return c.Send(T(c, "import.denied"))
+	}
+	doc := importDocument(c)
+	if doc == nil {
+		return c.Send(T(c, "import.usage"))
+	}
+	if doc.FileSize > maxImportSize {
+		return c.Send(T(c, "import.too_large"))
+	}
+	chatID := c.Chat().ID
+	go func() {
+		tags, err := downloadImport(c.Bot(), doc, chatID)
+		dataMu.Lock()
+		defer dataMu.Unlock()
+		if err == nil {
+			err = loadChats(chatID)
+		}
+		if err != nil {
+			c.Send(T(c, "import.failed", esc(err.Error())))
+			return
+		}
+		if err := importTags(c, tags); err != nil {
+			onBotError(err, c)
+		}
+	}()
+	return nil
+}
+
+func importTags(c tele.Context, tags []ExportedTag) error {
+	if readOnly {
+		return c.Send(T(c, "readonly.rejected"))
+	}
+	created, updated, capped, blocked := 0, 0, 0, 0
+	chatID := c.Chat().ID
+	limit := dailyTagCap(chatID)
+	room := limit - createdToday(chatID)
+	for _, in := range tags {
+		if in.Name == "" {
+			continue
+		}
+		if !validPingPolicy(in.PingPolicy) {
+			in.PingPolicy = pingAnyone
+		}
+		if !validAccess(in.Access) {
+			in.Access = accessOpen
+		}
+		tag := tagService.Local(chatID, in.Name)
+		if tag == nil {
+			if blockedWord(chatID, in.Name, in.Category, in.Description) != "" {
+				blocked++
+				continue
+			}
+			if limit > 0 && created >= room {
+				capped++
+				continue
+			}
+			tag = &Tag{
+				Name:        in.Name,
+				ChatID:      chatID,
+				CreatorID:   c.Sender().ID,
+				CreatorName: c.Sender().Username,
+				Description: in.Description,
+				Category:    in.Category,
+				Access:      in.Access,
+				Silent:      in.Silent,
+				PingPolicy:  in.PingPolicy,
+				Moderators:  in.Moderators,
+				Subscribers: []Subscriber{},
+				CreatedAt:   time.Now(),
+			}
+			data.Tags = append(data.Tags, tag)
+			tagService.Add(tag)
+			created++
+		} else {
+			updated++
+		}
+		for _, sub := range in.Subscribers {
+			if !isSubscribed(tag, sub.ID) && !isBanned(tag, sub.ID) {
+				tagService.Subscribe(tag, sub)
+			}
+		}
+		saveTag("import", tag)
+	}
+	if err := c.Send(T(c, "import.done", created, updated)); err != nil {
+		return err
+	}
+	if blocked > 0 {
+		if err := c.Send(T(c, "import.blocked", blocked)); err != nil {
+			return err
+		}
+	}
+	if capped > 0 {
+		return c.Send(T(c, "import.capped", capped, limit))
+	}
+	return nil
+}
+
+func onDocument(c tele.Context) error {
+	if strings.HasPrefix(strings.TrimSpace(c.Message().Caption), "/import") {
+		return handleImport(c)
+	}
+	return nil
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func notifySubscribers(ctx context.Context, chatID int64, tag *Tag, actorID int64, text string) {
+	var subs []Subscriber
+	for _, sub := range tag.Subscribers {
+		if sub.ID != actorID {
+			subs = append(subs, sub)
+		}
+	}
+	if len(subs) == 0 {
+		return
+	}
+	if chatID < 0 {
+		mentions := make([]string, 0, len(subs))
+		for _, sub := range subs {
+			mentions = append(mentions, mentionHTML(sub))
+		}
+		sendQueue.send(ctx, &tele.Chat{ID: chatID}, strings.Join(mentions, " ")+"\n"+text)
+		return
+	}
+	for _, sub := range subs {
+		sendQueue.send(ctx, &tele.User{ID: sub.ID}, text)
+	}
+}
+
+func renameTag(tag *Tag, name string) {
+	before := *tag
+	tagService.Remove(tag)
+	tag.Name = name
+	tagService.Add(tag)
+	for i := range data.Mentions {
+		if ev := &data.Mentions[i]; ev.ChatID == tag.ChatID && strings.EqualFold(ev.Tag, before.Name) {
+			ev.Tag = name
+		}
+	}
+	for _, sub := range tag.Subscribers {
+		p := data.Users[sub.ID]
+		if p == nil {
+			continue
+		}
+		for i, muted := range p.Muted {
+			if strings.EqualFold(muted, before.Name) {
+				p.Muted[i] = name
+			}
+		}
+		if until, ok := p.Snoozed[strings.ToLower(before.Name)]; ok {
+			delete(p.Snoozed, strings.ToLower(before.Name))
+			p.Snoozed[strings.ToLower(name)] = until
+		}
+	}
+	if s := data.Chats[tag.ChatID]; s != nil {
+		for _, rule := range s.Rules {
+			if strings.EqualFold(rule.Tag, before.Name) {
+				rule.Tag = name
+			}
+		}
+		for _, ev := range s.Events {
+			if strings.EqualFold(ev.Tag, before.Name) {
+				ev.Tag = name
+			}
+		}
+	}
+	journalTag("purge", &before)
+	saveTag("update", tag)
+}
+
+func handleRenameTag(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) < 2 {
+		return c.Send(T(c, "rename.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	name := strings.TrimPrefix(args[1], "#")
+	if name == "" || strings.ContainsAny(name, "#:") {
+		return c.Send(T(c, "rename.usage"))
+	}
+	if other := tagService.Local(tag.ChatID, name); other != nil && other != tag {
+		return c.Send(T(c, "create.exists"))
+	}
+	if blockedWord(c.Chat().ID, name) != "" {
+		return c.Send(T(c, "blockword.rejected"))
+	}
+	old := tag.Name
+	renameTag(tag, name)
+	notifySubscribers(traceContext(c), c.Chat().ID, tag, c.Sender().ID, T(c, "farewell.renamed", esc(old), esc(name)))
+	return c.Send(T(c, "rename.done", esc(old), esc(name)))
+}
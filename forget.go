@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log/slog"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+func forgetUser(userID int64, username string) storage.ForgetResult {
+	res := storage.ForgetUser(&data, userID, username, func(before, after *Tag) {
+		if before.CreatorID != after.CreatorID {
+			journalTag("purge", before)
+		}
+		journalTag("update", after)
+	})
+	tagService.Rebuild()
+	saveData()
+	return res
+}
+
+func handleForgetMe(c tele.Context) error {
+	userID := c.Sender().ID
+	subs := len(tagService.Subscriptions(userID))
+	created := 0
+	for _, tag := range data.Tags {
+		if tag.CreatorID == userID {
+			created++
+		}
+	}
+	return askConfirmation(c, T(c, "forget.confirm", subs, created), "forgetme", "")
+}
+
+func confirmForgetMe(c tele.Context, _ string) error {
+	res := forgetUser(c.Sender().ID, c.Sender().Username)
+	slog.Info("🧹 Пользователь удалил свои данные", "user_id", c.Sender().ID,
+		"subscriptions", res.Subscriptions, "transferred", res.Transferred, "anonymized", res.Anonymized)
+	return c.Send(T(c, "forget.done", res.Subscriptions, res.Transferred, res.Anonymized))
+}
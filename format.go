@@ -0,0 +1,32 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"strconv"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func esc(s string) string {
+	return html.EscapeString(s)
+}
+
+func mentionHTML(sub Subscriber) string {
+	if sub.Username != "" && sub.Username != fmt.Sprintf("User%d", sub.ID) {
+		return "@" + esc(sub.Username)
+	}
+	return fmt.Sprintf(`<a href="tg://user?id=%d">%s</a>`, sub.ID, esc(sub.Username))
+}
+
+func messageLink(chat *tele.Chat, messageID int) string {
+	if chat.Username != "" {
+		return fmt.Sprintf("https://t.me/%s/%d", chat.Username, messageID)
+	}
+	id := strconv.FormatInt(chat.ID, 10)
+	if !strings.HasPrefix(id, "-100") {
+		return ""
+	}
+	return fmt.Sprintf("https://t.me/c/%s/%d", strings.TrimPrefix(id, "-100"), messageID)
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+	"tagger/telefake"
+)
+
+const testChatID = -1001000000000
+
+var (
+	alice = &tele.User{ID: 10, Username: "alice"}
+	bob   = &tele.User{ID: 11, Username: "bob"}
+)
+
+func testTag(name string, subs ...*tele.User) *Tag {
+	tag := &Tag{Name: name, ChatID: testChatID, CreatorID: 1, Subscribers: []Subscriber{}, CreatedAt: time.Now()}
+	for _, u := range subs {
+		tag.Subscribers = append(tag.Subscribers, Subscriber{ID: u.ID, Username: u.Username})
+	}
+	return tag
+}
+
+func withTags(t *testing.T, tags ...*Tag) *tele.Chat {
+	t.Helper()
+	setupHarness(t)
+	t.Cleanup(drainOutbox)
+	dataMu.Lock()
+	data = Data{SchemaVersion: storage.SchemaVersion, Tags: tags, Chats: map[int64]*ChatSettings{}}
+	tagService.Rebuild()
+	dataMu.Unlock()
+	return &tele.Chat{ID: testChatID, Type: tele.ChatSuperGroup, Title: "test"}
+}
+
+func drainOutbox() {
+	for {
+		sendQueue.mu.Lock()
+		idle := len(sendQueue.pending) == 0
+		sendQueue.mu.Unlock()
+		if idle {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func handle(h tele.HandlerFunc, c tele.Context) error {
+	return lockData(h)(c)
+}
+
+func ping(c tele.Context) error {
+	return pingHashtags(c, map[string]bool{})
+}
+
+func TestHistoryListsMentions(t *testing.T) {
+	chat := withTags(t, testTag("raid", alice))
+	from := &tele.User{ID: 999, Username: "pinger"}
+	if err := handle(ping, telefake.Message(chat, from, "сбор! #raid")); err != nil {
+		t.Fatal(err)
+	}
+	c := telefake.Message(chat, from, "/history raid")
+	if err := handle(handleHistory, c); err != nil {
+		t.Fatal(err)
+	}
+	out := c.LastText()
+	if !strings.Contains(out, "#raid") || !strings.Contains(out, "pinger") || !strings.Contains(out, "https://t.me/c/1000000000/1") {
+		t.Fatalf("unexpected history: %q", out)
+	}
+}
+
+func TestHistoryUnknownTag(t *testing.T) {
+	chat := withTags(t, testTag("raid", alice))
+	c := telefake.Message(chat, &tele.User{ID: 1}, "/history nope")
+	if err := handle(handleHistory, c); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := c.LastText(), tr(defaultLang, "tag_not_found"); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestSnoozeMutesTag(t *testing.T) {
+	tag, other := testTag("raid", alice), testTag("quiz", alice)
+	chat := withTags(t, tag, other)
+	user := alice
+	if isMuted(user.ID, tag) {
+		t.Fatal("muted before snoozing")
+	}
+	c := telefake.Callback(chat, user, btnSnooze.Unique, "raid|60")
+	if err := handle(onSnooze, c); err != nil {
+		t.Fatal(err)
+	}
+	if !isMuted(user.ID, tag) {
+		t.Fatal("snooze did not mute the tag")
+	}
+	if isMuted(user.ID, other) {
+		t.Fatal("snooze muted an unrelated tag")
+	}
+
+	stranger := telefake.Callback(chat, bob, btnSnooze.Unique, "raid|60")
+	if err := handle(onSnooze, stranger); err != nil {
+		t.Fatal(err)
+	}
+	if len(stranger.Responses) != 1 || stranger.Responses[0].Text != tr(defaultLang, "ack.not_subscribed") {
+		t.Fatalf("unexpected response for a non-subscriber: %+v", stranger.Responses)
+	}
+}
+
+func TestSummaryCollectsMutedMentions(t *testing.T) {
+	tag := testTag("raid", alice, bob)
+	chat := withTags(t, tag)
+	user := alice
+	dm := &tele.Chat{ID: user.ID, Type: tele.ChatPrivate}
+	if err := handle(handleSummary, telefake.Message(dm, user, "/summary on 20")); err != nil {
+		t.Fatal(err)
+	}
+	userPrefs(user.ID).Muted = []string{tag.Name}
+
+	if err := handle(ping, telefake.Message(chat, &tele.User{ID: 999, Username: "pinger"}, "#raid")); err != nil {
+		t.Fatal(err)
+	}
+	if items := userPrefs(user.ID).Summary.Items; len(items) != 1 || items[0].Tag != tag.Name {
+		t.Fatalf("unexpected summary items: %+v", items)
+	}
+
+	c := telefake.Message(dm, user, "/summary now")
+	if err := handle(handleSummary, c); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(c.LastText(), "#raid") {
+		t.Fatalf("summary misses the tag: %q", c.LastText())
+	}
+	if n := len(userPrefs(user.ID).Summary.Items); n != 0 {
+		t.Fatalf("%d items left after sending the summary", n)
+	}
+}
+
+func TestPingTextLinksSupergroupsOnly(t *testing.T) {
+	tag := testTag("raid", alice)
+	chat := withTags(t, tag)
+	from := &tele.User{ID: 1, Username: "pinger"}
+
+	super := telefake.Message(chat, from, "#raid")
+	if text := pingText(super, tag, []string{"@x"}, "", "#raid"); !strings.Contains(text, "https://t.me/c/1000000000/1") {
+		t.Fatalf("no link in a supergroup ping: %q", text)
+	}
+	small := telefake.Message(&tele.Chat{ID: -42, Type: tele.ChatGroup}, from, "#raid")
+	if text := pingText(small, tag, []string{"@x"}, "", "#raid"); strings.Contains(text, "t.me/") {
+		t.Fatalf("link in a basic group ping: %q", text)
+	}
+}
+
+func TestDigestKeepsItemsQueuedWhileSending(t *testing.T) {
+	withTags(t)
+	first := DigestItem{Tag: "raid", ChatID: testChatID, From: "bob", At: time.Now()}
+	later := DigestItem{Tag: "raid", ChatID: testChatID, From: "bob", At: first.At.Add(time.Second)}
+	dataMu.Lock()
+	data.Digests = map[int64][]DigestItem{alice.ID: {first}}
+	sendDigests(nil)
+	data.Digests[alice.ID] = append(data.Digests[alice.ID], later)
+	dataMu.Unlock()
+
+	drainOutbox()
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	if left := data.Digests[alice.ID]; digestsSending[alice.ID] || len(left) != 1 || !left[0].At.Equal(later.At) {
+		t.Fatalf("unexpected digest left: %+v", left)
+	}
+}
+
+func TestWebhookRefusesPrivateAddresses(t *testing.T) {
+	for _, ip := range []string{"127.0.0.1", "10.1.2.3", "192.168.0.1", "169.254.169.254", "::1", "fe80::1", "0.0.0.0"} {
+		if publicIP(net.ParseIP(ip)) {
+			t.Errorf("%s treated as public", ip)
+		}
+	}
+	if !publicIP(net.ParseIP("1.1.1.1")) {
+		t.Error("1.1.1.1 treated as private")
+	}
+	if err := checkWebhookHost(context.Background(), "127.0.0.1"); !errors.Is(err, errPrivateAddress) {
+		t.Fatalf("loopback host accepted: %v", err)
+	}
+
+	hits := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(http.ResponseWriter, *http.Request) { hits++ }))
+	defer srv.Close()
+	err := postWebhook(Webhook{URL: srv.URL, Secret: "s"}, eventTagCreated, []byte("{}"))
+	if !errors.Is(err, errPrivateAddress) || hits != 0 {
+		t.Fatalf("webhook reached a loopback server: %v", err)
+	}
+}
+
+func TestTrendingStaysInChat(t *testing.T) {
+	other := testTag("secret", bob)
+	other.ChatID = testChatID - 1
+	chat := withTags(t, testTag("raid", alice), other)
+	dataMu.Lock()
+	data.Mentions = []MentionEvent{
+		{Tag: "raid", ChatID: testChatID, At: time.Now()},
+		{Tag: "secret", ChatID: other.ChatID, At: time.Now()},
+	}
+	dataMu.Unlock()
+
+	c := telefake.Message(chat, alice, "/trending")
+	if err := handle(handleTrending, c); err != nil {
+		t.Fatal(err)
+	}
+	if out := c.LastText(); !strings.Contains(out, "#raid") || strings.Contains(out, "secret") {
+		t.Fatalf("unexpected trending in a group: %q", out)
+	}
+	dm := telefake.Message(&tele.Chat{ID: alice.ID, Type: tele.ChatPrivate}, alice, "/trending")
+	if err := handle(handleTrending, dm); err != nil {
+		t.Fatal(err)
+	}
+	if out := dm.LastText(); !strings.Contains(out, "#raid") || strings.Contains(out, "secret") {
+		t.Fatalf("unexpected trending in private: %q", out)
+	}
+}
+
+func TestDMDeliveryForwardsThroughOutbox(t *testing.T) {
+	tag := testTag("raid", alice)
+	tag.Subscribers[0].Delivery = deliveryDM
+	chat := withTags(t, tag)
+	forwards := func() int {
+		harnessAPI.mu.Lock()
+		defer harnessAPI.mu.Unlock()
+		return harnessAPI.calls["forwardMessage"]
+	}
+	before := forwards()
+	if err := handle(ping, telefake.Message(chat, bob, "#raid")); err != nil {
+		t.Fatal(err)
+	}
+	drainOutbox()
+	if got := forwards() - before; got != 1 {
+		t.Fatalf("forwarded %d times, want 1", got)
+	}
+}
+
+func TestRulesRespectPingPolicy(t *testing.T) {
+	tag := testTag("raid", alice)
+	tag.PingPolicy = pingSubscribers
+	chat := withTags(t, tag)
+	adminMu.Lock()
+	adminCache[chat.ID] = adminEntry{ids: map[int64]bool{}, fetched: time.Now()}
+	adminMu.Unlock()
+	rule := &TagRule{ID: 1, Keyword: "сбор", Tag: tag.Name}
+	data.Chats[chat.ID] = &ChatSettings{Rules: []*TagRule{rule}}
+
+	if err := handle(ping, telefake.Message(chat, &tele.User{ID: 999}, "сбор через час")); err != nil {
+		t.Fatal(err)
+	}
+	if tag.Stats.Mentions != 0 || !rule.LastFired.IsZero() {
+		t.Fatal("a rule pinged the tag for a user the ping policy refuses")
+	}
+	if err := handle(ping, telefake.Message(chat, alice, "сбор через час")); err != nil {
+		t.Fatal(err)
+	}
+	if tag.Stats.Mentions != 1 {
+		t.Fatal("a rule did not fire for a subscriber")
+	}
+}
+
+func TestRenameMovesTagReferences(t *testing.T) {
+	tag := testTag("raid", alice)
+	chat := withTags(t, tag)
+	until := time.Now().Add(time.Hour)
+	data.Users = map[int64]*UserPrefs{alice.ID: {Muted: []string{"raid"}, Snoozed: map[string]time.Time{"raid": until}}}
+	data.Chats[chat.ID] = &ChatSettings{
+		Rules:  []*TagRule{{ID: 1, Keyword: "сбор", Tag: "raid"}},
+		Events: []*Event{{ID: 1, Tag: "raid"}},
+	}
+
+	if err := handle(handleRenameTag, telefake.Message(chat, &tele.User{ID: 1}, "/rename raid boss")); err != nil {
+		t.Fatal(err)
+	}
+	s, p := data.Chats[chat.ID], data.Users[alice.ID]
+	if tag.Name != "boss" || s.Rules[0].Tag != "boss" || s.Events[0].Tag != "boss" {
+		t.Fatalf("rename left stale references: rule %q, event %q", s.Rules[0].Tag, s.Events[0].Tag)
+	}
+	if p.Muted[0] != "boss" || !p.Snoozed["boss"].Equal(until) || !isMuted(alice.ID, tag) {
+		t.Fatalf("rename lost the mute or snooze: %+v", p)
+	}
+}
+
+func TestMigrationMergesIntoTrackedChat(t *testing.T) {
+	chat := withTags(t, testTag("raid", alice))
+	const to = -1009
+	data.Chats[chat.ID] = &ChatSettings{Lang: "en", Title: "old", Rules: []*TagRule{{ID: 1, Keyword: "сбор", Tag: "raid"}}}
+	data.Chats[to] = &ChatSettings{Title: "new", LastSeen: time.Now()}
+	data.Users = map[int64]*UserPrefs{alice.ID: {Summary: &DailySummary{Items: []DigestItem{{Tag: "raid", ChatID: chat.ID}}}}}
+	data.Digests = map[int64][]DigestItem{bob.ID: {{Tag: "raid", ChatID: chat.ID}}}
+
+	remapChat(chat.ID, to)
+	s := data.Chats[to]
+	if s.Title != "new" || s.Lang != "en" || len(s.Rules) != 1 || data.Chats[chat.ID] != nil {
+		t.Fatalf("settings were not merged: %+v", s)
+	}
+	if data.Users[alice.ID].Summary.Items[0].ChatID != to || data.Digests[bob.ID][0].ChatID != to {
+		t.Fatal("queued items still point at the old chat")
+	}
+}
+
+func TestArchiveKeepsTagsCreatedWithoutSubscribers(t *testing.T) {
+	tag := testTag("raid")
+	tag.CreatedAt = time.Now().Add(-2 * emptyGrace)
+	tag.KeepEmpty = true
+	withTags(t, tag)
+	archiveEmptyTags(nil)
+	if tag.ArchivedAt != nil {
+		t.Fatal("a tag created with --nosub was archived for being empty")
+	}
+}
+
+func TestRestoreInPrivateOnlyFindsOwnTags(t *testing.T) {
+	removed := time.Now()
+	mine, theirs := testTag("raid"), testTag("raid")
+	mine.CreatorID, mine.ChatID, mine.DeletedAt = alice.ID, -5, &removed
+	later := removed.Add(time.Minute)
+	theirs.CreatorID, theirs.DeletedAt = bob.ID, &later
+	withTags(t, mine, theirs)
+	dm := &tele.Chat{ID: alice.ID, Type: tele.ChatPrivate}
+	if got := findRemovedTag(telefake.Message(dm, alice, "/restore raid"), "raid"); got != mine {
+		t.Fatalf("found %+v, want the caller's own removed tag", got)
+	}
+}
+
+func TestCategoryMatchesOnlyWithinChat(t *testing.T) {
+	here, there := testTag("raid"), testTag("quiz")
+	here.Category, there.Category, there.ChatID = "Games", "GAMES", -5
+	withTags(t, there)
+	if got := findCategory(testChatID, "games"); got != "" {
+		t.Fatalf("picked up category %q from another chat", got)
+	}
+	data.Tags = append(data.Tags, here)
+	tagService.Rebuild()
+	if got := findCategory(testChatID, "games"); got != "Games" {
+		t.Fatalf("findCategory = %q, want the chat's own spelling", got)
+	}
+}
+
+func TestInviteResolvesUsernamesFromChatOnly(t *testing.T) {
+	carol := &tele.User{ID: 12, Username: "carol"}
+	here, there := testTag("raid", alice), testTag("quiz", carol)
+	there.ChatID = -5
+	chat := withTags(t, here, there)
+	c := telefake.Message(chat, &tele.User{ID: 1}, "/invite raid @carol")
+	if err := handle(handleInvite, c); err != nil {
+		t.Fatal(err)
+	}
+	if c.LastText() != tr(defaultLang, "user.unknown") || isSubscribed(here, carol.ID) {
+		t.Fatal("a username from another chat was resolved")
+	}
+	data.Tags = append(data.Tags, testTag("dota", carol))
+	tagService.Rebuild()
+	if err := handle(handleInvite, telefake.Message(chat, &tele.User{ID: 1}, "/invite raid @carol")); err != nil {
+		t.Fatal(err)
+	}
+	if !isSubscribed(here, carol.ID) {
+		t.Fatal("a chat member was not invited")
+	}
+}
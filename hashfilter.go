@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var hashtagSources = []string{"forwarded", "bots", "commands"}
+
+func hashtagFilter(chatID int64) HashtagFilter {
+	if s := data.Chats[chatID]; s != nil && s.Hashtags != nil {
+		return *s.Hashtags
+	}
+	return HashtagFilter{}
+}
+
+func isForwarded(m *tele.Message) bool {
+	return m.IsForwarded() || m.Origin != nil || m.OriginalUnixtime != 0
+}
+
+func ignoreHashtags(c tele.Context) bool {
+	m := c.Message()
+	f := hashtagFilter(c.Chat().ID)
+	switch {
+	case isForwarded(m) && !f.Forwarded:
+		return true
+	case c.Sender().IsBot && !f.Bots:
+		return true
+	case strings.HasPrefix(m.Text, "/") && !f.Commands:
+		return true
+	}
+	return false
+}
+
+func allowedSource(f *HashtagFilter, source string) *bool {
+	switch source {
+	case "forwarded":
+		return &f.Forwarded
+	case "bots":
+		return &f.Bots
+	case "commands":
+		return &f.Commands
+	}
+	return nil
+}
+
+func sourceState(c tele.Context, on bool) string {
+	if on {
+		return T(c, "hashtags.on")
+	}
+	return T(c, "hashtags.off")
+}
+
+func handleHashtags(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "hashtags.groups_only"))
+	}
+	args := strings.Fields(strings.ToLower(c.Text()))[1:]
+	f := hashtagFilter(c.Chat().ID)
+	if len(args) == 0 {
+		var b strings.Builder
+		b.WriteString(T(c, "hashtags.header"))
+		for _, source := range hashtagSources {
+			b.WriteString(T(c, "hashtags.line", T(c, "hashtags."+source), sourceState(c, *allowedSource(&f, source))))
+		}
+		b.WriteString(T(c, "hashtags.footer"))
+		return c.Send(b.String())
+	}
+	if len(args) != 2 || allowedSource(&f, args[0]) == nil || (args[1] != "on" && args[1] != "off") {
+		return c.Send(T(c, "hashtags.usage"))
+	}
+	*allowedSource(&f, args[0]) = args[1] == "on"
+	chatSettings(c.Chat().ID).Hashtags = &f
+	saveData()
+	return c.Send(T(c, "hashtags.line", T(c, "hashtags."+args[0]), sourceState(c, args[1] == "on")))
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const hashtagMemory = 48 * time.Hour
+
+var hashtagPattern = regexp.MustCompile(`#(!?)([A-Za-zА-Яа-я0-9_]+)`)
+
+func hashtagsKey(c tele.Context) string {
+	return fmt.Sprintf("hashtags:%d:%d", c.Chat().ID, c.Message().ID)
+}
+
+func seenHashtags(c tele.Context) map[string]bool {
+	seen := map[string]bool{}
+	raw, ok, err := kv.Get(hashtagsKey(c))
+	if err != nil || !ok {
+		return seen
+	}
+	for _, name := range strings.Fields(string(raw)) {
+		seen[name] = true
+	}
+	return seen
+}
+
+func rememberHashtags(c tele.Context, seen map[string]bool) {
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	kv.Set(hashtagsKey(c), []byte(strings.Join(names, " ")), hashtagMemory)
+}
+
+func pingHashtags(c tele.Context, seen map[string]bool) error {
+	if ignoreHashtags(c) {
+		return nil
+	}
+	text := c.Text()
+	matches := hashtagPattern.FindAllStringSubmatch(text, -1)
+	var responses []string
+	var pinged []*Tag
+	silent := true
+	forwarded := map[int64]bool{}
+	found := false
+	mention := func(tag *Tag, quiet bool) {
+		seen[strings.ToLower(tag.Name)] = true
+		found = true
+		recordMention(tag, c)
+		emitEvent(c.Chat().ID, eventTagMentioned, tag, &Subscriber{ID: c.Sender().ID, Username: c.Sender().Username}, text)
+		mentions, allQuiet := collectMentions(c, tag, quiet, forwarded)
+		if len(mentions) > 0 {
+			responses = append(responses, pingText(c, tag, mentions, "", text))
+			pinged = append(pinged, tag)
+			silent = silent && allQuiet
+		}
+	}
+	for _, match := range matches {
+		tag := tagService.Find(c.Chat().ID, match[2])
+		if tag == nil || seen[strings.ToLower(tag.Name)] || !allowedInTopic(tag, threadOf(c)) || !canPing(c, tag) {
+			continue
+		}
+		mention(tag, match[1] == "!" || tag.Silent)
+	}
+	for _, tag := range ruleTags(c, text, seen) {
+		mention(tag, tag.Silent)
+	}
+	if found {
+		rememberHashtags(c, seen)
+	}
+	if len(responses) == 0 {
+		if found {
+			saveStats()
+		}
+		return nil
+	}
+	notePings(c, len(pinged))
+	return sendPing(c, strings.Join(responses, "\n\n"), pinged, silent)
+}
+
+func onEdited(c tele.Context) error {
+	if c.Sender() == nil || time.Since(c.Message().Time()) > hashtagMemory || shadowMuted(c) {
+		return nil
+	}
+	return pingHashtags(c, seenHashtags(c))
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func startHealthServer(b *tele.Bot) {
+	addr := cfg.HTTP.HealthAddr
+	if addr == "" {
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	})
+	mux.HandleFunc("/readyz", func(w http.ResponseWriter, r *http.Request) {
+		if err := store.Check(); err != nil {
+			http.Error(w, "data: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		if _, err := b.Raw("getMe", nil); err != nil {
+			http.Error(w, "telegram: "+err.Error(), http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ready"))
+	})
+	mux.HandleFunc("/metrics", serveMetrics)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			slog.Error("HTTP-сервер проверок остановлен", "addr", addr, "err", err)
+		}
+	}()
+	slog.Info("🩺 Проверки здоровья доступны", "addr", addr)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	defaultHistorySize = 10
+	maxHistorySize     = 50
+)
+
+func handleHistory(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "history.groups_only"))
+	}
+	args := strings.Fields(c.Message().Payload)
+	if len(args) == 0 || len(args) > 2 {
+		return c.Send(T(c, "history.usage"))
+	}
+	limit := defaultHistorySize
+	if len(args) == 2 {
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n <= 0 {
+			return c.Send(T(c, "history.usage"))
+		}
+		limit = min(n, maxHistorySize)
+	}
+	tag := tagService.Find(c.Chat().ID, strings.TrimPrefix(args[0], "#"))
+	if tag == nil || !visibleTo(tag, c.Sender().ID) {
+		return tagNotFound(c, strings.TrimPrefix(args[0], "#"))
+	}
+	var events []MentionEvent
+	for i := len(data.Mentions) - 1; i >= 0 && len(events) < limit; i-- {
+		if ev := data.Mentions[i]; ev.ChatID == tag.ChatID && strings.EqualFold(ev.Tag, tag.Name) {
+			events = append(events, ev)
+		}
+	}
+	if len(events) == 0 {
+		return c.Send(T(c, "history.empty", esc(tag.Name), int(mentionRetention.Hours()/24)))
+	}
+	loc := chatLocation(c.Chat().ID)
+	chat := c.Chat()
+	if tag.ChatID != chat.ID {
+		chat = &tele.Chat{ID: tag.ChatID}
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "history.header", esc(tag.Name), len(events)))
+	for _, ev := range events {
+		line := fmt.Sprintf("%s — %s", ev.At.In(loc).Format("02.01 15:04"), esc(displayName(ev.UserID, ev.Username)))
+		if ev.MessageID != 0 {
+			if link := messageLink(chat, ev.MessageID); link != "" {
+				line += fmt.Sprintf(` <a href="%s">%s</a>`, link, T(c, "digest.open"))
+			}
+		}
+		b.WriteString(line + "\n")
+	}
+	return c.Send(b.String(), tele.NoPreview)
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+type catalog struct {
+	Messages map[string]string `json:"messages"`
+	Phrases  []string          `json:"phrases"`
+}
+
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+const langKey = "lang"
+
+var (
+	defaultLang = "ru"
+	catalogs    = map[string]*catalog{}
+)
+
+func readCatalogs(fsys fs.FS, dir string, into map[string]*catalog) error {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".json" {
+			continue
+		}
+		raw, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		var cat catalog
+		if err := json.Unmarshal(raw, &cat); err != nil {
+			return fmt.Errorf("%s: %w", entry.Name(), err)
+		}
+		lang := strings.TrimSuffix(entry.Name(), ".json")
+		base, ok := into[lang]
+		if !ok {
+			into[lang] = &cat
+			continue
+		}
+		for key, msg := range cat.Messages {
+			base.Messages[key] = msg
+		}
+		if len(cat.Phrases) > 0 {
+			base.Phrases = cat.Phrases
+		}
+	}
+	return nil
+}
+
+func loadCatalogs() error {
+	loaded := map[string]*catalog{}
+	if err := readCatalogs(localeFiles, "locales", loaded); err != nil {
+		return err
+	}
+	if dir := cfg.Locales.Dir; dir != "" {
+		if err := readCatalogs(os.DirFS(dir), ".", loaded); err != nil {
+			return fmt.Errorf("%s: %w", dir, err)
+		}
+	}
+	if _, ok := loaded[defaultLang]; !ok {
+		return fmt.Errorf("нет каталога для языка по умолчанию %q", defaultLang)
+	}
+	catalogs = loaded
+	return nil
+}
+
+func languages() []string {
+	langs := make([]string, 0, len(catalogs))
+	for lang := range catalogs {
+		langs = append(langs, lang)
+	}
+	sort.Strings(langs)
+	return langs
+}
+
+func chatLang(chatID int64) string {
+	if s, ok := data.Chats[chatID]; ok && s.Lang != "" {
+		if _, ok := catalogs[s.Lang]; ok {
+			return s.Lang
+		}
+	}
+	return defaultLang
+}
+
+func langOf(c tele.Context) string {
+	if lang, ok := c.Get(langKey).(string); ok {
+		return lang
+	}
+	chat := c.Chat()
+	if chat != nil {
+		if s, ok := data.Chats[chat.ID]; ok && s.Lang != "" {
+			return chatLang(chat.ID)
+		}
+	}
+	if (chat == nil || chat.Type == tele.ChatPrivate) && c.Sender() != nil {
+		if _, ok := catalogs[c.Sender().LanguageCode]; ok {
+			return c.Sender().LanguageCode
+		}
+	}
+	return defaultLang
+}
+
+func tr(lang, key string, args ...interface{}) string {
+	msg, ok := catalogs[lang].Messages[key]
+	if !ok {
+		msg, ok = catalogs[defaultLang].Messages[key]
+	}
+	if !ok {
+		return key
+	}
+	if len(args) == 0 {
+		return msg
+	}
+	return fmt.Sprintf(msg, args...)
+}
+
+func localized(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		c.Set(langKey, langOf(c))
+		return next(c)
+	}
+}
+
+func T(c tele.Context, key string, args ...interface{}) string {
+	return tr(langOf(c), key, args...)
+}
+
+func funnyPhrase(c tele.Context, tagName string) string {
+	phrases := catalogs[langOf(c)].Phrases
+	if len(phrases) == 0 {
+		phrases = catalogs[defaultLang].Phrases
+	}
+	phrase := phrases[rand.Intn(len(phrases))]
+	if strings.Contains(phrase, "%s") {
+		return fmt.Sprintf(phrase, tagName)
+	}
+	return phrase
+}
+
+func handleLang(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	available := strings.Join(languages(), ", ")
+	if len(args) == 0 {
+		return c.Send(T(c, "lang.current", T(c, "lang.name"), available))
+	}
+	lang := strings.ToLower(args[0])
+	if _, ok := catalogs[lang]; !ok {
+		return c.Send(T(c, "lang.unknown", available))
+	}
+	if c.Chat().Type != tele.ChatPrivate && !isChatAdmin(c, c.Chat().ID) {
+		return c.Send(T(c, "lang.denied"))
+	}
+	chatSettings(c.Chat().ID).Lang = lang
+	c.Set(langKey, chatLang(c.Chat().ID))
+	saveData()
+	return c.Send(T(c, "lang.set"))
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/tagservice"
+)
+
+var tagService tagservice.Service = tagservice.New(&data)
+
+func tagNotFound(c tele.Context, name string) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		if matches := tagService.Matches(c.Sender().ID, name); len(matches) > 1 {
+			var chats []string
+			for _, tag := range matches {
+				title := fmt.Sprint(tag.ChatID)
+				if s := data.Chats[tag.ChatID]; s != nil && s.Title != "" {
+					title = s.Title
+				}
+				chats = append(chats, "• "+esc(title))
+			}
+			return c.Send(T(c, "tag_ambiguous", esc(name), strings.Join(chats, "\n")))
+		}
+	}
+	return c.Send(T(c, "tag_not_found"))
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	btnInfoSubscribe   = tele.Btn{Unique: "info_sub"}
+	btnInfoUnsubscribe = tele.Btn{Unique: "info_unsub"}
+)
+
+func infoCard(c tele.Context, tag *Tag) (string, *tele.ReplyMarkup) {
+	zone := zoneOf(c)
+	description := tag.Description
+	if description == "" {
+		description = T(c, "info.no_description")
+	}
+	category := tag.Category
+	if category == "" {
+		category = T(c, "info.no_category")
+	}
+	creator := T(c, "info.unknown_creator")
+	if tag.CreatorName != "" {
+		creator = "@" + esc(tag.CreatorName)
+	}
+	lastUsed := T(c, "info.never")
+	if !tag.Stats.LastUsed.IsZero() {
+		lastUsed = tag.Stats.LastUsed.In(zone).Format("02.01.2006 15:04")
+	}
+	icon := tag.Emoji
+	if icon == "" {
+		icon = "🏷"
+	}
+	text := T(c, "info.card", icon, esc(tag.Name), esc(description), esc(category), creator,
+		tag.CreatedAt.In(zone).Format("02.01.2006"), len(tag.Subscribers), lastUsed,
+		T(c, "pingperm."+policyName(tag.PingPolicy)))
+
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(T(c, "info.subscribe"), btnInfoSubscribe.Unique, tag.Name),
+		markup.Data(T(c, "info.unsubscribe"), btnInfoUnsubscribe.Unique, tag.Name),
+	))
+	return text, markup
+}
+
+func handleInfo(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "info.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, strings.TrimPrefix(args[0], "#"))
+	if tag == nil || !visibleTo(tag, c.Sender().ID) {
+		return tagNotFound(c, strings.TrimPrefix(args[0], "#"))
+	}
+	text, markup := infoCard(c, tag)
+	return c.Send(text, markup)
+}
+
+func infoTag(c tele.Context) *Tag {
+	tag := tagService.Find(c.Chat().ID, c.Callback().Data)
+	if tag == nil {
+		c.Respond(&tele.CallbackResponse{Text: T(c, "tag_not_found")})
+	}
+	return tag
+}
+
+func refreshInfo(c tele.Context, tag *Tag, notice string) error {
+	c.Respond(&tele.CallbackResponse{Text: notice})
+	text, markup := infoCard(c, tag)
+	return c.Edit(text, markup)
+}
+
+func onInfoSubscribe(c tele.Context) error {
+	tag := infoTag(c)
+	if tag == nil {
+		return nil
+	}
+	userID := c.Sender().ID
+	switch {
+	case isBanned(tag, userID):
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "subscribe.banned")})
+	case isSubscribed(tag, userID):
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "subscribe.already")})
+	}
+	username := c.Sender().Username
+	if username == "" {
+		username = fmt.Sprintf("User%d", userID)
+	}
+	sub := Subscriber{ID: userID, Username: username}
+	if needsApproval(tag) {
+		c.Respond()
+		return requestJoin(c, tag, sub)
+	}
+	tagService.Subscribe(tag, sub)
+	saveTag("subscribe", tag)
+	emitEvent(c.Chat().ID, eventSubscriberJoin, tag, &sub, "")
+	notifyMembership(traceContext(c), tag, sub, true)
+	welcomeSubscriber(traceContext(c), tag, sub)
+	return refreshInfo(c, tag, T(c, "info.subscribed", tag.Name))
+}
+
+func onInfoUnsubscribe(c tele.Context) error {
+	tag := infoTag(c)
+	if tag == nil {
+		return nil
+	}
+	sub, ok := subscriberOf(tag, c.Sender().ID)
+	if !ok || !tagService.Unsubscribe(tag, sub.ID) {
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "info.not_subscribed")})
+	}
+	saveAction("unsubscribe", tag, sub.ID, &sub)
+	notifyMembership(traceContext(c), tag, sub, false)
+	return refreshInfo(c, tag, T(c, "info.unsubscribed", tag.Name))
+}
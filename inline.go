@@ -0,0 +1,58 @@
+package main
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const maxInlineResults = 50
+
+func onInlineQuery(c tele.Context) error {
+	query := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(c.Query().Text), "#"))
+	userID := c.Sender().ID
+
+	var matches []*Tag
+	for _, tag := range tagService.InChat(userID) {
+		if !visibleTo(tag, userID) {
+			continue
+		}
+		name := strings.ToLower(tag.Name)
+		if query == "" || strings.Contains(name, query) || strings.Contains(strings.ToLower(tag.Description), query) {
+			matches = append(matches, tag)
+		}
+	}
+	sort.SliceStable(matches, func(i, j int) bool {
+		pi := strings.HasPrefix(strings.ToLower(matches[i].Name), query)
+		pj := strings.HasPrefix(strings.ToLower(matches[j].Name), query)
+		if pi != pj {
+			return pi
+		}
+		return len(matches[i].Subscribers) > len(matches[j].Subscribers)
+	})
+	if len(matches) > maxInlineResults {
+		matches = matches[:maxInlineResults]
+	}
+
+	results := make(tele.Results, 0, len(matches))
+	for i, tag := range matches {
+		text := "#" + tag.Name
+		if tag.Description != "" {
+			text += " — " + tag.Description
+		}
+		result := &tele.ArticleResult{
+			Title:       "#" + tag.Name,
+			Text:        text,
+			Description: T(c, "inline.description", len(tag.Subscribers), tag.Description),
+		}
+		result.SetResultID(strconv.Itoa(i))
+		results = append(results, result)
+	}
+	return c.Answer(&tele.QueryResponse{
+		Results:    results,
+		CacheTime:  30,
+		IsPersonal: true,
+	})
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+const integrityReportLines = 30
+
+func verifyData() (repaired, problems []string) {
+	repaired, problems = storage.Verify(&data, true)
+	for _, msg := range repaired {
+		slog.Warn("🩹 Исправлено при проверке данных", "fix", msg)
+	}
+	for _, msg := range problems {
+		slog.Error("Проблема в данных требует внимания", "problem", msg)
+	}
+	if len(repaired) > 0 {
+		saveData()
+	}
+	return repaired, problems
+}
+
+func integrityList(items []string) string {
+	if len(items) > integrityReportLines {
+		items = append(items[:integrityReportLines:integrityReportLines],
+			fmt.Sprintf("… +%d", len(items)-integrityReportLines))
+	}
+	return esc("• " + strings.Join(items, "\n• "))
+}
+
+func reportIntegrity(repaired, problems []string) {
+	chatID := ownerID
+	if chatID == 0 {
+		chatID = errorsChatID
+	}
+	if chatID == 0 || reportBot == nil || len(repaired)+len(problems) == 0 {
+		return
+	}
+	text := tr(defaultLang, "report.integrity", len(repaired), len(problems))
+	if len(repaired) > 0 {
+		text += tr(defaultLang, "report.integrity_fixed", integrityList(repaired))
+	}
+	if len(problems) > 0 {
+		text += tr(defaultLang, "report.integrity_problems", integrityList(problems))
+	}
+	if _, err := reportBot.Send(&tele.Chat{ID: chatID}, text); err != nil {
+		slog.Warn("Не удалось отправить отчёт о проверке данных", "chat_id", chatID, "err", err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"tagger/storage"
+)
+
+var (
+	journalFile = "journal.jsonl"
+	journalMu   sync.Mutex
+	journalOut  *os.File
+)
+
+func openJournal() error {
+	journalFile = cfg.Storage.Journal
+	f, err := os.OpenFile(journalFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	journalOut = f
+	return nil
+}
+
+func journalTag(op string, tag *Tag) {
+	journalEntry(storage.JournalEntry{Op: op, Tag: tag})
+}
+
+func journalEntry(entry storage.JournalEntry) {
+	data.JournalSeq++
+	entry.Seq, entry.At = data.JournalSeq, time.Now()
+	markDirectory(entry.Tag)
+	line, err := storage.EncodeJournal(entry)
+	if err == nil {
+		journalMu.Lock()
+		_, err = journalOut.Write(line)
+		if err == nil {
+			err = journalOut.Sync()
+		}
+		journalMu.Unlock()
+	}
+	if err != nil {
+		slog.Error("Не удалось записать журнал", "op", entry.Op, "tag", entry.Tag.Name, "err", err)
+		captureError(nil, "storage", err, "op", entry.Op)
+		metricStorageErrors.add(1)
+	}
+}
+
+func saveTag(op string, tag *Tag) {
+	journalTag(op, tag)
+	saveData()
+}
+
+func saveAction(op string, tag *Tag, by int64, sub *Subscriber) {
+	journalEntry(storage.JournalEntry{Op: op, Tag: tag, By: by, Sub: sub})
+	saveData()
+}
+
+func replayJournal() error {
+	entries, err := storage.ReadJournal(journalFile)
+	if err != nil {
+		return err
+	}
+	replayed := 0
+	for _, entry := range entries {
+		if entry.Seq <= data.JournalSeq {
+			continue
+		}
+		if err := loadChats(entry.Tag.ChatID); err != nil {
+			return err
+		}
+		storage.ApplyJournal(&data, entry)
+		data.JournalSeq = entry.Seq
+		replayed++
+	}
+	if replayed > 0 {
+		slog.Info("📜 Восстановлены изменения из журнала", "entries", replayed)
+		saveData()
+	}
+	return nil
+}
+
+func compactJournal(seq int64) {
+	journalMu.Lock()
+	defer journalMu.Unlock()
+	entries, err := storage.ReadJournal(journalFile)
+	if err != nil {
+		slog.Warn("Не удалось прочитать журнал", "err", err)
+		return
+	}
+	var kept []byte
+	dropped := 0
+	for _, entry := range entries {
+		if entry.Seq > seq || time.Since(entry.At) < undoWindow {
+			line, _ := storage.EncodeJournal(entry)
+			kept = append(kept, line...)
+		} else {
+			dropped++
+		}
+	}
+	if dropped == 0 {
+		return
+	}
+	tmp := journalFile + ".tmp"
+	if err := os.WriteFile(tmp, kept, 0644); err != nil {
+		slog.Warn("Не удалось сжать журнал", "err", err)
+		return
+	}
+	journalOut.Close()
+	if err := os.Rename(tmp, journalFile); err != nil {
+		slog.Warn("Не удалось сжать журнал", "err", err)
+	}
+	f, err := os.OpenFile(journalFile, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		slog.Error("Не удалось открыть журнал", "err", err)
+		return
+	}
+	journalOut = f
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var removedChatGrace = 30 * 24 * time.Hour
+
+func botLeft(m *tele.ChatMember) bool {
+	return m != nil && (m.Role == tele.Left || m.Role == tele.Kicked)
+}
+
+func onBotMembership(c tele.Context, upd *tele.ChatMemberUpdate) {
+	if upd.NewChatMember == nil || upd.NewChatMember.User == nil || upd.NewChatMember.User.ID != c.Bot().Me.ID {
+		return
+	}
+	chatID := upd.Chat.ID
+	if botLeft(upd.NewChatMember) {
+		now := time.Now()
+		chatSettings(chatID).RemovedAt = &now
+		invalidateAdmins(chatID)
+		saveData()
+		slog.Info("➖ Меня удалили из чата", "chat_id", chatID, "title", upd.Chat.Title,
+			"purge_after", now.Add(removedChatGrace).Format(time.RFC3339))
+		return
+	}
+	readdChat(chatID)
+}
+
+func readdChat(chatID int64) {
+	if s := data.Chats[chatID]; s != nil && s.RemovedAt != nil {
+		s.RemovedAt = nil
+		saveData()
+		slog.Info("↩️ Меня вернули в чат, данные сохранены", "chat_id", chatID)
+	}
+}
+
+func purgeRemovedChats(b *tele.Bot) {
+	cutoff := time.Now().Add(-removedChatGrace)
+	for chatID, s := range data.Chats {
+		if s.RemovedAt == nil || s.RemovedAt.After(cutoff) {
+			continue
+		}
+		if err := loadChats(chatID); err != nil {
+			slog.Error("Не удалось удалить данные покинутого чата", "chat_id", chatID, "err", err)
+			continue
+		}
+		purgeChat(chatID)
+		slog.Info("🗑 Данные покинутого чата удалены", "chat_id", chatID, "title", s.Title)
+	}
+}
+
+func purgeChat(chatID int64) {
+	kept := []*Tag{}
+	for _, tag := range data.Tags {
+		if tag.ChatID != chatID {
+			kept = append(kept, tag)
+			continue
+		}
+		if tag.Active() {
+			tagService.Remove(tag)
+		}
+		journalTag("purge", tag)
+	}
+	data.Tags = kept
+	tagService.DropChat(chatID)
+
+	mentions := data.Mentions[:0]
+	for _, ev := range data.Mentions {
+		if ev.ChatID != chatID {
+			mentions = append(mentions, ev)
+		}
+	}
+	data.Mentions = mentions
+	for userID, items := range data.Digests {
+		left := items[:0]
+		for _, item := range items {
+			if item.ChatID != chatID {
+				left = append(left, item)
+			}
+		}
+		if len(left) == 0 {
+			delete(data.Digests, userID)
+		} else {
+			data.Digests[userID] = left
+		}
+	}
+	forgetRules(chatID)
+	delete(data.Chats, chatID)
+	saveData()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+type fakeAPI struct {
+	mu    sync.Mutex
+	calls map[string]int
+}
+
+func (f *fakeAPI) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	f.mu.Lock()
+	f.calls[method]++
+	f.mu.Unlock()
+	result := "true"
+	if strings.HasPrefix(method, "send") || method == "editMessageText" || method == "forwardMessage" {
+		result = `{"message_id":1,"date":0,"chat":{"id":-1001,"type":"supergroup"}}`
+	}
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Header:     http.Header{"Content-Type": {"application/json"}},
+		Body:       io.NopCloser(strings.NewReader(`{"ok":true,"result":` + result + `}`)),
+		Request:    req,
+	}, nil
+}
+
+var (
+	harnessOnce sync.Once
+	harnessBot  *tele.Bot
+	harnessAPI  = &fakeAPI{calls: map[string]int{}}
+)
+
+func setupHarness(tb testing.TB) *tele.Bot {
+	tb.Helper()
+	harnessOnce.Do(func() {
+		cfg = defaultConfig()
+		applyConfig()
+		if err := loadCatalogs(); err != nil {
+			tb.Fatal(err)
+		}
+		bot, err := tele.NewBot(tele.Settings{
+			Token:     "harness",
+			Offline:   true,
+			ParseMode: tele.ModeHTML,
+			Client:    &http.Client{Transport: harnessAPI},
+		})
+		if err != nil {
+			tb.Fatal(err)
+		}
+		harnessBot = bot
+		startOutbox(bot)
+		kv = newMemoryEphemeral()
+	})
+	return harnessBot
+}
+
+type loadGen struct {
+	rnd   *rand.Rand
+	chats []int64
+	tags  map[int64][]string
+	users int
+	seq   int
+}
+
+var fillerWords = strings.Fields("привет all как дела today raid вечером кто идёт ping please meeting в 19:00 check this out")
+
+func newLoadGen(seed int64, chats, tagsPerChat, subsPerTag, users int) *loadGen {
+	g := &loadGen{rnd: rand.New(rand.NewSource(seed)), tags: map[int64][]string{}, users: users}
+	data = Data{SchemaVersion: storage.SchemaVersion, Tags: []*Tag{}, Chats: map[int64]*ChatSettings{}}
+	now := time.Now()
+	for i := 0; i < chats; i++ {
+		chatID := -1001000000000 - int64(i)
+		g.chats = append(g.chats, chatID)
+		for j := 0; j < tagsPerChat; j++ {
+			name := fmt.Sprintf("tag%d", j)
+			tag := &Tag{Name: name, ChatID: chatID, CreatorID: g.user(), Description: "synthetic", Subscribers: []Subscriber{}, CreatedAt: now}
+			for k := 0; k < subsPerTag; k++ {
+				id := g.user()
+				if !isSubscribed(tag, id) {
+					tag.Subscribers = append(tag.Subscribers, Subscriber{ID: id, Username: fmt.Sprintf("user%d", id)})
+				}
+			}
+			data.Tags = append(data.Tags, tag)
+			g.tags[chatID] = append(g.tags[chatID], name)
+		}
+	}
+	tagService.Rebuild()
+	return g
+}
+
+func (g *loadGen) user() int64 {
+	return int64(g.rnd.Intn(g.users) + 1)
+}
+
+func (g *loadGen) chat() int64 {
+	return g.chats[g.rnd.Intn(len(g.chats))]
+}
+
+func (g *loadGen) tagName(chatID int64) string {
+	names := g.tags[chatID]
+	return names[g.rnd.Intn(len(names))]
+}
+
+func (g *loadGen) message(maxTags int) *tele.Message {
+	g.seq++
+	chatID := g.chat()
+	words := make([]string, 0, 12)
+	for i := 0; i < 8; i++ {
+		words = append(words, fillerWords[g.rnd.Intn(len(fillerWords))])
+	}
+	for i := g.rnd.Intn(maxTags + 1); i > 0; i-- {
+		words = append(words, "#"+g.tagName(chatID))
+	}
+	g.rnd.Shuffle(len(words), func(i, j int) { words[i], words[j] = words[j], words[i] })
+	id := g.user()
+	return &tele.Message{
+		ID:       g.seq,
+		Sender:   &tele.User{ID: id, Username: fmt.Sprintf("user%d", id)},
+		Chat:     &tele.Chat{ID: chatID, Type: tele.ChatSuperGroup, Title: "load"},
+		Text:     strings.Join(words, " "),
+		Unixtime: time.Now().Unix(),
+	}
+}
+
+func TestLoad(t *testing.T) {
+	updates, _ := strconv.Atoi(os.Getenv("LOAD_UPDATES"))
+	if updates <= 0 {
+		t.Skip("set LOAD_UPDATES to run the load test")
+	}
+	bot := setupHarness(t)
+	g := newLoadGen(time.Now().UnixNano(), 200, 500, 20, 20000)
+	start := time.Now()
+	for i := 0; i < updates; i++ {
+		c := bot.NewContext(tele.Update{Message: g.message(3)})
+		dataMu.Lock()
+		err := pingHashtags(c, map[string]bool{})
+		dataMu.Unlock()
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	elapsed := time.Since(start)
+	harnessAPI.mu.Lock()
+	defer harnessAPI.mu.Unlock()
+	t.Logf("%d updates in %s (%.0f/s), %d mention events, API calls: %v",
+		updates, elapsed.Round(time.Millisecond), float64(updates)/elapsed.Seconds(), len(data.Mentions), harnessAPI.calls)
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var requestLogLevel = slog.LevelInfo
+
+func parseLevel(name string) slog.Level {
+	switch strings.ToLower(name) {
+	case "debug":
+		return slog.LevelDebug
+	case "warn", "warning":
+		return slog.LevelWarn
+	case "error":
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+func setupLogging() {
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, &slog.HandlerOptions{Level: parseLevel(cfg.LogLevel)})))
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	captureError(nil, "fatal", fmt.Errorf("%s: %w", msg, err))
+	flushSentry()
+	os.Exit(1)
+}
+
+func commandOf(c tele.Context) string {
+	switch {
+	case c.Callback() != nil:
+		return "callback:" + strings.TrimSpace(strings.SplitN(c.Callback().Data, "|", 2)[0])
+	case c.Query() != nil:
+		return "inline"
+	case c.Message() != nil && strings.HasPrefix(c.Message().Text, "/"):
+		cmd := strings.Fields(c.Message().Text)[0]
+		return strings.SplitN(cmd, "@", 2)[0]
+	}
+	return ""
+}
+
+func logRequest(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		start := time.Now()
+		err := next(c)
+		attrs := append(contextAttrs(c), "latency_ms", time.Since(start).Milliseconds())
+		if err != nil {
+			attrs = append(attrs, "err", err)
+		}
+		slog.Log(traceContext(c), requestLogLevel, "Обновление обработано", attrs...)
+		return err
+	}
+}
+
+func contextAttrs(c tele.Context) []any {
+	attrs := []any{"command", commandOf(c)}
+	if chat := c.Chat(); chat != nil {
+		attrs = append(attrs, "chat_id", chat.ID)
+	}
+	if user := c.Sender(); user != nil {
+		attrs = append(attrs, "user_id", user.ID)
+	}
+	return attrs
+}
+
+func onBotError(err error, c tele.Context) {
+	if c == nil {
+		slog.Error("Ошибка Telegram", "err", err)
+		captureError(nil, "telegram", err)
+	} else {
+		slog.Error("Ошибка обработки обновления", append(contextAttrs(c), "err", err)...)
+		captureError(c, "handler", err)
+	}
+	if repeatedError(err.Error()) {
+		reportError(c, err, nil)
+	}
+}
+
+func recoverPanics(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) (err error) {
+		defer func() {
+			r := recover()
+			if r == nil {
+				return
+			}
+			stack := debug.Stack()
+			err = fmt.Errorf("panic: %v", r)
+			slog.Error("Паника в обработчике", append(contextAttrs(c), "err", err, "stack", string(stack))...)
+			reportError(c, err, stack)
+			captureError(c, "panic", err, "stack", string(stack))
+			if c.Callback() != nil {
+				c.Respond(&tele.CallbackResponse{Text: T(c, "error.internal_plain")})
+			} else if c.Query() == nil {
+				c.Send(T(c, "error.internal"))
+			}
+			err = nil
+		}()
+		return next(c)
+	}
+}
@@ -1,262 +1,442 @@
 package main
 
 import (
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io/ioutil"
-	"log"
-	"math/rand"
+	"log/slog"
 	"os"
-	"regexp"
+	"os/signal"
 	"strings"
+	"syscall"
 	"time"
 
-	"github.com/joho/godotenv"
 	tele "gopkg.in/telebot.v3"
-)
-
-type Subscriber struct {
-	ID       int64  `json:"id"`
-	Username string `json:"username"`
-}
-
-type Tag struct {
-	Name        string       `json:"name"`
-	CreatorID   int64        `json:"creator_id"`
-	CreatorName string       `json:"creator_name"`
-	Description string       `json:"description"`
-	Subscribers []Subscriber `json:"subscribers"`
-	CreatedAt   time.Time    `json:"created_at"`
-}
 
-type Data struct {
-	Tags []Tag `json:"tags"`
-}
-
-var (
-	data     Data
-	dataFile = "tags.json"
-	funnyPhrases = []string{
-		"Ау! Китайские сыновья солнца, вас тут пингуют.",
-		"Просыпайтесь, воины тега #%s!",
-		"Снова вы, #%s? Ну давайте...",
-		"Собрание #%s начинается. Кто опоздает — тот кодит в пятницу вечером!",
-		"🔔 Призыв по тегу #%s! Сбор у обелиска.",
-	}
+	"tagger/storage"
 )
 
+var data Data
+
 func loadData() error {
-	if _, err := os.Stat(dataFile); os.IsNotExist(err) {
-		data = Data{Tags: []Tag{}}
-		return saveData()
-	}
-	file, err := ioutil.ReadFile(dataFile)
+	_, span := tracer.Start(context.Background(), "storage.load")
+	err := store.Load(&data)
+	endSpan(span, err)
+	span.End()
 	if err != nil {
 		return err
 	}
-	return json.Unmarshal(file, &data)
+	tagService.Rebuild()
+	return nil
 }
 
 func saveData() error {
-	file, err := json.MarshalIndent(data, "", "  ")
-	if err != nil {
-		return err
-	}
-	return ioutil.WriteFile(dataFile, file, 0644)
+	dataDirty = true
+	statsDirty = true
+	return nil
 }
 
-func findTag(name string) *Tag {
-	name = strings.ToLower(name)
-	for i, tag := range data.Tags {
-		if strings.ToLower(tag.Name) == name {
-			return &data.Tags[i]
-		}
-	}
-	return nil
+func deleteTag(c tele.Context, tag *Tag) error {
+	notifySubscribers(traceContext(c), c.Chat().ID, tag, c.Sender().ID,
+		T(c, "farewell.deleted", esc(tag.Name), int(deletedRetention.Hours()/24)))
+	now := time.Now()
+	tagService.Remove(tag)
+	tag.DeletedAt = &now
+	tag.DeletedBy = c.Sender().ID
+	saveAction("delete", tag, c.Sender().ID, nil)
+	return c.Send(T(c, "delete.done",
+		esc(tag.Name), int(deletedRetention.Hours()/24), esc(tag.Name)))
 }
 
-func cleanEmptyTags() {
-	newTags := []Tag{}
-	for _, tag := range data.Tags {
-		if len(tag.Subscribers) > 0 {
-			newTags = append(newTags, tag)
+const noSubscribeFlag = "--nosub"
+
+func withoutFlag(args []string, flag string) ([]string, bool) {
+	kept := args[:0:0]
+	found := false
+	for _, arg := range args {
+		if strings.EqualFold(arg, flag) {
+			found = true
+			continue
 		}
+		kept = append(kept, arg)
 	}
-	data.Tags = newTags
-	saveData()
+	return kept, found
 }
 
 func main() {
-	_ = godotenv.Load()
-	token := os.Getenv("TELEGRAM_BOT_TOKEN")
-	if token == "" {
-		log.Fatal("TELEGRAM_BOT_TOKEN not set")
+	var err error
+	cfg, err = loadConfig()
+	setupLogging()
+	if err != nil {
+		fatal("Ошибка в настройках", err)
 	}
+	if cfg.File != "" {
+		slog.Info("⚙️ Настройки загружены", "file", cfg.File)
+	}
+	setupSentry()
+	setupTracing()
+	applyConfig()
 
 	bot, err := tele.NewBot(tele.Settings{
-		Token:  token,
-		Poller: &tele.LongPoller{Timeout: 10 * time.Second},
+		Token: cfg.Token,
+		Poller: &tele.LongPoller{
+			Timeout:        10 * time.Second,
+			AllowedUpdates: []string{"message", "edited_message", "callback_query", "inline_query", "my_chat_member", "chat_member", "message_reaction", "poll_answer"},
+		},
+		ParseMode: tele.ModeHTML,
+		OnError:   onBotError,
+		Client:    newAPIClient(),
 	})
 	if err != nil {
-		log.Fatal(err)
+		fatal("Не удалось создать бота", err)
 	}
 
+	setupErrorReports(bot)
+	startOutbox(bot)
+
+	if kv, err = openEphemeral(); err != nil {
+		fatal("Не удалось подключиться к Redis", err)
+	}
+	if store, err = openStorage(); err != nil {
+		if errors.Is(err, storage.ErrLocked) {
+			fatal("Данные уже использует другой экземпляр бота — остановите его перед запуском", err)
+		}
+		fatal("Не удалось открыть хранилище", err)
+	}
 	if err := loadData(); err != nil {
-		log.Fatal(err)
+		fatal("Не удалось загрузить данные", err)
+	}
+	if err := openJournal(); err != nil {
+		fatal("Не удалось открыть журнал", err)
 	}
+	if err := replayJournal(); err != nil {
+		fatal("Не удалось восстановить журнал", err)
+	}
+	repaired, problems := verifyData()
+	tagService.Rebuild()
+	if err := loadCatalogs(); err != nil {
+		fatal("Не удалось загрузить переводы", err)
+	}
+	loadBlocklist()
+	if err := loadS3Config(); err != nil {
+		fatal("Не удалось настроить выгрузку в S3", err)
+	}
+	if readOnly = cfg.ReadOnly; readOnly {
+		slog.Info("Бот запущен в режиме только для чтения")
+	}
+	bot.Poller = reactionPoller(bot)
+	bot.Use(traceUpdate, countUpdates, logRequest, lockData, ensureChat, localized, recoverPanics, queued, topicAware, chatGuard, throttled, trackChat)
 
 	bot.Handle("/start", func(c tele.Context) error {
-		return c.Send("👋 Привет! Я бот для тегов. Команды:\n\n"+
-			"/ct <тег> [описание] — создать тег\n"+
-			"/st <тег> — подписаться\n"+
-			"/dt <тег> — удалить\n"+
-			"/lt — все теги\n"+
-			"/mt — мои теги\n"+
-			"/stats — статистика\n\nТег упоминается через #тег")
+		return c.Send(T(c, "help"))
 	})
 
 	bot.Handle("/ct", func(c tele.Context) error {
-		args := strings.Fields(c.Text())[1:]
+		args, noSubscribe := withoutFlag(strings.Fields(c.Text())[1:], noSubscribeFlag)
+		emoji := ""
+		if len(args) > 1 && isEmoji(args[0]) {
+			emoji, args = args[0], args[1:]
+		}
 		if len(args) == 0 {
-			return c.Send("❗ Укажи название тега: /ct <тег> [описание]")
+			return c.Send(T(c, "create.usage"))
+		}
+		category, tagName := splitCategory(args[0])
+		if tagName == "" {
+			return c.Send(T(c, "create.usage"))
+		}
+		if tagService.Find(c.Chat().ID, tagName) != nil {
+			return c.Send(T(c, "create.exists"))
 		}
-		tagName := args[0]
-		if findTag(tagName) != nil {
-			return c.Send("⚠️ Такой тег уже существует!")
+		if existing := findCategory(c.Chat().ID, category); existing != "" {
+			category = existing
 		}
 		description := ""
 		if len(args) > 1 {
 			description = strings.Join(args[1:], " ")
 		}
-		tag := Tag{
+		if word := blockedWord(c.Chat().ID, tagName, category, description); word != "" {
+			return c.Send(T(c, "blockword.rejected"))
+		}
+		if limit := dailyTagCap(c.Chat().ID); limit > 0 && createdToday(c.Chat().ID) >= limit {
+			return c.Send(T(c, "create.daily_cap", limit))
+		}
+		if onCooldown(fmt.Sprintf("ct:%d", c.Sender().ID), createCooldown) {
+			return c.Send(T(c, "create.cooldown", int(createCooldown.Seconds())))
+		}
+		tag := &Tag{
 			Name:        tagName,
+			ChatID:      c.Chat().ID,
 			CreatorID:   c.Sender().ID,
 			CreatorName: c.Sender().Username,
 			Description: description,
+			Category:    category,
+			Emoji:       emoji,
 			Subscribers: []Subscriber{},
 			CreatedAt:   time.Now(),
+			KeepEmpty:   noSubscribe,
 		}
 		data.Tags = append(data.Tags, tag)
-		saveData()
-		return c.Send(fmt.Sprintf("🌟 *Новый тег создан!\n👤 Создатель:* @%s\n🏷️ *Тег:* `#%s`\n📜 *Описание:* %s",
-			c.Sender().Username, tagName, description), tele.ModeMarkdown)
-	})
+		tagService.Add(tag)
+		creator := Subscriber{ID: c.Sender().ID, Username: c.Sender().Username}
+		if creator.Username == "" {
+			creator.Username = fmt.Sprintf("User%d", creator.ID)
+		}
+		if !noSubscribe {
+			tagService.Subscribe(tag, creator)
+		}
+		saveTag("create", tag)
+		emitEvent(c.Chat().ID, eventTagCreated, tag, &creator, description)
+		text := T(c, "create.done", esc(c.Sender().Username), esc(tagName), esc(description))
+		if !noSubscribe {
+			text += "\n\n" + T(c, "create.subscribed", noSubscribeFlag)
+		}
+		if !needsApproval(tag) {
+			text += "\n" + T(c, "create.react", subscribeReaction, int(reactionWindow.Hours()))
+		}
+		sendQueue.sendThen(c, c.Chat(), text, func(msg *tele.Message, err error) error {
+			if err != nil {
+				return err
+			}
+			tag.Announcement = &Announcement{MessageID: msg.ID, At: time.Now()}
+			saveData()
+			return nil
+		}, sendOptions(c))
+		return nil
+	}, writable)
 
 	bot.Handle("/st", func(c tele.Context) error {
 		args := strings.Fields(c.Text())[1:]
 		if len(args) == 0 {
-			return c.Send("❗ Укажи тег: /st <тег>")
+			return c.Send(T(c, "subscribe.usage"))
 		}
-		tag := findTag(args[0])
+		tag := tagService.Find(c.Chat().ID, args[0])
 		if tag == nil {
-			return c.Send("⛔ Тег не найден!")
+			return tagNotFound(c, args[0])
+		}
+		if isBanned(tag, c.Sender().ID) {
+			return c.Send(T(c, "subscribe.banned"))
 		}
 		for _, sub := range tag.Subscribers {
 			if sub.ID == c.Sender().ID {
-				return c.Send("✅ Ты уже подписан!")
+				return c.Send(T(c, "subscribe.already"))
 			}
 		}
 		username := c.Sender().Username
 		if username == "" {
 			username = fmt.Sprintf("User%d", c.Sender().ID)
 		}
-		tag.Subscribers = append(tag.Subscribers, Subscriber{ID: c.Sender().ID, Username: username})
-		saveData()
-		return c.Send(fmt.Sprintf("📬 Подписка на `#%s` оформлена!", tag.Name), tele.ModeMarkdown)
-	})
+		if needsApproval(tag) {
+			return requestJoin(c, tag, Subscriber{ID: c.Sender().ID, Username: username})
+		}
+		sub := Subscriber{ID: c.Sender().ID, Username: username}
+		tagService.Subscribe(tag, sub)
+		saveTag("subscribe", tag)
+		emitEvent(c.Chat().ID, eventSubscriberJoin, tag, &sub, "")
+		notifyMembership(traceContext(c), tag, sub, true)
+		welcomeSubscriber(traceContext(c), tag, sub)
+		return c.Send(T(c, "subscribe.done", esc(tag.Name)))
+	}, writable)
 
 	bot.Handle("/dt", func(c tele.Context) error {
 		args := strings.Fields(c.Text())[1:]
 		if len(args) == 0 {
-			return c.Send("❗ Укажи тег: /dt <тег>")
+			return c.Send(T(c, "delete.usage"))
 		}
-		tag := findTag(args[0])
+		tag := tagService.Find(c.Chat().ID, args[0])
 		if tag == nil {
-			return c.Send("⛔ Тег не найден!")
+			return tagNotFound(c, args[0])
 		}
-		if tag.CreatorID != c.Sender().ID {
-			return c.Send("🚫 Только создатель может удалить тег!")
+		if len(tag.Subscribers) >= deleteConfirmThreshold {
+			return askConfirmation(c, T(c, "delete.confirm",
+				tag.Name, len(tag.Subscribers)), "delete", tag.Name)
 		}
-		newTags := []Tag{}
-		for _, t := range data.Tags {
-			if strings.ToLower(t.Name) != strings.ToLower(tag.Name) {
-				newTags = append(newTags, t)
-			}
+		return deleteTag(c, tag)
+	}, managerOnly("delete.denied"), writable)
+	confirmActions["delete"] = func(c tele.Context, name string) error {
+		tag := tagService.Find(c.Chat().ID, name)
+		if tag == nil {
+			return tagNotFound(c, name)
 		}
-		data.Tags = newTags
-		saveData()
-		return c.Send(fmt.Sprintf("🗑️ Тег `#%s` удалён!", tag.Name), tele.ModeMarkdown)
-	})
+		if !canManage(c, tag) {
+			return c.Send(T(c, "delete.denied"))
+		}
+		return deleteTag(c, tag)
+	}
+	bot.Handle(&btnConfirmYes, onConfirmYes, writable)
+	bot.Handle(&btnConfirmNo, onConfirmNo)
 
 	bot.Handle("/lt", func(c tele.Context) error {
-		cleanEmptyTags()
-		if len(data.Tags) == 0 {
-			return c.Send("📭 Пока тегов нет!")
+		if strings.EqualFold(strings.TrimSpace(c.Message().Payload), "archived") {
+			return listArchived(c)
+		}
+		var tags []*Tag
+		for _, tag := range tagService.InChat(c.Chat().ID) {
+			if visibleTo(tag, c.Sender().ID) {
+				tags = append(tags, tag)
+			}
+		}
+		if len(tags) == 0 {
+			return c.Send(T(c, "list.empty"))
 		}
 		var b strings.Builder
-		b.WriteString("📚 *Список тегов:*\n")
-		for _, tag := range data.Tags {
-			b.WriteString(fmt.Sprintf("`#%s` (%d): %s\n", tag.Name, len(tag.Subscribers), tag.Description))
+		b.WriteString(T(c, "list.header"))
+		writeTagList(&b, langOf(c), tags)
+		if archived := len(archivedIn(c.Chat().ID)); archived > 0 {
+			b.WriteString(T(c, "list.archived", archived))
 		}
-		return c.Send(b.String(), tele.ModeMarkdown)
+		return c.Send(b.String())
 	})
 
 	bot.Handle("/mt", func(c tele.Context) error {
 		var b strings.Builder
-		b.WriteString("📌 *Твои теги:*\n")
+		b.WriteString(T(c, "my.header"))
 		found := false
-		for _, tag := range data.Tags {
-			for _, sub := range tag.Subscribers {
-				if sub.ID == c.Sender().ID {
-					b.WriteString(fmt.Sprintf("`#%s` — %s\n", tag.Name, tag.Description))
-					found = true
-				}
-			}
+		for _, tag := range tagService.Subscriptions(c.Sender().ID) {
+			b.WriteString(fmt.Sprintf("<code>#%s</code> — %s\n", esc(tag.Name), esc(tag.Description)))
+			found = true
 		}
 		if !found {
-			b.WriteString("_Ты не подписан ни на один тег._")
+			b.WriteString(T(c, "my.empty"))
 		}
-		return c.Send(b.String(), tele.ModeMarkdown)
+		return c.Send(b.String())
 	})
 
-	bot.Handle("/stats", func(c tele.Context) error {
-		cleanEmptyTags()
-		var b strings.Builder
-		b.WriteString("📊 *Статистика:*\n")
-		for _, tag := range data.Tags {
-			b.WriteString(fmt.Sprintf("`#%s` — %d подписчиков\n", tag.Name, len(tag.Subscribers)))
-		}
-		return c.Send(b.String(), tele.ModeMarkdown)
-	})
+	bot.Handle("/stats", handleStats)
 
 	bot.Handle(tele.OnText, func(c tele.Context) error {
-		text := c.Text()
-		re := regexp.MustCompile(`#([A-Za-zА-Яа-я0-9_]+)`)
-		matches := re.FindAllStringSubmatch(text, -1)
-		var responses []string
-		for _, match := range matches {
-			tagName := match[1]
-			tag := findTag(tagName)
-			if tag == nil {
-				continue
-			}
-			var mentions []string
-			for _, sub := range tag.Subscribers {
-				if sub.Username != "" && sub.Username != fmt.Sprintf("User%d", sub.ID) {
-					mentions = append(mentions, fmt.Sprintf("@%s", sub.Username))
-				}
-			}
-			if len(mentions) > 0 {
-				phrase := fmt.Sprintf(funnyPhrases[rand.Intn(len(funnyPhrases))], tagName)
-				responses = append(responses, fmt.Sprintf("%s\n%s", strings.Join(mentions, " "), phrase))
-			}
+		if handled, err := handlePrefsInput(c); handled {
+			return err
 		}
-		if len(responses) > 0 {
-			return c.Send(strings.Join(responses, "\n\n"))
+		if handled, err := handleMenuInput(c); handled {
+			return err
 		}
-		return nil
+		if shadowMuted(c) {
+			return nil
+		}
+		return pingHashtags(c, map[string]bool{})
 	})
+	bot.Handle(tele.OnEdited, onEdited)
+
+	bot.Handle("/et", handleEditTag, managerOnly("edit.denied"), writable)
+	bot.Handle("/rt", handleRenameTag, managerOnly("rename.denied"), writable)
+	bot.Handle("/blockword", handleBlockWord, writable)
+	bot.Handle("/tagcap", handleTagCap, withArgs(adminOnly("tagcap.denied")), writable)
+	bot.Handle("/hashtags", handleHashtags, withArgs(adminOnly("hashtags.denied")), writable)
+	bot.Handle("/cleantrigger", handleCleanTrigger, withArgs(adminOnly("cleantrigger.denied")), writable)
+	bot.Handle("/directory", handleDirectory, writable)
+	bot.Handle("/rule", handleRule, withArgs(adminOnly("rule.denied")), writable)
+	bot.Handle("/watchmembers", handleWatchMembers, managerOnly("watch.denied"), writable)
+	bot.Handle("/cat", handleCategory, managerOnly("category.denied"), writable)
+	bot.Handle("/silent", handleSilent, managerOnly("silent.denied"), writable)
+	bot.Handle("/pingperm", handlePingPolicy, withValue(managerOnly("pingperm.denied")), writable)
+	bot.Handle("/template", handleTemplate, withValue(managerOnly("template.denied")), writable)
+	bot.Handle("/setwelcome", handleSetWelcome, withValue(managerOnly("onboard.denied")), writable)
+	bot.Handle("/ping", handlePing)
+	bot.Handle("/preview", handlePreview)
+	bot.Handle("/event", handleEvent, writable)
+	bot.Handle(&btnRSVP, onRSVP, writable)
+	bot.Handle("/poll", handlePoll, writable)
+	bot.Handle(tele.OnPollAnswer, onPollAnswer)
+	bot.Handle("/tagfrompoll", handleTagFromPoll, writable)
+	bot.Handle("/antispam", handleAntiSpam, withArgs(adminOnly("spam.denied")), writable)
+	bot.Handle("/topic", handleTopic, managerOnly("topic.denied"), writable)
+	bot.Handle("/notifyme", handleNotifyMe, writable)
+	bot.Handle("/addmod", handleAddMod, writable)
+	bot.Handle("/delmod", handleDelMod, writable)
+	bot.Handle("/kickfrom", handleKickFrom, managerOnly("kick.denied"), writable)
+	bot.Handle("/banfrom", handleBanFrom, managerOnly("ban.denied"), writable)
+	bot.Handle("/unbanfrom", handleUnbanFrom, managerOnly("unban.denied"), writable)
+	bot.Handle("/access", handleAccess, managerOnly("access.denied"), writable)
+	bot.Handle("/invite", handleInvite, managerOnly("invite.denied"), writable)
+	bot.Handle(&btnJoinApprove, onJoinApprove, writable)
+	bot.Handle(&btnJoinDeny, onJoinDeny, writable)
+	bot.Handle(tele.OnQuery, onInlineQuery)
+	bot.Handle(tele.OnMigration, onMigration)
+	bot.Handle(tele.OnAddedToGroup, onAddedToGroup)
+	bot.Handle(&btnWelcomeCreate, onWelcomeCreate)
+	bot.Handle(&btnWelcomeSettings, onWelcomeSettings)
+	bot.Handle(tele.OnChatMember, onChatMemberUpdate)
+	bot.Handle(tele.OnMyChatMember, onChatMemberUpdate)
+	bot.Handle("/denychat", handleDenyChat, ownerOnly)
+	bot.Handle("/allowchat", handleAllowChat, ownerOnly)
+	bot.Handle("/chats", handleChats, ownerOnly)
+	bot.Handle("/leavechat", handleLeaveChat, ownerOnly)
+	bot.Handle("/globalstats", handleGlobalStats, ownerOnly)
+	bot.Handle("/broadcast", handleBroadcast, ownerOnly)
+	bot.Handle("/readonly", handleReadOnly, ownerOnly)
+	bot.Handle("/backupnow", handleBackupNow, ownerOnly)
+	bot.Handle("/reload", handleReload, ownerOnly)
+	bot.Handle("/version", handleVersion, ownerOnly)
+	bot.Handle("/uptime", handleUptime, ownerOnly)
+	bot.Handle("/botstats", handleBotStats, ownerOnly)
+	bot.Handle("/restore", handleRestore, managesTag("restore.denied", findRemovedTag), writable)
+	bot.Handle("/undo", handleUndo, writable)
+	bot.Handle("/clone", handleClone, unlessOwner(managerOnly("clone.denied")), writable)
+	bot.Handle("/global", handleGlobal, managesTag("global.denied", func(c tele.Context, name string) *Tag {
+		return tagService.Local(c.Chat().ID, name)
+	}), writable)
+	bot.Handle("/link", handleLink, ownerOnly, writable)
+	bot.Handle("/unlink", handleUnlink, ownerOnly, writable)
+	bot.Handle("/communities", handleCommunities, ownerOnly)
+	bot.Handle("/export", handleExport)
+	bot.Handle("/import", handleImport)
+	bot.Handle("/apitoken", handleAPIToken, writable)
+	bot.Handle("/webhook", handleWebhook, writable)
+	bot.Handle("/mydata", handleMyData)
+	bot.Handle("/prefs", handlePrefs)
+	bot.Handle("/summary", handleSummary, writable)
+	bot.Handle("/forgetme", handleForgetMe, writable)
+	confirmActions["forgetme"] = confirmForgetMe
+	bot.Handle(tele.OnDocument, onDocument)
+	bot.Handle("/trending", handleTrending)
+	bot.Handle("/top", handleTop)
+	bot.Handle("/history", handleHistory)
+	bot.Handle("/lang", handleLang)
+	bot.Handle("/tz", handleTimezone, writable)
+	bot.Handle("/weekly", handleWeekly, writable)
+	bot.Handle("/ack", handleAck)
+	bot.Handle("/nudge", handleNudge)
+	bot.Handle(&btnAck, onAckButton)
+	bot.Handle(&btnSnooze, onSnooze, writable)
+	bot.Handle("/info", handleInfo)
+	bot.Handle(&btnInfoSubscribe, onInfoSubscribe, writable)
+	bot.Handle(&btnInfoUnsubscribe, onInfoUnsubscribe, writable)
+	bot.Handle("/menu", handleMenu)
+	bot.Handle(&btnMenu, onMenu)
+	bot.Handle(&btnMenuAct, onMenuAction, writable)
+
+	schedule("stale-tags", time.Hour, checkStaleTags)
+	schedule("purge-deleted", time.Hour, purgeDeletedTags)
+	schedule("archive-empty", time.Hour, archiveEmptyTags)
+	schedule("purge-archived", time.Hour, purgeArchivedTags)
+	schedule("purge-removed-chats", time.Hour, purgeRemovedChats)
+	schedule("digests", digestInterval, sendDigests)
+	schedule("daily-summaries", 10*time.Minute, sendSummaries)
+	schedule("weekly-reports", 10*time.Minute, sendWeeklyReports)
+	schedule("subscriber-history", time.Hour, recordSubscriberHistory)
+	schedule("directory", 30*time.Second, refreshDirectories)
+	schedule("events", time.Minute, runEvents)
+	schedule("backup", backupInterval, runBackup)
+	startPersistence()
+	reportIntegrity(repaired, problems)
+	startScheduler(bot)
+	startHealthServer(bot)
+	startDebugServer()
+	startAPIServer(bot)
+	registerCommands(bot)
+	watchReload(bot)
+
+	go func() {
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		<-sig
+		slog.Info("🛑 Останавливаюсь")
+		bot.Stop()
+	}()
 
-	log.Println("🤖 Бот запущен...")
+	slog.Info("🤖 Бот запущен", "version", version, "commit", buildCommit())
 	bot.Start()
+	stopPersistence()
+	flushSentry()
+	shutdownTracing()
 }
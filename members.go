@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	memberNotifyDM   = "dm"
+	memberNotifyChat = "chat"
+)
+
+func subscriberOf(tag *Tag, userID int64) (Subscriber, bool) {
+	for _, sub := range tag.Subscribers {
+		if sub.ID == userID {
+			return sub, true
+		}
+	}
+	return Subscriber{}, false
+}
+
+func notifyMembership(ctx context.Context, tag *Tag, user Subscriber, joined bool) {
+	if tag.MemberNotify == "" || user.ID == tag.CreatorID {
+		return
+	}
+	key := "members.left"
+	if joined {
+		key = "members.joined"
+	}
+	text := tr(chatLang(tag.ChatID), key, esc(user.Username), esc(tag.Name), len(tag.Subscribers))
+	if tag.MemberNotify == memberNotifyChat && tag.ChatID != 0 {
+		sendQueue.send(ctx, &tele.Chat{ID: tag.ChatID}, text, tele.Silent)
+		return
+	}
+	sendQueue.send(ctx, &tele.User{ID: tag.CreatorID}, text)
+}
+
+func handleWatchMembers(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) < 2 {
+		return c.Send(T(c, "watch.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	switch strings.ToLower(args[1]) {
+	case memberNotifyDM:
+		tag.MemberNotify = memberNotifyDM
+	case memberNotifyChat:
+		tag.MemberNotify = memberNotifyChat
+	case "off":
+		tag.MemberNotify = ""
+	default:
+		return c.Send(T(c, "watch.usage"))
+	}
+	saveTag("update", tag)
+	switch tag.MemberNotify {
+	case memberNotifyDM:
+		return c.Send(T(c, "watch.dm", esc(tag.Name)))
+	case memberNotifyChat:
+		return c.Send(T(c, "watch.chat", esc(tag.Name)))
+	}
+	return c.Send(T(c, "watch.off", esc(tag.Name)))
+}
+
+func isBanned(tag *Tag, userID int64) bool {
+	for _, b := range tag.Banned {
+		if b.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func handleKickFrom(c tele.Context) error {
+	tag, user, err := parseTagAndUser(c, T(c, "kick.usage"))
+	if tag == nil {
+		return err
+	}
+	if !tagService.Unsubscribe(tag, user.ID) {
+		return c.Send(T(c, "kick.not_subscribed"))
+	}
+	saveAction("kick", tag, c.Sender().ID, &user)
+	notifyMembership(traceContext(c), tag, user, false)
+	return c.Send(T(c, "kick.done", esc(user.Username), esc(tag.Name)))
+}
+
+func handleBanFrom(c tele.Context) error {
+	tag, user, err := parseTagAndUser(c, T(c, "ban.usage"))
+	if tag == nil {
+		return err
+	}
+	if user.ID == tag.CreatorID {
+		return c.Send(T(c, "ban.creator"))
+	}
+	if isBanned(tag, user.ID) {
+		return c.Send(T(c, "ban.already"))
+	}
+	left := tagService.Unsubscribe(tag, user.ID)
+	tag.Banned = append(tag.Banned, user)
+	saveTag("update", tag)
+	if left {
+		notifyMembership(traceContext(c), tag, user, false)
+	}
+	return c.Send(T(c, "ban.done", esc(user.Username), esc(tag.Name)))
+}
+
+func handleUnbanFrom(c tele.Context) error {
+	tag, user, err := parseTagAndUser(c, T(c, "unban.usage"))
+	if tag == nil {
+		return err
+	}
+	banned := []Subscriber{}
+	for _, b := range tag.Banned {
+		if b.ID != user.ID {
+			banned = append(banned, b)
+		}
+	}
+	if len(banned) == len(tag.Banned) {
+		return c.Send(T(c, "unban.not_banned"))
+	}
+	tag.Banned = banned
+	saveTag("update", tag)
+	return c.Send(T(c, "unban.done", esc(user.Username), esc(tag.Name)))
+}
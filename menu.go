@@ -0,0 +1,288 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	btnMenu    = tele.Btn{Unique: "menu"}
+	btnMenuAct = tele.Btn{Unique: "menu_act"}
+)
+
+func menuEditKey(userID int64) string {
+	return "menuedit:" + strconv.FormatInt(userID, 10)
+}
+
+func menuRef(tag *Tag) string {
+	return fmt.Sprintf("%d:%s", tag.ChatID, tag.Name)
+}
+
+func menuTag(ref string) *Tag {
+	chat, name, ok := strings.Cut(ref, ":")
+	if !ok {
+		return nil
+	}
+	chatID, err := strconv.ParseInt(chat, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return tagService.Local(chatID, name)
+}
+
+func menuChatTitle(c tele.Context, chatID int64) string {
+	if s := data.Chats[chatID]; s != nil && s.Title != "" {
+		return s.Title
+	}
+	if chatID == 0 {
+		return T(c, "menu.all_chats")
+	}
+	return strconv.FormatInt(chatID, 10)
+}
+
+func createdBy(userID int64) []*Tag {
+	var tags []*Tag
+	for _, tag := range data.Tags {
+		if tag.Active() && tag.CreatorID == userID {
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+func menuShow(c tele.Context, text string, markup *tele.ReplyMarkup) error {
+	if c.Callback() != nil {
+		c.Respond()
+		return c.Edit(text, markup)
+	}
+	return c.Send(text, markup)
+}
+
+func menuBack(markup *tele.ReplyMarkup, c tele.Context, view string) tele.Row {
+	return markup.Row(markup.Data(T(c, "menu.back"), btnMenu.Unique, view))
+}
+
+func handleMenu(c tele.Context) error {
+	if c.Chat().Type != tele.ChatPrivate {
+		return c.Send(T(c, "menu.private_only"))
+	}
+	return menuHome(c)
+}
+
+func menuHome(c tele.Context) error {
+	userID := c.Sender().ID
+	byChat := map[int64]int{}
+	for _, tag := range tagService.Subscriptions(userID) {
+		byChat[tag.ChatID]++
+	}
+	chats := make([]int64, 0, len(byChat))
+	for id := range byChat {
+		chats = append(chats, id)
+	}
+	sort.Slice(chats, func(i, j int) bool { return menuChatTitle(c, chats[i]) < menuChatTitle(c, chats[j]) })
+
+	markup := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	for _, id := range chats {
+		label := fmt.Sprintf("💬 %s (%d)", menuChatTitle(c, id), byChat[id])
+		rows = append(rows, markup.Row(markup.Data(label, btnMenu.Unique, "chat|"+strconv.FormatInt(id, 10))))
+	}
+	created := len(createdBy(userID))
+	if created > 0 {
+		rows = append(rows, markup.Row(markup.Data(T(c, "menu.btn_mine", created), btnMenu.Unique, "mine|")))
+	}
+	rows = append(rows, markup.Row(
+		markup.Data(T(c, "menu.btn_prefs"), btnMenu.Unique, "prefs|"),
+		markup.Data(T(c, "menu.btn_close"), btnMenu.Unique, "close|"),
+	))
+	markup.Inline(rows...)
+	return menuShow(c, T(c, "menu.home", len(tagService.Subscriptions(userID)), created), markup)
+}
+
+func menuChat(c tele.Context, chatID int64) error {
+	userID := c.Sender().ID
+	var tags []*Tag
+	for _, tag := range tagService.Subscriptions(userID) {
+		if tag.ChatID == chatID {
+			tags = append(tags, tag)
+		}
+	}
+	if len(tags) == 0 {
+		return menuHome(c)
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name) })
+	markup := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	for _, tag := range tags {
+		bell := "🔔"
+		if isMuted(userID, tag) {
+			bell = "🔕"
+		}
+		rows = append(rows, markup.Row(
+			markup.Data(fmt.Sprintf("%s %s#%s", bell, tagIcon(tag), tag.Name), btnMenuAct.Unique, "mute|"+menuRef(tag)),
+			markup.Data(T(c, "menu.btn_unsubscribe"), btnMenuAct.Unique, "unsub|"+menuRef(tag)),
+		))
+	}
+	rows = append(rows, menuBack(markup, c, "home|"))
+	markup.Inline(rows...)
+	return menuShow(c, T(c, "menu.chat", esc(menuChatTitle(c, chatID))), markup)
+}
+
+func menuMine(c tele.Context) error {
+	tags := createdBy(c.Sender().ID)
+	if len(tags) == 0 {
+		return menuHome(c)
+	}
+	sort.Slice(tags, func(i, j int) bool { return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name) })
+	markup := &tele.ReplyMarkup{}
+	var rows []tele.Row
+	for _, tag := range tags {
+		label := fmt.Sprintf("%s#%s · %s (%d)", tagIcon(tag), tag.Name, menuChatTitle(c, tag.ChatID), len(tag.Subscribers))
+		rows = append(rows, markup.Row(markup.Data(label, btnMenu.Unique, "tag|"+menuRef(tag))))
+	}
+	rows = append(rows, menuBack(markup, c, "home|"))
+	markup.Inline(rows...)
+	return menuShow(c, T(c, "menu.mine"), markup)
+}
+
+func menuTagCard(c tele.Context, tag *Tag) error {
+	description := tag.Description
+	if description == "" {
+		description = T(c, "info.no_description")
+	}
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(
+			markup.Data(T(c, "menu.btn_edit"), btnMenuAct.Unique, "edit|"+menuRef(tag)),
+			markup.Data(T(c, "menu.btn_delete"), btnMenu.Unique, "confirm|"+menuRef(tag)),
+		),
+		menuBack(markup, c, "mine|"),
+	)
+	return menuShow(c, T(c, "menu.tag", tagIcon(tag), esc(tag.Name), esc(menuChatTitle(c, tag.ChatID)),
+		esc(description), len(tag.Subscribers), tag.Stats.Mentions), markup)
+}
+
+func menuConfirmDelete(c tele.Context, tag *Tag) error {
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(
+		markup.Row(markup.Data(T(c, "menu.btn_delete_yes"), btnMenuAct.Unique, "delete|"+menuRef(tag))),
+		menuBack(markup, c, "tag|"+menuRef(tag)),
+	)
+	return menuShow(c, T(c, "menu.confirm_delete", esc(tag.Name), len(tag.Subscribers)), markup)
+}
+
+func onMenu(c tele.Context) error {
+	view, arg, _ := strings.Cut(c.Callback().Data, "|")
+	switch view {
+	case "chat":
+		chatID, _ := strconv.ParseInt(arg, 10, 64)
+		return menuChat(c, chatID)
+	case "mine":
+		return menuMine(c)
+	case "tag", "confirm":
+		tag := menuTag(arg)
+		if tag == nil || tag.CreatorID != c.Sender().ID {
+			c.Respond(&tele.CallbackResponse{Text: T(c, "tag_not_found")})
+			return menuMine(c)
+		}
+		if view == "confirm" {
+			return menuConfirmDelete(c, tag)
+		}
+		return menuTagCard(c, tag)
+	case "prefs":
+		c.Respond()
+		return showPrefsMenu(c)
+	case "close":
+		c.Respond()
+		return c.Delete()
+	}
+	return menuHome(c)
+}
+
+func onMenuAction(c tele.Context) error {
+	action, ref, _ := strings.Cut(c.Callback().Data, "|")
+	tag := menuTag(ref)
+	if tag == nil {
+		c.Respond(&tele.CallbackResponse{Text: T(c, "tag_not_found")})
+		return menuHome(c)
+	}
+	userID := c.Sender().ID
+	switch action {
+	case "mute":
+		p := userPrefs(userID)
+		if isMuted(userID, tag) {
+			kept := p.Muted[:0]
+			for _, name := range p.Muted {
+				if !strings.EqualFold(name, tag.Name) {
+					kept = append(kept, name)
+				}
+			}
+			p.Muted = kept
+		} else {
+			p.Muted = append(p.Muted, tag.Name)
+		}
+		saveData()
+		return menuChat(c, tag.ChatID)
+	case "unsub":
+		sub, ok := subscriberOf(tag, userID)
+		if !ok || !tagService.Unsubscribe(tag, userID) {
+			c.Respond(&tele.CallbackResponse{Text: T(c, "info.not_subscribed")})
+			return menuChat(c, tag.ChatID)
+		}
+		saveAction("unsubscribe", tag, userID, &sub)
+		notifyMembership(traceContext(c), tag, sub, false)
+		c.Respond(&tele.CallbackResponse{Text: T(c, "info.unsubscribed", tag.Name)})
+		return menuChat(c, tag.ChatID)
+	}
+	if tag.CreatorID != userID {
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "menu.not_creator"), ShowAlert: true})
+	}
+	switch action {
+	case "edit":
+		kv.Set(menuEditKey(userID), []byte(ref), prefsStateTTL)
+		c.Respond()
+		return c.Send(T(c, "menu.edit_prompt", esc(tag.Name)))
+	case "delete":
+		lang := chatLang(tag.ChatID)
+		notifySubscribers(traceContext(c), tag.ChatID, tag, userID, tr(lang, "farewell.deleted", esc(tag.Name), int(deletedRetention.Hours()/24)))
+		now := time.Now()
+		tagService.Remove(tag)
+		tag.DeletedAt = &now
+		tag.DeletedBy = userID
+		saveAction("delete", tag, userID, nil)
+		c.Respond(&tele.CallbackResponse{Text: T(c, "menu.deleted", tag.Name)})
+		return menuMine(c)
+	}
+	return c.Respond()
+}
+
+func handleMenuInput(c tele.Context) (bool, error) {
+	if c.Chat().Type != tele.ChatPrivate || c.Sender() == nil {
+		return false, nil
+	}
+	raw, ok, err := kv.Get(menuEditKey(c.Sender().ID))
+	if err != nil || !ok {
+		return false, nil
+	}
+	kv.Delete(menuEditKey(c.Sender().ID))
+	tag := menuTag(string(raw))
+	if tag == nil || tag.CreatorID != c.Sender().ID {
+		return true, c.Send(T(c, "tag_not_found"))
+	}
+	if readOnly {
+		return true, c.Send(T(c, "readonly.rejected"))
+	}
+	description := strings.TrimSpace(c.Text())
+	if word := blockedWord(tag.ChatID, description); word != "" {
+		return true, c.Send(T(c, "blockword.rejected"))
+	}
+	tag.Description = description
+	saveTag("update", tag)
+	c.Send(T(c, "edit.done", esc(tag.Name), esc(description)))
+	return true, menuTagCard(c, tag)
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+const metricHours = 24
+
+type counter struct {
+	name  string
+	help  string
+	mu    sync.Mutex
+	total int64
+	hours [metricHours]int64
+	stamp [metricHours]int64
+}
+
+var (
+	metricUpdates        = &counter{name: "chinatagger_updates_total", help: "Updates handled."}
+	metricHandlerErrors  = &counter{name: "chinatagger_handler_errors_total", help: "Updates whose handler returned an error."}
+	metricTelegramCalls  = &counter{name: "chinatagger_telegram_requests_total", help: "Bot API requests, getUpdates excluded."}
+	metricTelegramErrors = &counter{name: "chinatagger_telegram_errors_total", help: "Bot API requests that failed."}
+	metricMentions       = &counter{name: "chinatagger_mentions_delivered_total", help: "Subscribers notified of a mention."}
+	metricStorageErrors  = &counter{name: "chinatagger_storage_errors_total", help: "Failed saves and journal writes."}
+
+	allCounters = []*counter{metricUpdates, metricHandlerErrors, metricTelegramCalls, metricTelegramErrors, metricMentions, metricStorageErrors}
+)
+
+func (m *counter) add(n int64) {
+	hour := time.Now().Unix() / 3600
+	slot := hour % metricHours
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stamp[slot] != hour {
+		m.stamp[slot], m.hours[slot] = hour, 0
+	}
+	m.hours[slot] += n
+	m.total += n
+}
+
+func (m *counter) lastDay() int64 {
+	hour := time.Now().Unix() / 3600
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var sum int64
+	for i, stamp := range m.stamp {
+		if hour-stamp < metricHours {
+			sum += m.hours[i]
+		}
+	}
+	return sum
+}
+
+func (m *counter) value() int64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.total
+}
+
+func countUpdates(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		metricUpdates.add(1)
+		err := next(c)
+		if err != nil {
+			metricHandlerErrors.add(1)
+		}
+		return err
+	}
+}
+
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if path.Base(req.URL.Path) == "getUpdates" {
+		return resp, err
+	}
+	metricTelegramCalls.add(1)
+	if err != nil || resp.StatusCode >= 400 {
+		metricTelegramErrors.add(1)
+	}
+	return resp, err
+}
+
+func writeMetrics(w io.Writer) {
+	for _, m := range allCounters {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s counter\n%s %d\n", m.name, m.help, m.name, m.name, m.value())
+	}
+	fmt.Fprintf(w, "# HELP chinatagger_uptime_seconds Seconds since start.\n# TYPE chinatagger_uptime_seconds gauge\nchinatagger_uptime_seconds %d\n",
+		int64(time.Since(startedAt).Seconds()))
+}
+
+func serveMetrics(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	writeMetrics(w)
+}
+
+func pathSize(p string) int64 {
+	var size int64
+	filepath.Walk(p, func(_ string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			size += info.Size()
+		}
+		return nil
+	})
+	return size
+}
+
+func storageSize() (int64, bool) {
+	if _, ok := store.(*storage.Postgres); ok {
+		return 0, false
+	}
+	return pathSize(dataPath()) + pathSize(journalFile), true
+}
+
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+func handleBotStats(c tele.Context) error {
+	full, err := allData()
+	if err != nil {
+		return err
+	}
+	tags, subs := 0, 0
+	for _, tag := range full.Tags {
+		if tag.Active() {
+			tags++
+			subs += len(tag.Subscribers)
+		}
+	}
+	calls, failed := metricTelegramCalls.lastDay(), metricTelegramErrors.lastDay()
+	errorRate := 0.0
+	if calls > 0 {
+		errorRate = float64(failed) * 100 / float64(calls)
+	}
+	size := T(c, "botstats.size_unknown")
+	if n, ok := storageSize(); ok {
+		size = formatBytes(n)
+	}
+	return c.Send(T(c, "botstats.text", len(groupChats()), tags, subs, metricMentions.lastDay(),
+		metricUpdates.lastDay(), calls, failed, errorRate, metricStorageErrors.lastDay(), size, formatUptime(time.Since(startedAt))))
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/telefake"
+)
+
+func TestThrottleLimitsCommands(t *testing.T) {
+	setupHarness(t)
+	userWindows = map[int64]*userWindow{}
+	calls := 0
+	h := throttled(func(tele.Context) error {
+		calls++
+		return nil
+	})
+	chat := &tele.Chat{ID: -1, Type: tele.ChatSuperGroup}
+	user := &tele.User{ID: 77}
+	var last *telefake.Context
+	for i := 0; i < userRateLimit+3; i++ {
+		last = telefake.Message(chat, user, "/lt")
+		if err := h(last); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if calls != userRateLimit {
+		t.Fatalf("handler ran %d times, want %d", calls, userRateLimit)
+	}
+	if len(last.Sent) != 0 {
+		t.Fatal("the slow-down notice should be sent only once per window")
+	}
+	if err := h(telefake.Message(chat, user, "just chatting")); err != nil || calls != userRateLimit+1 {
+		t.Fatal("plain messages must not be throttled")
+	}
+	userWindows[user.ID].start = time.Now().Add(-throttleWindow)
+	if err := h(telefake.Message(chat, user, "/lt")); err != nil || calls != userRateLimit+2 {
+		t.Fatal("the limit did not reset after the window")
+	}
+}
+
+func TestAdminOnlyWithArgs(t *testing.T) {
+	setupHarness(t)
+	ran := false
+	h := withArgs(adminOnly("rule.denied"))(func(tele.Context) error {
+		ran = true
+		return nil
+	})
+	group := &tele.Chat{ID: -5, Type: tele.ChatSuperGroup}
+	user := &tele.User{ID: 5}
+	adminMu.Lock()
+	adminCache[group.ID] = adminEntry{ids: map[int64]bool{}, fetched: time.Now()}
+	adminMu.Unlock()
+
+	if err := h(telefake.Message(group, user, "/rule")); err != nil || !ran {
+		t.Fatal("a bare command should reach the handler")
+	}
+	ran = false
+	c := telefake.Message(group, user, "/rule del 1")
+	if err := h(c); err != nil || ran {
+		t.Fatal("a non-admin changed settings")
+	}
+	if c.LastText() != tr(defaultLang, "rule.denied") {
+		t.Fatalf("unexpected reply %q", c.LastText())
+	}
+	if err := h(telefake.Message(&tele.Chat{ID: 5, Type: tele.ChatPrivate}, user, "/rule del 1")); err != nil || !ran {
+		t.Fatal("private chats should not need admin rights")
+	}
+}
+
+func TestManagerOnlyGuardsTagChanges(t *testing.T) {
+	tag := testTag("raid", alice)
+	tag.Moderators = []Subscriber{{ID: bob.ID, Username: bob.Username}}
+	chat := withTags(t, tag)
+	adminMu.Lock()
+	adminCache[chat.ID] = adminEntry{ids: map[int64]bool{}, fetched: time.Now()}
+	adminMu.Unlock()
+	ran := false
+	h := withValue(managerOnly("template.denied"))(func(tele.Context) error {
+		ran = true
+		return nil
+	})
+
+	if err := h(telefake.Message(chat, alice, "/template raid")); err != nil || !ran {
+		t.Fatal("viewing the template should not need rights")
+	}
+	ran = false
+	c := telefake.Message(chat, alice, "/template raid hello")
+	if err := h(c); err != nil || ran || c.LastText() != tr(defaultLang, "template.denied") {
+		t.Fatal("a subscriber changed the tag")
+	}
+	if err := h(telefake.Message(chat, bob, "/template #raid hello")); err != nil || !ran {
+		t.Fatal("a moderator was refused")
+	}
+	ran = false
+	if err := h(telefake.Message(chat, alice, "/template nope hello")); err != nil || !ran {
+		t.Fatal("unknown tags should reach the handler for its not-found reply")
+	}
+}
+
+func TestRetryableRepeatsOnlyIdempotentServerErrors(t *testing.T) {
+	cases := []struct {
+		method string
+		status int
+		want   bool
+	}{
+		{"sendMessage", http.StatusTooManyRequests, true},
+		{"sendMessage", http.StatusBadGateway, false},
+		{"editMessageText", http.StatusBadGateway, true},
+		{"getChatMember", http.StatusServiceUnavailable, true},
+		{"deleteMessage", http.StatusBadRequest, false},
+	}
+	for _, tc := range cases {
+		if got := retryable(tc.method, &http.Response{StatusCode: tc.status}, nil); got != tc.want {
+			t.Errorf("retryable(%s, %d) = %v, want %v", tc.method, tc.status, got, tc.want)
+		}
+	}
+}
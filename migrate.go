@@ -0,0 +1,153 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func remapChat(from, to int64) int {
+	changed := 0
+	for _, tag := range data.Tags {
+		if tag.ChatID == from {
+			tag.ChatID = to
+			changed++
+		}
+		if tag.LastPing != nil && tag.LastPing.ChatID == from {
+			tag.LastPing.ChatID = to
+		}
+	}
+	for i := range data.Mentions {
+		if data.Mentions[i].ChatID == from {
+			data.Mentions[i].ChatID = to
+		}
+	}
+	for _, items := range data.Digests {
+		remapItems(items, from, to)
+	}
+	for _, p := range data.Users {
+		if p.Summary != nil {
+			remapItems(p.Summary.Items, from, to)
+		}
+	}
+	if s, ok := data.Chats[from]; ok {
+		if target, exists := data.Chats[to]; exists {
+			mergeSettings(target, s)
+		} else {
+			data.Chats[to] = s
+		}
+		delete(data.Chats, from)
+		changed++
+	}
+	return changed
+}
+
+func remapItems(items []DigestItem, from, to int64) {
+	for i := range items {
+		if items[i].ChatID == from {
+			items[i].ChatID = to
+		}
+	}
+}
+
+func mergeSettings(dst, src *ChatSettings) {
+	if dst.Lang == "" {
+		dst.Lang = src.Lang
+	}
+	if dst.Title == "" {
+		dst.Title = src.Title
+	}
+	if src.LastSeen.After(dst.LastSeen) {
+		dst.LastSeen = src.LastSeen
+	}
+	if dst.APITokenHash == "" {
+		dst.APITokenHash, dst.APITokenBy = src.APITokenHash, src.APITokenBy
+	}
+	if dst.Webhook == nil {
+		dst.Webhook = src.Webhook
+	}
+	if dst.Timezone == "" {
+		dst.Timezone = src.Timezone
+	}
+	if dst.Spam == nil {
+		dst.Spam = src.Spam
+	}
+	for _, word := range src.BlockedWords {
+		if !containsFold(dst.BlockedWords, word) {
+			dst.BlockedWords = append(dst.BlockedWords, word)
+		}
+	}
+	if dst.DailyTagCap == 0 {
+		dst.DailyTagCap = src.DailyTagCap
+	}
+	if dst.Weekly == nil {
+		dst.Weekly = src.Weekly
+	}
+	dst.SubsHistory = append(src.SubsHistory, dst.SubsHistory...)
+	if dst.Community == "" {
+		dst.Community = src.Community
+	}
+	if dst.Hashtags == nil {
+		dst.Hashtags = src.Hashtags
+	}
+	dst.DeleteTriggers = dst.DeleteTriggers || src.DeleteTriggers
+	if dst.Directory == 0 {
+		dst.Directory = src.Directory
+	}
+	used, next := map[int]bool{}, 1
+	for _, ev := range dst.Events {
+		used[ev.ID] = true
+		next = max(next, ev.ID+1)
+	}
+	for _, ev := range src.Events {
+		next = max(next, ev.ID+1)
+	}
+	for _, ev := range src.Events {
+		if used[ev.ID] {
+			ev.ID = next
+			next++
+		}
+		dst.Events = append(dst.Events, ev)
+	}
+	dst.Polls = append(dst.Polls, src.Polls...)
+	used, next = map[int]bool{}, 1
+	for _, rule := range dst.Rules {
+		used[rule.ID] = true
+		next = max(next, rule.ID+1)
+	}
+	for _, rule := range src.Rules {
+		next = max(next, rule.ID+1)
+	}
+	for _, rule := range src.Rules {
+		if used[rule.ID] {
+			rule.ID = next
+			next++
+		}
+		dst.Rules = append(dst.Rules, rule)
+	}
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
+
+func onMigration(c tele.Context) error {
+	from, to := c.Migration()
+	if from == 0 || to == 0 || from == to {
+		return nil
+	}
+	if err := loadChats(from, to); err != nil {
+		return err
+	}
+	changed := remapChat(from, to)
+	tagService.Rebuild()
+	saveData()
+	slog.Info("🔀 Чат стал супергруппой", "from", from, "to", to, "changed", changed)
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import "tagger/storage"
+
+type (
+	Subscriber    = storage.Subscriber
+	Tag           = storage.Tag
+	Data          = storage.Data
+	Ping          = storage.Ping
+	Announcement  = storage.Announcement
+	TagStats      = storage.TagStats
+	MentionEvent  = storage.MentionEvent
+	ChatSettings  = storage.ChatSettings
+	DigestItem    = storage.DigestItem
+	Webhook       = storage.Webhook
+	UserPrefs     = storage.UserPrefs
+	SpamLimits    = storage.SpamLimits
+	Weekly        = storage.Weekly
+	HashtagFilter = storage.HashtagFilter
+	Event         = storage.Event
+	EventResponse = storage.EventResponse
+	TrackedPoll   = storage.TrackedPoll
+	PollVote      = storage.PollVote
+	TagRule       = storage.TagRule
+	DailySummary  = storage.DailySummary
+	SubsPoint     = storage.SubsPoint
+	Storage       = storage.Storage
+)
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+type userTagRef struct {
+	ChatID   int64  `json:"chat_id"`
+	Tag      string `json:"tag"`
+	Delivery string `json:"delivery,omitempty"`
+}
+
+type userCreatedTag struct {
+	ChatID      int64     `json:"chat_id"`
+	Name        string    `json:"name"`
+	Description string    `json:"description"`
+	CreatedAt   time.Time `json:"created_at"`
+	Status      string    `json:"status"`
+}
+
+type userPing struct {
+	ChatID int64     `json:"chat_id"`
+	Tag    string    `json:"tag"`
+	At     time.Time `json:"at"`
+}
+
+type userData struct {
+	User          Subscriber       `json:"user"`
+	ExportedAt    time.Time        `json:"exported_at"`
+	Prefs         *UserPrefs       `json:"prefs,omitempty"`
+	Subscriptions []userTagRef     `json:"subscriptions"`
+	CreatedTags   []userCreatedTag `json:"created_tags"`
+	Moderates     []userTagRef     `json:"moderates,omitempty"`
+	BannedFrom    []userTagRef     `json:"banned_from,omitempty"`
+	Requests      []userTagRef     `json:"pending_requests,omitempty"`
+	MentionCounts map[string]int   `json:"mention_counts,omitempty"`
+	Mentions      []MentionEvent   `json:"mentions"`
+	Pings         []userPing       `json:"pings,omitempty"`
+	Acks          []userPing       `json:"acks,omitempty"`
+	Digest        []DigestItem     `json:"pending_digest,omitempty"`
+}
+
+func tagStatus(tag *Tag) string {
+	switch {
+	case tag.DeletedAt != nil:
+		return "deleted"
+	case tag.ArchivedAt != nil:
+		return "archived"
+	}
+	return "active"
+}
+
+func hasUser(subs []Subscriber, userID int64) bool {
+	for _, sub := range subs {
+		if sub.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func collectUserData(user Subscriber) userData {
+	out := userData{
+		User:          user,
+		ExportedAt:    time.Now(),
+		Subscriptions: []userTagRef{},
+		CreatedTags:   []userCreatedTag{},
+		MentionCounts: map[string]int{},
+		Mentions:      []MentionEvent{},
+		Digest:        data.Digests[user.ID],
+		Prefs:         data.Users[user.ID],
+	}
+	for _, tag := range data.Tags {
+		ref := userTagRef{ChatID: tag.ChatID, Tag: tag.Name}
+		for _, sub := range tag.Subscribers {
+			if sub.ID == user.ID {
+				out.Subscriptions = append(out.Subscriptions, userTagRef{ChatID: tag.ChatID, Tag: tag.Name, Delivery: sub.Delivery})
+			}
+		}
+		if tag.CreatorID == user.ID {
+			out.CreatedTags = append(out.CreatedTags, userCreatedTag{
+				ChatID:      tag.ChatID,
+				Name:        tag.Name,
+				Description: tag.Description,
+				CreatedAt:   tag.CreatedAt,
+				Status:      tagStatus(tag),
+			})
+		}
+		if hasUser(tag.Moderators, user.ID) {
+			out.Moderates = append(out.Moderates, ref)
+		}
+		if hasUser(tag.Banned, user.ID) {
+			out.BannedFrom = append(out.BannedFrom, ref)
+		}
+		if hasUser(tag.Requests, user.ID) {
+			out.Requests = append(out.Requests, ref)
+		}
+		if n := tag.Stats.ByUser[user.ID]; n > 0 {
+			out.MentionCounts[fmt.Sprintf("%d/%s", tag.ChatID, tag.Name)] = n
+		}
+		if p := tag.LastPing; p != nil {
+			if p.PingerID == user.ID {
+				out.Pings = append(out.Pings, userPing{ChatID: p.ChatID, Tag: tag.Name, At: p.At})
+			}
+			if p.Acked(user.ID) {
+				out.Acks = append(out.Acks, userPing{ChatID: p.ChatID, Tag: tag.Name, At: p.At})
+			}
+		}
+	}
+	for _, ev := range data.Mentions {
+		if ev.UserID == user.ID {
+			out.Mentions = append(out.Mentions, ev)
+		}
+	}
+	return out
+}
+
+func handleMyData(c tele.Context) error {
+	user := Subscriber{ID: c.Sender().ID, Username: c.Sender().Username}
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(collectUserData(user)); err != nil {
+		return err
+	}
+	doc := &tele.Document{
+		File:     tele.FromReader(bytes.NewReader(buf.Bytes())),
+		FileName: fmt.Sprintf("mydata-%d.json", user.ID),
+		Caption:  T(c, "mydata.caption"),
+	}
+	sendQueue.sendThen(c, c.Sender(), doc, func(_ *tele.Message, err error) error {
+		if err != nil {
+			return c.Send(T(c, "dm_failed"))
+		}
+		if c.Chat().Type == tele.ChatPrivate {
+			return nil
+		}
+		return c.Send(T(c, "mydata.sent"))
+	})
+	return nil
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const maxWelcomeLen = 1000
+
+func welcomeSubscriber(ctx context.Context, tag *Tag, sub Subscriber) {
+	if tag.Welcome == "" || sub.ID == tag.CreatorID {
+		return
+	}
+	lang := chatLang(tag.ChatID)
+	title := ""
+	if s := data.Chats[tag.ChatID]; s != nil {
+		title = s.Title
+	}
+	sendQueue.send(ctx, &tele.User{ID: sub.ID}, tr(lang, "onboard.dm", tagIcon(tag), esc(tag.Name), esc(title), esc(tag.Welcome), esc(tag.Name)))
+}
+
+func handleSetWelcome(c tele.Context) error {
+	name, text, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " ")
+	if name == "" {
+		return c.Send(T(c, "onboard.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, strings.TrimPrefix(name, "#"))
+	if tag == nil {
+		return tagNotFound(c, strings.TrimPrefix(name, "#"))
+	}
+	text = strings.TrimSpace(text)
+	if text == "" {
+		if tag.Welcome == "" {
+			return c.Send(T(c, "onboard.none", esc(tag.Name)))
+		}
+		return c.Send(T(c, "onboard.current", esc(tag.Name), esc(tag.Welcome)))
+	}
+	if strings.EqualFold(text, "off") {
+		tag.Welcome = ""
+		saveTag("update", tag)
+		return c.Send(T(c, "onboard.removed", esc(tag.Name)))
+	}
+	if len([]rune(text)) > maxWelcomeLen {
+		return c.Send(T(c, "onboard.too_long", maxWelcomeLen))
+	}
+	if word := blockedWord(c.Chat().ID, text); word != "" {
+		return c.Send(T(c, "blockword.rejected"))
+	}
+	tag.Welcome = text
+	saveTag("update", tag)
+	return c.Send(T(c, "onboard.set", esc(tag.Name)))
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"reflect"
+	"sync"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	tele "gopkg.in/telebot.v3"
+)
+
+const maxMessageLength = 4096
+
+type outgoing struct {
+	ctx   context.Context
+	links []trace.Link
+	to    tele.Recipient
+	what  interface{}
+	opts  []interface{}
+	done  []func(*tele.Message, error)
+}
+
+type outbox struct {
+	bot     *tele.Bot
+	mu      sync.Mutex
+	pending map[string][]*outgoing
+}
+
+var sendQueue *outbox
+
+func startOutbox(b *tele.Bot) {
+	sendQueue = &outbox{bot: b, pending: map[string][]*outgoing{}}
+}
+
+func hasMarkup(opts []interface{}) bool {
+	for _, opt := range opts {
+		switch o := opt.(type) {
+		case *tele.ReplyMarkup:
+			return true
+		case *tele.SendOptions:
+			if o.ReplyMarkup != nil {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (m *outgoing) coalesce(what interface{}, opts []interface{}) bool {
+	prev, ok := m.what.(string)
+	text, ok2 := what.(string)
+	if !ok || !ok2 || hasMarkup(m.opts) || hasMarkup(opts) || !reflect.DeepEqual(m.opts, opts) {
+		return false
+	}
+	if len(prev)+len(text)+2 > maxMessageLength {
+		return false
+	}
+	m.what = prev + "\n\n" + text
+	return true
+}
+
+func (q *outbox) enqueue(ctx context.Context, to tele.Recipient, what interface{}, done func(*tele.Message, error), opts ...interface{}) {
+	key := to.Recipient()
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list, running := q.pending[key]
+	if n := len(list); n > 0 && list[n-1].coalesce(what, opts) {
+		list[n-1].links = append(list[n-1].links, trace.LinkFromContext(ctx))
+		if done != nil {
+			list[n-1].done = append(list[n-1].done, done)
+		}
+		return
+	}
+	m := &outgoing{ctx: ctx, to: to, what: what, opts: opts}
+	if done != nil {
+		m.done = append(m.done, done)
+	}
+	q.pending[key] = append(list, m)
+	if !running {
+		go q.run(key)
+	}
+}
+
+func (q *outbox) next(key string) *outgoing {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	list := q.pending[key]
+	if len(list) == 0 {
+		delete(q.pending, key)
+		return nil
+	}
+	q.pending[key] = list[1:]
+	return list[0]
+}
+
+func (q *outbox) run(key string) {
+	for m := q.next(key); m != nil; m = q.next(key) {
+		_, span := tracer.Start(m.ctx, "telegram send", trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithLinks(m.links...), trace.WithAttributes(attribute.String("chat_id", key)))
+		waitChatSlot(key)
+		msg, err := q.bot.Send(m.to, m.what, m.opts...)
+		endSpan(span, err)
+		span.End()
+		if err != nil {
+			slog.Error("Не удалось отправить сообщение", "chat_id", key, "err", err)
+			captureError(nil, "telegram", err, "chat_id", key)
+			if repeatedError(err.Error()) {
+				reportError(nil, err, nil)
+			}
+		}
+		for _, done := range m.done {
+			done(msg, err)
+		}
+	}
+}
+
+func (q *outbox) send(ctx context.Context, to tele.Recipient, what interface{}, opts ...interface{}) {
+	q.enqueue(ctx, to, what, nil, opts...)
+}
+
+func (q *outbox) sendThen(c tele.Context, to tele.Recipient, what interface{}, then func(*tele.Message, error) error, opts ...interface{}) {
+	q.enqueue(traceContext(c), to, what, func(msg *tele.Message, err error) {
+		dataMu.Lock()
+		err = then(msg, err)
+		dataMu.Unlock()
+		if err != nil {
+			onBotError(err, c)
+		}
+	}, opts...)
+}
+
+type queuedContext struct {
+	tele.Context
+}
+
+func (c queuedContext) Send(what interface{}, opts ...interface{}) error {
+	sendQueue.send(traceContext(c), c.Recipient(), what, opts...)
+	return nil
+}
+
+func queued(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		return next(queuedContext{Context: c})
+	}
+}
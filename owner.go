@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func trackChat(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if chat := c.Chat(); chat != nil && chat.Type != tele.ChatPrivate {
+			s := chatSettings(chat.ID)
+			s.Title = chat.Title
+			s.LastSeen = time.Now()
+		}
+		return next(c)
+	}
+}
+
+func ownerOnly(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if !isBotOwner(c) {
+			return nil
+		}
+		return next(c)
+	}
+}
+
+func groupChats() []int64 {
+	ids := make([]int64, 0, len(data.Chats))
+	for id, s := range data.Chats {
+		if id < 0 && !s.LastSeen.IsZero() {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return data.Chats[ids[i]].LastSeen.After(data.Chats[ids[j]].LastSeen)
+	})
+	return ids
+}
+
+func handleChats(c tele.Context) error {
+	ids := groupChats()
+	if len(ids) == 0 {
+		return c.Send(T(c, "owner.no_chats"))
+	}
+	full, err := allData()
+	if err != nil {
+		return err
+	}
+	tagsPerChat := map[int64]int{}
+	for _, tag := range full.Tags {
+		if tag.Active() {
+			tagsPerChat[tag.ChatID]++
+		}
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "owner.chats_header", len(ids)))
+	for _, id := range ids {
+		s := data.Chats[id]
+		b.WriteString(fmt.Sprintf("<code>%d</code> %s — %s\n", id, esc(s.Title),
+			T(c, "owner.chat_line", tagsPerChat[id], s.LastSeen.In(zoneOf(c)).Format("02.01.2006 15:04"))))
+	}
+	return c.Send(b.String())
+}
+
+func handleLeaveChat(c tele.Context) error {
+	chatID, ok := parseChatArg(c)
+	if !ok {
+		return c.Send(T(c, "owner.leave_usage"))
+	}
+	if err := c.Bot().Leave(&tele.Chat{ID: chatID}); err != nil {
+		return c.Send(T(c, "owner.leave_failed", chatID, esc(err.Error())))
+	}
+	forgetRules(chatID)
+	delete(data.Chats, chatID)
+	saveData()
+	return c.Send(T(c, "owner.left", chatID))
+}
+
+func handleGlobalStats(c tele.Context) error {
+	tags, subs := 0, 0
+	users := map[int64]bool{}
+	for _, tag := range data.Tags {
+		if !tag.Active() {
+			continue
+		}
+		tags++
+		subs += len(tag.Subscribers)
+		for _, sub := range tag.Subscribers {
+			users[sub.ID] = true
+		}
+	}
+	day := time.Now().Add(-24 * time.Hour)
+	mentions := 0
+	for _, ev := range data.Mentions {
+		if ev.At.After(day) {
+			mentions++
+		}
+	}
+	return c.Send(T(c, "owner.globalstats", len(groupChats()), tags, subs, len(users), mentions))
+}
+
+func handleBroadcast(c tele.Context) error {
+	text := strings.TrimSpace(c.Message().Payload)
+	if text == "" {
+		return c.Send(T(c, "owner.broadcast_usage"))
+	}
+	chats := groupChats()
+	if len(chats) == 0 {
+		return c.Send(T(c, "owner.broadcast_done", 0, 0))
+	}
+	sent, failed := 0, 0
+	for _, id := range chats {
+		sendQueue.sendThen(c, &tele.Chat{ID: id}, text, func(_ *tele.Message, err error) error {
+			if err != nil {
+				slog.Warn("Рассылка в чат не удалась", "chat_id", id, "err", err)
+				failed++
+			} else {
+				sent++
+			}
+			if sent+failed < len(chats) {
+				return nil
+			}
+			return c.Send(T(c, "owner.broadcast_done", sent, failed))
+		})
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"time"
+
+	"tagger/storage"
+)
+
+const flushInterval = time.Second
+
+var (
+	dataDirty          bool
+	statsDirty         bool
+	statsFlushInterval = time.Minute
+	lastStatsFlush     time.Time
+	lastStatsSum       [sha256.Size]byte
+	persistStop        = make(chan struct{})
+	persistDone        = make(chan struct{})
+)
+
+func saveStats() {
+	statsDirty = true
+}
+
+func flushDue(now time.Time, force bool) (bool, bool) {
+	return dataDirty, statsDirty && (force || now.Sub(lastStatsFlush) >= statsFlushInterval)
+}
+
+func snapshotData() (*Data, error) {
+	file, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+	snap := &Data{}
+	if err := json.Unmarshal(file, snap); err != nil {
+		return nil, err
+	}
+	return snap, nil
+}
+
+func snapshotStats() (*storage.Stats, [sha256.Size]byte, error) {
+	file, err := json.Marshal(storage.StatsOf(&data))
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	snap := &storage.Stats{}
+	if err := json.Unmarshal(file, snap); err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	return snap, sha256.Sum256(file), nil
+}
+
+func flushIfDirty(force bool) {
+	dataMu.Lock()
+	if evictChats() {
+		dataDirty = true
+	}
+	now := time.Now()
+	saveMain, saveCounters := flushDue(now, force)
+	if !saveMain && !saveCounters {
+		dataMu.Unlock()
+		return
+	}
+	storage.AssignTagIDs(&data)
+	if lazy, ok := store.(storage.Lazy); ok {
+		lazy.Snapshot()
+	}
+	var (
+		snap  *Data
+		stats *storage.Stats
+		sum   [sha256.Size]byte
+		err   error
+	)
+	if saveMain {
+		snap, err = snapshotData()
+		dataDirty = false
+	}
+	if saveCounters && err == nil {
+		stats, sum, err = snapshotStats()
+		statsDirty = false
+		lastStatsFlush = now
+		if sum == lastStatsSum {
+			stats = nil
+		}
+	}
+	dataMu.Unlock()
+	if err == nil && snap != nil {
+		_, span := tracer.Start(context.Background(), "storage.save")
+		err = store.Save(snap)
+		endSpan(span, err)
+		span.End()
+		if err == nil {
+			compactJournal(snap.JournalSeq)
+		}
+	}
+	if err == nil && stats != nil {
+		_, span := tracer.Start(context.Background(), "storage.save_stats")
+		err = store.SaveStats(stats)
+		endSpan(span, err)
+		span.End()
+		if err == nil {
+			lastStatsSum = sum
+		}
+	}
+	if errors.Is(err, storage.ErrLocked) {
+		fatal("Хранилище перешло к другому экземпляру бота", err)
+	}
+	if err != nil {
+		slog.Error("Не удалось сохранить данные", "err", err)
+		captureError(nil, "storage", err)
+		metricStorageErrors.add(1)
+		dataMu.Lock()
+		dataDirty = dataDirty || saveMain
+		statsDirty = statsDirty || saveCounters
+		dataMu.Unlock()
+	}
+}
+
+func startPersistence() {
+	go func() {
+		defer close(persistDone)
+		ticker := time.NewTicker(flushInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				flushIfDirty(false)
+			case <-persistStop:
+				flushIfDirty(true)
+				return
+			}
+		}
+	}()
+}
+
+func stopPersistence() {
+	close(persistStop)
+	<-persistDone
+	if err := store.Close(); err != nil {
+		slog.Warn("Не удалось закрыть хранилище", "err", err)
+	}
+	releaseInstanceLock()
+}
@@ -0,0 +1,175 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	pingAnyone      = ""
+	pingSubscribers = "subscribers"
+	pingCreator     = "creator"
+)
+
+func validPingPolicy(policy string) bool {
+	return policy == pingAnyone || policy == pingSubscribers || policy == pingCreator
+}
+
+func canPing(c tele.Context, tag *Tag) bool {
+	switch tag.PingPolicy {
+	case pingSubscribers:
+		return isSubscribed(tag, c.Sender().ID) || canManage(c, tag)
+	case pingCreator:
+		return canManage(c, tag)
+	}
+	return true
+}
+
+func collectMentions(c tele.Context, tag *Tag, quiet bool, forwarded map[int64]bool) ([]string, bool) {
+	allQuiet := true
+	var mentions []string
+	for _, sub := range tag.Subscribers {
+		noteSummary(c, sub, tag)
+		if isMuted(sub.ID, tag) {
+			continue
+		}
+		subQuiet := quiet || inQuietHours(sub.ID, time.Now())
+		delivery := deliveryFor(sub)
+		if delivery == deliveryDM && sub.ID != c.Sender().ID {
+			if forwarded[sub.ID] {
+				continue
+			}
+			deliverDM(c, sub, subQuiet)
+			forwarded[sub.ID] = true
+			metricMentions.add(1)
+			continue
+		}
+		if delivery == deliveryDigest && sub.ID != c.Sender().ID {
+			queueDigest(c, sub, tag)
+			metricMentions.add(1)
+			continue
+		}
+		mentions = append(mentions, mentionHTML(sub))
+		allQuiet = allQuiet && subQuiet
+	}
+	metricMentions.add(int64(len(mentions)))
+	return mentions, allQuiet
+}
+
+func sendPing(c tele.Context, text string, pinged []*Tag, silent bool) error {
+	opts := sendOptions(c)
+	opts.ReplyMarkup = ackMarkup(c, pinged...)
+	opts.DisableNotification = silent
+	opts.DisableWebPagePreview = true
+	sendQueue.sendThen(c, c.Chat(), text, func(msg *tele.Message, err error) error {
+		if err != nil {
+			return err
+		}
+		for _, tag := range pinged {
+			tag.LastPing = &Ping{
+				ChatID:     msg.Chat.ID,
+				MessageID:  msg.ID,
+				PingerID:   c.Sender().ID,
+				PingerName: c.Sender().Username,
+				At:         time.Now(),
+				Acks:       []int64{},
+			}
+		}
+		saveStats()
+		deleteTrigger(c)
+		return nil
+	}, opts)
+	return nil
+}
+
+func handlePing(c tele.Context) error {
+	name, message, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " ")
+	if name == "" {
+		return c.Send(T(c, "ping.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, strings.TrimPrefix(name, "#"))
+	if tag == nil || !visibleTo(tag, c.Sender().ID) {
+		return tagNotFound(c, strings.TrimPrefix(name, "#"))
+	}
+	if !allowedInTopic(tag, threadOf(c)) {
+		return c.Send(T(c, "ping.wrong_topic"))
+	}
+	if !canPing(c, tag) {
+		return c.Send(T(c, "ping.denied"))
+	}
+	if shadowMuted(c) {
+		return nil
+	}
+	message = strings.TrimSpace(message)
+	recordMention(tag, c)
+	emitEvent(c.Chat().ID, eventTagMentioned, tag, &Subscriber{ID: c.Sender().ID, Username: c.Sender().Username}, message)
+	mentions, allQuiet := collectMentions(c, tag, tag.Silent, map[int64]bool{})
+	if len(mentions) == 0 {
+		saveStats()
+		return c.Send(T(c, "ping.nobody"))
+	}
+	notePings(c, 1)
+	return sendPing(c, pingText(c, tag, mentions, message, message), []*Tag{tag}, allQuiet)
+}
+
+func triggerLink(c tele.Context) string {
+	if c.Message() == nil {
+		return ""
+	}
+	return messageLink(c.Chat(), c.Message().ID)
+}
+
+func pingText(c tele.Context, tag *Tag, mentions []string, message, trigger string) string {
+	link := triggerLink(c)
+	if tag.Template != "" {
+		text := renderTemplate(c, tag, mentions, trigger)
+		if link != "" && !strings.Contains(tag.Template, "{link}") {
+			text += "\n" + T(c, "ping.source", link)
+		}
+		return text
+	}
+	text := funnyPhrase(c, esc(tag.Name))
+	if message != "" {
+		text = T(c, "ping.message", esc(c.Sender().Username), esc(message))
+	}
+	if link != "" {
+		text += "\n" + T(c, "ping.source", link)
+	}
+	return fmt.Sprintf("%s\n%s%s", strings.Join(mentions, " "), tagIcon(tag), text)
+}
+
+func handlePingPolicy(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "pingperm.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	if len(args) == 1 {
+		return c.Send(T(c, "pingperm.current", esc(tag.Name), T(c, "pingperm."+policyName(tag.PingPolicy))))
+	}
+	switch strings.ToLower(args[1]) {
+	case "anyone":
+		tag.PingPolicy = pingAnyone
+	case pingSubscribers:
+		tag.PingPolicy = pingSubscribers
+	case pingCreator:
+		tag.PingPolicy = pingCreator
+	default:
+		return c.Send(T(c, "pingperm.usage"))
+	}
+	saveTag("update", tag)
+	return c.Send(T(c, "pingperm.set", esc(tag.Name), T(c, "pingperm."+policyName(tag.PingPolicy))))
+}
+
+func policyName(policy string) string {
+	if policy == pingAnyone {
+		return "anyone"
+	}
+	return policy
+}
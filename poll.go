@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	pollKeep       = 30 * 24 * time.Hour
+	maxPollOptions = 10
+)
+
+func findPoll(pollID string) *TrackedPoll {
+	for _, s := range data.Chats {
+		for _, p := range s.Polls {
+			if p.ID == pollID {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+func handlePoll(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "poll.groups_only"))
+	}
+	var parts []string
+	for _, part := range strings.Split(c.Message().Payload, "|") {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	if len(parts) < 3 || len(parts) > maxPollOptions+1 {
+		return c.Send(T(c, "poll.usage", maxPollOptions))
+	}
+	poll := &tele.Poll{Type: tele.PollRegular, Question: parts[0], MultipleAnswers: true}
+	for _, option := range parts[1:] {
+		poll.AddOptions(option)
+	}
+	sendQueue.sendThen(c, c.Chat(), poll, func(msg *tele.Message, err error) error {
+		if err != nil {
+			return err
+		}
+		s := chatSettings(c.Chat().ID)
+		kept := s.Polls[:0]
+		for _, p := range s.Polls {
+			if time.Since(p.CreatedAt) < pollKeep {
+				kept = append(kept, p)
+			}
+		}
+		s.Polls = append(kept, &TrackedPoll{
+			ID:        msg.Poll.ID,
+			MessageID: msg.ID,
+			Question:  parts[0],
+			Options:   parts[1:],
+			CreatedAt: time.Now(),
+		})
+		saveData()
+		return nil
+	}, &tele.SendOptions{ThreadID: threadOf(c)})
+	return nil
+}
+
+func onPollAnswer(c tele.Context) error {
+	answer := c.PollAnswer()
+	if readOnly || answer.Sender == nil {
+		return nil
+	}
+	p := findPoll(answer.PollID)
+	if p == nil {
+		return nil
+	}
+	votes := p.Votes[:0]
+	for _, v := range p.Votes {
+		if v.UserID != answer.Sender.ID {
+			votes = append(votes, v)
+		}
+	}
+	if len(answer.Options) > 0 {
+		votes = append(votes, PollVote{UserID: answer.Sender.ID, Username: answer.Sender.Username, Options: answer.Options})
+	}
+	p.Votes = votes
+	saveData()
+	return nil
+}
+
+func votersFor(p *TrackedPoll, option int) []Subscriber {
+	var subs []Subscriber
+	for _, v := range p.Votes {
+		for _, o := range v.Options {
+			if o == option {
+				name := v.Username
+				if name == "" {
+					name = fmt.Sprintf("User%d", v.UserID)
+				}
+				subs = append(subs, Subscriber{ID: v.UserID, Username: name})
+				break
+			}
+		}
+	}
+	return subs
+}
+
+func handleTagFromPoll(c tele.Context) error {
+	reply := c.Message().ReplyTo
+	args := strings.Fields(c.Message().Payload)
+	if reply == nil || len(args) < 2 {
+		return c.Send(T(c, "pollTag.usage"))
+	}
+	var p *TrackedPoll
+	if s := data.Chats[c.Chat().ID]; s != nil {
+		for _, candidate := range s.Polls {
+			if candidate.MessageID == reply.ID {
+				p = candidate
+			}
+		}
+	}
+	if p == nil {
+		return c.Send(T(c, "pollTag.not_tracked"))
+	}
+	option, err := strconv.Atoi(args[0])
+	if err != nil || option < 1 || option > len(p.Options) {
+		return c.Send(T(c, "pollTag.bad_option", len(p.Options)))
+	}
+	category, name := splitCategory(args[1])
+	if name == "" || strings.ContainsAny(name, "#") {
+		return c.Send(T(c, "pollTag.usage"))
+	}
+	if tagService.Find(c.Chat().ID, name) != nil {
+		return c.Send(T(c, "create.exists"))
+	}
+	if existing := findCategory(c.Chat().ID, category); existing != "" {
+		category = existing
+	}
+	description := strings.Join(args[2:], " ")
+	if description == "" {
+		description = p.Options[option-1]
+	}
+	if word := blockedWord(c.Chat().ID, name, category, description); word != "" {
+		return c.Send(T(c, "blockword.rejected"))
+	}
+	if limit := dailyTagCap(c.Chat().ID); limit > 0 && createdToday(c.Chat().ID) >= limit {
+		return c.Send(T(c, "create.daily_cap", limit))
+	}
+	tag := &Tag{
+		Name:        name,
+		ChatID:      c.Chat().ID,
+		CreatorID:   c.Sender().ID,
+		CreatorName: c.Sender().Username,
+		Description: description,
+		Category:    category,
+		Subscribers: []Subscriber{},
+		CreatedAt:   time.Now(),
+	}
+	data.Tags = append(data.Tags, tag)
+	tagService.Add(tag)
+	for _, sub := range votersFor(p, option-1) {
+		tagService.Subscribe(tag, sub)
+	}
+	saveTag("create", tag)
+	emitEvent(c.Chat().ID, eventTagCreated, tag, nil, description)
+	return c.Send(T(c, "pollTag.done", esc(name), esc(p.Options[option-1]), len(tag.Subscribers)))
+}
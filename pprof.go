@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"time"
+)
+
+func loopbackAddr(addr string) (string, bool) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return "", false
+	}
+	if host == "" || host == "localhost" {
+		return net.JoinHostPort("127.0.0.1", port), true
+	}
+	ip := net.ParseIP(host)
+	return addr, ip != nil && ip.IsLoopback()
+}
+
+func startDebugServer() {
+	raw := cfg.HTTP.DebugAddr
+	if raw == "" {
+		return
+	}
+	addr, ok := loopbackAddr(raw)
+	if !ok {
+		slog.Error("DEBUG_ADDR должен указывать на localhost, профилировщик не запущен", "addr", raw)
+		return
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	srv := &http.Server{Addr: addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+	go func() {
+		if err := srv.ListenAndServe(); err != nil {
+			slog.Error("Профилировщик остановлен", "addr", addr, "err", err)
+		}
+	}()
+	slog.Info("🔬 pprof доступен", "addr", addr, "path", "/debug/pprof/")
+}
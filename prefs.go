@@ -0,0 +1,292 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	prefsMenu     = "menu"
+	prefsDelivery = "delivery"
+	prefsQuiet    = "quiet"
+	prefsTimezone = "tz"
+	prefsMute     = "mute"
+
+	prefsStateTTL = 10 * time.Minute
+)
+
+func userPrefs(userID int64) *UserPrefs {
+	if data.Users == nil {
+		data.Users = map[int64]*UserPrefs{}
+	}
+	p, ok := data.Users[userID]
+	if !ok {
+		p = &UserPrefs{}
+		data.Users[userID] = p
+	}
+	return p
+}
+
+func deliveryFor(sub Subscriber) string {
+	if sub.Delivery != "" {
+		return sub.Delivery
+	}
+	if p := data.Users[sub.ID]; p != nil {
+		return p.Delivery
+	}
+	return ""
+}
+
+func isMuted(userID int64, tag *Tag) bool {
+	p := data.Users[userID]
+	if p == nil {
+		return false
+	}
+	if snoozedUntil(userID, tag).After(time.Now()) {
+		return true
+	}
+	for _, name := range p.Muted {
+		if strings.EqualFold(name, tag.Name) {
+			return true
+		}
+	}
+	return false
+}
+
+func inQuietHours(userID int64, now time.Time) bool {
+	p := data.Users[userID]
+	if p == nil || !p.Quiet {
+		return false
+	}
+	hour := now.In(userLocation(userID)).Hour()
+	if p.QuietFrom <= p.QuietTo {
+		return hour >= p.QuietFrom && hour < p.QuietTo
+	}
+	return hour >= p.QuietFrom || hour < p.QuietTo
+}
+
+func prefsKey(userID int64) string {
+	return "prefs:" + strconv.FormatInt(userID, 10)
+}
+
+func setPrefsState(userID int64, state string) {
+	kv.Set(prefsKey(userID), []byte(state), prefsStateTTL)
+}
+
+func prefsState(userID int64) string {
+	raw, ok, err := kv.Get(prefsKey(userID))
+	if err != nil || !ok {
+		return ""
+	}
+	return string(raw)
+}
+
+func keyboard(rows ...[]string) *tele.ReplyMarkup {
+	markup := &tele.ReplyMarkup{ResizeKeyboard: true}
+	var out []tele.Row
+	for _, row := range rows {
+		var btns []tele.Btn
+		for _, text := range row {
+			btns = append(btns, markup.Text(text))
+		}
+		out = append(out, markup.Row(btns...))
+	}
+	markup.Reply(out...)
+	return markup
+}
+
+func prefsSummary(c tele.Context, p *UserPrefs) string {
+	delivery := T(c, "prefs.delivery_chat")
+	switch p.Delivery {
+	case deliveryDM:
+		delivery = T(c, "prefs.delivery_dm")
+	case deliveryDigest:
+		delivery = T(c, "prefs.delivery_digest")
+	}
+	quiet := T(c, "prefs.off")
+	if p.Quiet {
+		quiet = fmt.Sprintf("%02d:00–%02d:00", p.QuietFrom, p.QuietTo)
+	}
+	tz := p.Timezone
+	if tz == "" {
+		tz = time.Local.String()
+	}
+	muted := T(c, "prefs.none")
+	if len(p.Muted) > 0 {
+		muted = "#" + esc(strings.Join(p.Muted, ", #"))
+	}
+	return T(c, "prefs.summary", delivery, quiet, esc(tz), muted)
+}
+
+func showPrefsMenu(c tele.Context) error {
+	setPrefsState(c.Sender().ID, prefsMenu)
+	return c.Send(prefsSummary(c, userPrefs(c.Sender().ID)), keyboard(
+		[]string{T(c, "prefs.btn_delivery"), T(c, "prefs.btn_quiet")},
+		[]string{T(c, "prefs.btn_timezone"), T(c, "prefs.btn_mute")},
+		[]string{T(c, "prefs.btn_done")},
+	))
+}
+
+func handlePrefs(c tele.Context) error {
+	if c.Chat().Type != tele.ChatPrivate {
+		return c.Send(T(c, "prefs.private_only"))
+	}
+	return showPrefsMenu(c)
+}
+
+func handlePrefsInput(c tele.Context) (bool, error) {
+	if c.Chat().Type != tele.ChatPrivate || c.Sender() == nil {
+		return false, nil
+	}
+	state := prefsState(c.Sender().ID)
+	if state == "" {
+		return false, nil
+	}
+	text := strings.TrimSpace(c.Text())
+	if text == T(c, "prefs.btn_back") {
+		return true, showPrefsMenu(c)
+	}
+	if readOnly && text != T(c, "prefs.btn_done") {
+		return true, c.Send(T(c, "readonly.rejected_plain"))
+	}
+	switch state {
+	case prefsMenu:
+		return true, prefsMenuChoice(c, text)
+	case prefsDelivery:
+		return true, prefsSetDelivery(c, text)
+	case prefsQuiet:
+		return true, prefsSetQuiet(c, text)
+	case prefsTimezone:
+		return true, prefsSetTimezone(c, text)
+	case prefsMute:
+		return true, prefsToggleMute(c, text)
+	}
+	return false, nil
+}
+
+func prefsMenuChoice(c tele.Context, text string) error {
+	userID := c.Sender().ID
+	switch text {
+	case T(c, "prefs.btn_delivery"):
+		setPrefsState(userID, prefsDelivery)
+		return c.Send(T(c, "prefs.ask_delivery"), keyboard(
+			[]string{T(c, "prefs.delivery_chat"), T(c, "prefs.delivery_dm"), T(c, "prefs.delivery_digest")},
+			[]string{T(c, "prefs.btn_back")},
+		))
+	case T(c, "prefs.btn_quiet"):
+		setPrefsState(userID, prefsQuiet)
+		return c.Send(T(c, "prefs.ask_quiet"), keyboard(
+			[]string{"23-8", "0-8", T(c, "prefs.off")},
+			[]string{T(c, "prefs.btn_back")},
+		))
+	case T(c, "prefs.btn_timezone"):
+		setPrefsState(userID, prefsTimezone)
+		return c.Send(T(c, "prefs.ask_timezone"), keyboard(
+			[]string{"Europe/Moscow", "Asia/Shanghai", "UTC"},
+			[]string{T(c, "prefs.btn_back")},
+		))
+	case T(c, "prefs.btn_mute"):
+		return showMuteMenu(c)
+	case T(c, "prefs.btn_done"):
+		kv.Delete(prefsKey(userID))
+		return c.Send(T(c, "prefs.saved"), &tele.ReplyMarkup{RemoveKeyboard: true})
+	}
+	return showPrefsMenu(c)
+}
+
+func prefsSetDelivery(c tele.Context, text string) error {
+	p := userPrefs(c.Sender().ID)
+	switch text {
+	case T(c, "prefs.delivery_chat"):
+		p.Delivery = ""
+	case T(c, "prefs.delivery_dm"):
+		p.Delivery = deliveryDM
+	case T(c, "prefs.delivery_digest"):
+		p.Delivery = deliveryDigest
+	default:
+		return c.Send(T(c, "prefs.ask_delivery"))
+	}
+	saveData()
+	return showPrefsMenu(c)
+}
+
+func prefsSetQuiet(c tele.Context, text string) error {
+	p := userPrefs(c.Sender().ID)
+	if strings.EqualFold(text, T(c, "prefs.off")) || strings.EqualFold(text, "off") {
+		p.Quiet = false
+		saveData()
+		return showPrefsMenu(c)
+	}
+	from, to, ok := strings.Cut(text, "-")
+	start, err1 := strconv.Atoi(strings.TrimSpace(from))
+	end, err2 := strconv.Atoi(strings.TrimSpace(to))
+	if !ok || err1 != nil || err2 != nil || start < 0 || start > 23 || end < 0 || end > 23 || start == end {
+		return c.Send(T(c, "prefs.bad_quiet"))
+	}
+	p.Quiet, p.QuietFrom, p.QuietTo = true, start, end
+	saveData()
+	return showPrefsMenu(c)
+}
+
+func prefsSetTimezone(c tele.Context, text string) error {
+	zone, ok := normalizeTimezone(text)
+	if !ok {
+		return c.Send(T(c, "prefs.bad_timezone"))
+	}
+	userPrefs(c.Sender().ID).Timezone = zone
+	saveData()
+	return showPrefsMenu(c)
+}
+
+func showMuteMenu(c tele.Context) error {
+	userID := c.Sender().ID
+	setPrefsState(userID, prefsMute)
+	var rows [][]string
+	var row []string
+	for _, tag := range tagService.Subscriptions(userID) {
+		label := "#" + tag.Name
+		if isMuted(userID, tag) {
+			label = "🔇 " + label
+		}
+		row = append(row, label)
+		if len(row) == 3 {
+			rows = append(rows, row)
+			row = nil
+		}
+	}
+	if len(row) > 0 {
+		rows = append(rows, row)
+	}
+	rows = append(rows, []string{T(c, "prefs.btn_back")})
+	return c.Send(T(c, "prefs.ask_mute"), keyboard(rows...))
+}
+
+func prefsToggleMute(c tele.Context, text string) error {
+	name := strings.TrimPrefix(strings.TrimPrefix(text, "🔇 "), "#")
+	p := userPrefs(c.Sender().ID)
+	kept := p.Muted[:0:0]
+	for _, muted := range p.Muted {
+		if !strings.EqualFold(muted, name) {
+			kept = append(kept, muted)
+		}
+	}
+	if len(kept) == len(p.Muted) {
+		var found *Tag
+		for _, tag := range tagService.Subscriptions(c.Sender().ID) {
+			if strings.EqualFold(tag.Name, name) {
+				found = tag
+			}
+		}
+		if found == nil {
+			return showMuteMenu(c)
+		}
+		kept = append(kept, found.Name)
+	}
+	p.Muted = kept
+	saveData()
+	return showMuteMenu(c)
+}
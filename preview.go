@@ -0,0 +1,66 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func previewMention(sub Subscriber) string {
+	return "<code>" + esc(displayName(sub.ID, sub.Username)) + "</code>"
+}
+
+func handlePreview(c tele.Context) error {
+	name, message, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " ")
+	if name == "" {
+		return c.Send(T(c, "preview.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, strings.TrimPrefix(name, "#"))
+	if tag == nil || !visibleTo(tag, c.Sender().ID) {
+		return tagNotFound(c, strings.TrimPrefix(name, "#"))
+	}
+	var mentions []string
+	var dms, digests, muted, quiet int
+	for _, sub := range tag.Subscribers {
+		if isMuted(sub.ID, tag) {
+			muted++
+			continue
+		}
+		switch delivery := deliveryFor(sub); {
+		case delivery == deliveryDM && sub.ID != c.Sender().ID:
+			dms++
+			continue
+		case delivery == deliveryDigest && sub.ID != c.Sender().ID:
+			digests++
+			continue
+		}
+		if tag.Silent || inQuietHours(sub.ID, time.Now()) {
+			quiet++
+		}
+		mentions = append(mentions, previewMention(sub))
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "preview.header", esc(tag.Name), len(mentions)+dms+digests))
+	b.WriteString(T(c, "preview.breakdown", len(mentions), dms, digests))
+	if quiet > 0 {
+		b.WriteString(T(c, "preview.quiet", quiet))
+	}
+	if muted > 0 {
+		b.WriteString(T(c, "preview.muted", muted))
+	}
+	if !allowedInTopic(tag, threadOf(c)) {
+		b.WriteString(T(c, "preview.wrong_topic"))
+	}
+	if !canPing(c, tag) {
+		b.WriteString(T(c, "preview.denied"))
+	}
+	if len(mentions) == 0 {
+		b.WriteString(T(c, "preview.nobody"))
+	} else {
+		b.WriteString(T(c, "preview.message"))
+		b.WriteString(fmt.Sprintf("<blockquote>%s</blockquote>", pingText(c, tag, mentions, strings.TrimSpace(message), strings.TrimSpace(message))))
+	}
+	return c.Send(b.String())
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sendMaxRetries   = 4
+	sendBaseBackoff  = time.Second
+	sendMaxBackoff   = 30 * time.Second
+	groupSendLimit   = 20
+	groupSendWindow  = time.Minute
+	apiClientTimeout = 3 * time.Minute
+)
+
+var (
+	chatSendsMu sync.Mutex
+	chatSends   = map[string][]time.Time{}
+)
+
+type retryTransport struct {
+	base http.RoundTripper
+}
+
+func newAPIClient() *http.Client {
+	return &http.Client{
+		Timeout:   apiClientTimeout,
+		Transport: &countingTransport{base: &retryTransport{base: http.DefaultTransport}},
+	}
+}
+
+func waitChatSlot(chatID string) {
+	if !strings.HasPrefix(chatID, "-") {
+		return
+	}
+	for {
+		chatSendsMu.Lock()
+		now := time.Now()
+		sends := chatSends[chatID]
+		for len(sends) > 0 && now.Sub(sends[0]) >= groupSendWindow {
+			sends = sends[1:]
+		}
+		if len(sends) < groupSendLimit {
+			chatSends[chatID] = append(sends, now)
+			chatSendsMu.Unlock()
+			return
+		}
+		chatSends[chatID] = sends
+		wait := groupSendWindow - now.Sub(sends[0])
+		chatSendsMu.Unlock()
+		time.Sleep(wait)
+	}
+}
+
+func retryAfter(body []byte) time.Duration {
+	var resp struct {
+		Parameters struct {
+			RetryAfter int `json:"retry_after"`
+		} `json:"parameters"`
+	}
+	if json.Unmarshal(body, &resp) != nil || resp.Parameters.RetryAfter <= 0 {
+		return 0
+	}
+	return time.Duration(resp.Parameters.RetryAfter) * time.Second
+}
+
+var idempotentPrefixes = []string{"get", "edit", "delete", "set", "pin", "unpin", "answer"}
+
+func idempotent(method string) bool {
+	for _, prefix := range idempotentPrefixes {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func retryable(method string, resp *http.Response, err error) bool {
+	if err != nil {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	if resp.StatusCode >= http.StatusInternalServerError {
+		return idempotent(method)
+	}
+	return resp.StatusCode == http.StatusTooManyRequests
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	method := path.Base(req.URL.Path)
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	backoff := sendBaseBackoff
+	for attempt := 0; ; attempt++ {
+		r := req.Clone(req.Context())
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		r.ContentLength = int64(len(body))
+		resp, err := t.base.RoundTrip(r)
+		if !retryable(method, resp, err) || attempt >= sendMaxRetries || req.Context().Err() != nil {
+			return resp, err
+		}
+
+		delay := min(backoff, sendMaxBackoff)
+		status := 0
+		if resp != nil {
+			status = resp.StatusCode
+			respBody, _ := io.ReadAll(resp.Body)
+			resp.Body.Close()
+			if d := retryAfter(respBody); d > 0 {
+				delay = d
+			}
+		}
+		slog.Warn("Повтор запроса к Telegram", "method", method, "status", status, "attempt", attempt+1, "delay", delay.String(), "err", err)
+		select {
+		case <-time.After(delay):
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		}
+		backoff *= 2
+	}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const subscribeReaction = "👍"
+
+var reactionWindow = 24 * time.Hour
+
+func reactionPoller(b *tele.Bot) tele.Poller {
+	handler := lockData(recoverPanics(onReaction))
+	return tele.NewMiddlewarePoller(b.Poller, func(u *tele.Update) bool {
+		if u.MessageReaction == nil {
+			return true
+		}
+		c := b.NewContext(*u)
+		if err := handler(c); err != nil {
+			b.OnError(err, c)
+		}
+		return false
+	})
+}
+
+func hasReaction(reactions []tele.Reaction, emoji string) bool {
+	for _, r := range reactions {
+		if r.Emoji == emoji {
+			return true
+		}
+	}
+	return false
+}
+
+func announcedTag(chatID int64, messageID int) *Tag {
+	for _, tag := range tagService.Chat(chatID) {
+		if a := tag.Announcement; a != nil && a.MessageID == messageID {
+			return tag
+		}
+	}
+	return nil
+}
+
+func onReaction(c tele.Context) error {
+	r := c.Update().MessageReaction
+	if readOnly || r.User == nil || r.User.IsBot || r.Chat == nil {
+		return nil
+	}
+	if !hasReaction(r.NewReaction, subscribeReaction) || hasReaction(r.OldReaction, subscribeReaction) {
+		return nil
+	}
+	if err := loadChats(r.Chat.ID); err != nil {
+		return err
+	}
+	tag := announcedTag(r.Chat.ID, r.MessageID)
+	if tag == nil || time.Since(tag.Announcement.At) > reactionWindow {
+		return nil
+	}
+	userID := r.User.ID
+	if needsApproval(tag) || isBanned(tag, userID) || isSubscribed(tag, userID) {
+		return nil
+	}
+	username := r.User.Username
+	if username == "" {
+		username = fmt.Sprintf("User%d", userID)
+	}
+	sub := Subscriber{ID: userID, Username: username}
+	tagService.Subscribe(tag, sub)
+	saveTag("subscribe", tag)
+	emitEvent(r.Chat.ID, eventSubscriberJoin, tag, &sub, "")
+	notifyMembership(traceContext(c), tag, sub, true)
+	welcomeSubscriber(traceContext(c), tag, sub)
+	slog.Info("👍 Подписка реакцией", "chat_id", r.Chat.ID, "tag", tag.Name, "user_id", userID)
+	sendQueue.send(traceContext(c), &tele.User{ID: userID}, tr(chatLang(r.Chat.ID), "reaction.subscribed", esc(tag.Name), esc(r.Chat.Title)))
+	return nil
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var readOnly bool
+
+func writable(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if !readOnly {
+			return next(c)
+		}
+		if c.Callback() != nil {
+			return c.Respond(&tele.CallbackResponse{Text: T(c, "readonly.rejected_plain"), ShowAlert: true})
+		}
+		return c.Send(T(c, "readonly.rejected"))
+	}
+}
+
+func handleReadOnly(c tele.Context) error {
+	switch strings.ToLower(strings.TrimSpace(c.Message().Payload)) {
+	case "on":
+		readOnly = true
+	case "off":
+		readOnly = false
+	case "":
+		readOnly = !readOnly
+	default:
+		return c.Send(T(c, "readonly.usage"))
+	}
+	slog.Info("Режим только для чтения переключён", "read_only", readOnly, "user_id", c.Sender().ID)
+	if readOnly {
+		return c.Send(T(c, "readonly.on"))
+	}
+	return c.Send(T(c, "readonly.off"))
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"syscall"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var restartOnly = []string{"token", "read_only", "storage.", "http.", "telemetry.", "backup.s3.", "backup.hours", "limits.digest_hours"}
+
+func needsRestart(name string) bool {
+	for _, prefix := range restartOnly {
+		if name == prefix || (strings.HasSuffix(prefix, ".") && strings.HasPrefix(name, prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+func configFields(c *Config) ([]string, map[string]reflect.Value) {
+	var names []string
+	fields := map[string]reflect.Value{}
+	walkConfig(reflect.ValueOf(c).Elem(), "", func(field reflect.Value, name, env string) {
+		names = append(names, name)
+		fields[name] = field
+	})
+	return names, fields
+}
+
+func reloadConfig(b *tele.Bot) (applied, skipped []string, err error) {
+	next, err := loadConfig()
+	if err != nil {
+		return nil, nil, err
+	}
+	prev, prevCatalogs := cfg, catalogs
+	_, old := configFields(&prev)
+	names, fields := configFields(&next)
+	for _, name := range names {
+		if reflect.DeepEqual(old[name].Interface(), fields[name].Interface()) {
+			continue
+		}
+		if needsRestart(name) {
+			skipped = append(skipped, name)
+			fields[name].Set(old[name])
+			continue
+		}
+		applied = append(applied, name)
+	}
+	cfg = next
+	applyConfig()
+	if err := loadCatalogs(); err != nil {
+		cfg, catalogs = prev, prevCatalogs
+		applyConfig()
+		return nil, nil, err
+	}
+	blockedWords = nil
+	loadBlocklist()
+	registerCommands(b)
+	slog.Info("🔄 Настройки перечитаны", "file", cfg.File, "applied", applied, "restart_needed", skipped)
+	return applied, skipped, nil
+}
+
+func handleReload(c tele.Context) error {
+	applied, skipped, err := reloadConfig(c.Bot())
+	if err != nil {
+		return c.Send(T(c, "reload.failed", esc(err.Error())))
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "reload.done"))
+	if len(applied) > 0 {
+		b.WriteString(T(c, "reload.applied", esc(strings.Join(applied, ", "))))
+	}
+	if len(skipped) > 0 {
+		b.WriteString(T(c, "reload.restart", esc(strings.Join(skipped, ", "))))
+	}
+	return c.Send(b.String())
+}
+
+func watchReload(b *tele.Bot) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	go func() {
+		for range sig {
+			dataMu.Lock()
+			_, _, err := reloadConfig(b)
+			dataMu.Unlock()
+			if err != nil {
+				slog.Error("Не удалось перечитать настройки", "err", err)
+			}
+		}
+	}()
+}
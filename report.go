@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	errorRepeatThreshold = 3
+	errorRepeatWindow    = 10 * time.Minute
+	stackExcerptLines    = 20
+)
+
+type errorCount struct {
+	count int
+	first time.Time
+}
+
+var (
+	errorsChatID int64
+	reportBot    *tele.Bot
+	reportMu     sync.Mutex
+	errorCounts  = map[string]*errorCount{}
+)
+
+func setupErrorReports(b *tele.Bot) {
+	errorsChatID = cfg.ErrorsChatID
+	reportBot = b
+}
+
+func repeatedError(key string) bool {
+	reportMu.Lock()
+	defer reportMu.Unlock()
+	now := time.Now()
+	ec := errorCounts[key]
+	if ec == nil || now.Sub(ec.first) > errorRepeatWindow {
+		ec = &errorCount{first: now}
+		errorCounts[key] = ec
+	}
+	ec.count++
+	if ec.count < errorRepeatThreshold {
+		return false
+	}
+	delete(errorCounts, key)
+	return true
+}
+
+func stackExcerpt(stack []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(stack)), "\n")
+	if len(lines) > stackExcerptLines {
+		lines = append(lines[:stackExcerptLines], "…")
+	}
+	return strings.Join(lines, "\n")
+}
+
+func reportError(c tele.Context, err error, stack []byte) {
+	if errorsChatID == 0 || reportBot == nil {
+		return
+	}
+	command, chat := "—", "—"
+	if c != nil {
+		if cmd := commandOf(c); cmd != "" {
+			command = cmd
+		}
+		if ch := c.Chat(); ch != nil {
+			chat = fmt.Sprintf("%d %s", ch.ID, ch.Title)
+		}
+	}
+	text := tr(defaultLang, "report.error", esc(command), esc(chat), esc(err.Error()))
+	if len(stack) > 0 {
+		text += tr(defaultLang, "report.stack", esc(stackExcerpt(stack)))
+	}
+	if _, sendErr := reportBot.Send(&tele.Chat{ID: errorsChatID}, text); sendErr != nil {
+		slog.Warn("Не удалось отправить отчёт об ошибке", "chat_id", errorsChatID, "err", sendErr)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const deletedRetention = 7 * 24 * time.Hour
+
+func findRemovedTag(c tele.Context, name string) *Tag {
+	chatID := c.Chat().ID
+	name = strings.ToLower(name)
+	var found *Tag
+	for _, tag := range data.Tags {
+		if tag.Active() || strings.ToLower(tag.Name) != name {
+			continue
+		}
+		if chatID < 0 && tag.ChatID != chatID && tag.ChatID != 0 {
+			continue
+		}
+		if chatID > 0 && !canManage(c, tag) {
+			continue
+		}
+		if found == nil || removedAt(tag).After(removedAt(found)) {
+			found = tag
+		}
+	}
+	return found
+}
+
+func removedAt(tag *Tag) time.Time {
+	if tag.DeletedAt != nil {
+		return *tag.DeletedAt
+	}
+	if tag.ArchivedAt != nil {
+		return *tag.ArchivedAt
+	}
+	return time.Time{}
+}
+
+func handleRestore(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "restore.usage"))
+	}
+	tag := findRemovedTag(c, args[0])
+	if tag == nil {
+		return c.Send(T(c, "restore.not_found"))
+	}
+	if tagService.Find(tag.ChatID, tag.Name) != nil {
+		return c.Send(T(c, "restore.conflict"))
+	}
+	tag.DeletedAt = nil
+	tag.DeletedBy = 0
+	tag.ArchivedAt = nil
+	tag.StaleWarnedAt = nil
+	tagService.Add(tag)
+	saveTag("restore", tag)
+	return c.Send(T(c, "restore.done", esc(tag.Name), len(tag.Subscribers)))
+}
+
+func purgeDeletedTags(b *tele.Bot) {
+	cutoff := time.Now().Add(-deletedRetention)
+	kept := []*Tag{}
+	for _, tag := range data.Tags {
+		if tag.DeletedAt == nil || tag.DeletedAt.After(cutoff) {
+			kept = append(kept, tag)
+		} else {
+			journalTag("purge", tag)
+		}
+	}
+	if len(kept) != len(data.Tags) {
+		data.Tags = kept
+		saveData()
+	}
+}
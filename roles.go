@@ -0,0 +1,202 @@
+package main
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func isChatAdmin(c tele.Context, chatID int64) bool {
+	chat := c.Chat()
+	if chat == nil || chat.ID != chatID || chat.Type == tele.ChatPrivate {
+		return false
+	}
+	admins, err := chatAdmins(traceContext(c), c.Bot(), chat)
+	if err != nil {
+		return false
+	}
+	return admins[c.Sender().ID]
+}
+
+func isModerator(tag *Tag, userID int64) bool {
+	for _, mod := range tag.Moderators {
+		if mod.ID == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func isOwner(c tele.Context, tag *Tag) bool {
+	return tag.CreatorID == c.Sender().ID || isChatAdmin(c, tag.ChatID)
+}
+
+func canManage(c tele.Context, tag *Tag) bool {
+	return isOwner(c, tag) || isModerator(tag, c.Sender().ID)
+}
+
+func resolveUser(c tele.Context, target *Tag, arg string) (Subscriber, bool) {
+	if reply := c.Message().ReplyTo; reply != nil && reply.Sender != nil && arg == "" {
+		return Subscriber{ID: reply.Sender.ID, Username: reply.Sender.Username}, true
+	}
+	name := strings.ToLower(strings.TrimPrefix(arg, "@"))
+	if name == "" {
+		return Subscriber{}, false
+	}
+	chats := map[int64]bool{c.Chat().ID: true, target.ChatID: true}
+	for _, tag := range data.Tags {
+		if !chats[tag.ChatID] {
+			continue
+		}
+		for _, sub := range tag.Subscribers {
+			if strings.ToLower(sub.Username) == name {
+				return sub, true
+			}
+		}
+		if strings.ToLower(tag.CreatorName) == name {
+			return Subscriber{ID: tag.CreatorID, Username: tag.CreatorName}, true
+		}
+	}
+	for i := len(data.Mentions) - 1; i >= 0; i-- {
+		if chats[data.Mentions[i].ChatID] && strings.ToLower(data.Mentions[i].Username) == name {
+			return Subscriber{ID: data.Mentions[i].UserID, Username: data.Mentions[i].Username}, true
+		}
+	}
+	return Subscriber{}, false
+}
+
+func parseTagAndUser(c tele.Context, usage string) (*Tag, Subscriber, error) {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return nil, Subscriber{}, c.Send(usage)
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return nil, Subscriber{}, tagNotFound(c, args[0])
+	}
+	arg := ""
+	if len(args) > 1 {
+		arg = args[1]
+	}
+	user, ok := resolveUser(c, tag, arg)
+	if !ok {
+		return nil, Subscriber{}, c.Send(T(c, "user.unknown"))
+	}
+	return tag, user, nil
+}
+
+func handleAddMod(c tele.Context) error {
+	tag, user, err := parseTagAndUser(c, T(c, "mod.add_usage"))
+	if tag == nil {
+		return err
+	}
+	if !isOwner(c, tag) {
+		return c.Send(T(c, "mod.add_denied"))
+	}
+	if user.ID == tag.CreatorID || isModerator(tag, user.ID) {
+		return c.Send(T(c, "mod.already"))
+	}
+	tag.Moderators = append(tag.Moderators, user)
+	saveTag("update", tag)
+	return c.Send(T(c, "mod.added", esc(user.Username), esc(tag.Name)))
+}
+
+func handleDelMod(c tele.Context) error {
+	tag, user, err := parseTagAndUser(c, T(c, "mod.del_usage"))
+	if tag == nil {
+		return err
+	}
+	if !isOwner(c, tag) {
+		return c.Send(T(c, "mod.del_denied"))
+	}
+	mods := []Subscriber{}
+	for _, mod := range tag.Moderators {
+		if mod.ID != user.ID {
+			mods = append(mods, mod)
+		}
+	}
+	if len(mods) == len(tag.Moderators) {
+		return c.Send(T(c, "mod.not_mod"))
+	}
+	tag.Moderators = mods
+	saveTag("update", tag)
+	return c.Send(T(c, "mod.removed", esc(user.Username), esc(tag.Name)))
+}
+
+func adminOnly(deniedKey string) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			if c.Chat().Type != tele.ChatPrivate && !isChatAdmin(c, c.Chat().ID) {
+				if c.Callback() != nil {
+					return c.Respond(&tele.CallbackResponse{Text: T(c, deniedKey)})
+				}
+				return c.Send(T(c, deniedKey))
+			}
+			return next(c)
+		}
+	}
+}
+
+func tagArg(c tele.Context) string {
+	for _, arg := range strings.Fields(c.Text())[1:] {
+		if !strings.HasPrefix(arg, "--") {
+			return strings.TrimPrefix(arg, "#")
+		}
+	}
+	return ""
+}
+
+func managesTag(deniedKey string, find func(c tele.Context, name string) *Tag) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		return func(c tele.Context) error {
+			if name := tagArg(c); name != "" {
+				if tag := find(c, name); tag != nil && !canManage(c, tag) {
+					return c.Send(T(c, deniedKey))
+				}
+			}
+			return next(c)
+		}
+	}
+}
+
+func managerOnly(deniedKey string) tele.MiddlewareFunc {
+	return managesTag(deniedKey, func(c tele.Context, name string) *Tag {
+		return tagService.Find(c.Chat().ID, name)
+	})
+}
+
+func unlessOwner(mw tele.MiddlewareFunc) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		guarded := mw(next)
+		return func(c tele.Context) error {
+			if isBotOwner(c) {
+				return next(c)
+			}
+			return guarded(c)
+		}
+	}
+}
+
+func withValue(mw tele.MiddlewareFunc) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		guarded := mw(next)
+		return func(c tele.Context) error {
+			if len(strings.Fields(c.Text())) > 2 {
+				return guarded(c)
+			}
+			return next(c)
+		}
+	}
+}
+
+func withArgs(mw tele.MiddlewareFunc) tele.MiddlewareFunc {
+	return func(next tele.HandlerFunc) tele.HandlerFunc {
+		guarded := mw(next)
+		return func(c tele.Context) error {
+			if len(strings.Fields(c.Text())) > 1 {
+				return guarded(c)
+			}
+			return next(c)
+		}
+	}
+}
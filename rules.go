@@ -0,0 +1,174 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"regexp/syntax"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	defaultRuleCooldown = 10
+	maxRules            = 50
+	maxPatternLen       = 200
+	maxPatternInsts     = 1000
+)
+
+var ruleRegexps = map[*TagRule]*regexp.Regexp{}
+
+func compileRule(pattern string) (*regexp.Regexp, error) {
+	if len(pattern) > maxPatternLen {
+		return nil, fmt.Errorf("pattern is longer than %d bytes", maxPatternLen)
+	}
+	parsed, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return nil, err
+	}
+	prog, err := syntax.Compile(parsed.Simplify())
+	if err != nil {
+		return nil, err
+	}
+	if len(prog.Inst) > maxPatternInsts {
+		return nil, fmt.Errorf("pattern is too complex")
+	}
+	return regexp.Compile("(?i)" + pattern)
+}
+
+func ruleRegexp(rule *TagRule) (*regexp.Regexp, error) {
+	if re, ok := ruleRegexps[rule]; ok {
+		return re, nil
+	}
+	re, err := compileRule(rule.Keyword)
+	if err != nil {
+		return nil, err
+	}
+	ruleRegexps[rule] = re
+	return re, nil
+}
+
+func forgetRules(chatID int64) {
+	if s := data.Chats[chatID]; s != nil {
+		for _, rule := range s.Rules {
+			delete(ruleRegexps, rule)
+		}
+	}
+}
+
+func ruleMatches(rule *TagRule, text, lower string) bool {
+	if !rule.Regex {
+		return strings.Contains(lower, strings.ToLower(rule.Keyword))
+	}
+	re, err := ruleRegexp(rule)
+	return err == nil && re.MatchString(text)
+}
+
+func ruleTags(c tele.Context, text string, seen map[string]bool) []*Tag {
+	chatID := c.Chat().ID
+	s := data.Chats[chatID]
+	if s == nil || len(s.Rules) == 0 {
+		return nil
+	}
+	now := time.Now()
+	lower := strings.ToLower(text)
+	var tags []*Tag
+	for _, rule := range s.Rules {
+		if seen[strings.ToLower(rule.Tag)] || now.Sub(rule.LastFired) < time.Duration(rule.Cooldown)*time.Minute {
+			continue
+		}
+		if !ruleMatches(rule, text, lower) {
+			continue
+		}
+		tag := tagService.Find(chatID, rule.Tag)
+		if tag == nil || !allowedInTopic(tag, threadOf(c)) || !canPing(c, tag) {
+			continue
+		}
+		rule.LastFired = now
+		seen[strings.ToLower(tag.Name)] = true
+		tags = append(tags, tag)
+	}
+	return tags
+}
+
+func handleRule(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "rule.groups_only"))
+	}
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return listRules(c)
+	}
+	s := chatSettings(c.Chat().ID)
+	switch mode := strings.ToLower(args[0]); mode {
+	case "add", "regex":
+		if len(args) < 3 || len(args) > 4 {
+			return c.Send(T(c, "rule.usage"))
+		}
+		if len(s.Rules) >= maxRules {
+			return c.Send(T(c, "rule.too_many", maxRules))
+		}
+		tag := tagService.Find(c.Chat().ID, strings.TrimPrefix(args[2], "#"))
+		if tag == nil {
+			return tagNotFound(c, strings.TrimPrefix(args[2], "#"))
+		}
+		if mode == "regex" {
+			if _, err := compileRule(args[1]); err != nil {
+				return c.Send(T(c, "rule.bad_regex", esc(err.Error())))
+			}
+		}
+		cooldown := defaultRuleCooldown
+		if len(args) == 4 {
+			n, err := strconv.Atoi(args[3])
+			if err != nil || n < 0 {
+				return c.Send(T(c, "rule.usage"))
+			}
+			cooldown = n
+		}
+		rule := &TagRule{ID: 1, Keyword: args[1], Regex: mode == "regex", Tag: tag.Name, Cooldown: cooldown, CreatorID: c.Sender().ID}
+		for _, other := range s.Rules {
+			if other.ID >= rule.ID {
+				rule.ID = other.ID + 1
+			}
+		}
+		s.Rules = append(s.Rules, rule)
+		saveData()
+		return c.Send(T(c, "rule.added", rule.ID, esc(rule.Keyword), esc(tag.Name), cooldown))
+	case "del":
+		if len(args) != 2 {
+			return c.Send(T(c, "rule.usage"))
+		}
+		id, _ := strconv.Atoi(args[1])
+		for i, rule := range s.Rules {
+			if rule.ID == id {
+				delete(ruleRegexps, rule)
+				s.Rules = append(s.Rules[:i], s.Rules[i+1:]...)
+				saveData()
+				return c.Send(T(c, "rule.deleted", id))
+			}
+		}
+		return c.Send(T(c, "rule.not_found", esc(args[1])))
+	}
+	return c.Send(T(c, "rule.usage"))
+}
+
+func listRules(c tele.Context) error {
+	s := data.Chats[c.Chat().ID]
+	if s == nil || len(s.Rules) == 0 {
+		return c.Send(T(c, "rule.none"))
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "rule.header"))
+	for _, rule := range s.Rules {
+		kind := ""
+		if rule.Regex {
+			kind = "🔣 "
+		}
+		b.WriteString(fmt.Sprintf("%d. %s<code>%s</code> → <code>#%s</code> · %s\n",
+			rule.ID, kind, esc(rule.Keyword), esc(rule.Tag), T(c, "rule.cooldown", rule.Cooldown)))
+	}
+	b.WriteString(T(c, "rule.footer"))
+	return c.Send(b.String())
+}
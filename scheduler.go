@@ -0,0 +1,47 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      func(b *tele.Bot)
+}
+
+var (
+	dataMu sync.Mutex
+	jobs   []job
+)
+
+func schedule(name string, interval time.Duration, run func(b *tele.Bot)) {
+	jobs = append(jobs, job{name: name, interval: interval, run: run})
+}
+
+func startScheduler(b *tele.Bot) {
+	for _, j := range jobs {
+		go func(j job) {
+			ticker := time.NewTicker(j.interval)
+			defer ticker.Stop()
+			for range ticker.C {
+				dataMu.Lock()
+				j.run(b)
+				dataMu.Unlock()
+			}
+		}(j)
+		slog.Info("⏰ Задача запланирована", "job", j.name, "interval", j.interval.String())
+	}
+}
+
+func lockData(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		dataMu.Lock()
+		defer dataMu.Unlock()
+		return next(c)
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/getsentry/sentry-go"
+	tele "gopkg.in/telebot.v3"
+)
+
+const sentryFlushTimeout = 5 * time.Second
+
+var sentryEnabled bool
+
+func setupSentry() {
+	dsn := cfg.Telemetry.SentryDSN
+	if dsn == "" {
+		return
+	}
+	err := sentry.Init(sentry.ClientOptions{
+		Dsn:         dsn,
+		Environment: cfg.Telemetry.SentryEnvironment,
+		Release:     cfg.Telemetry.SentryRelease,
+	})
+	if err != nil {
+		slog.Warn("Не удалось подключить Sentry", "err", err)
+		return
+	}
+	sentryEnabled = true
+	slog.Info("🛰 Ошибки отправляются в Sentry")
+}
+
+func captureError(c tele.Context, kind string, err error, attrs ...any) {
+	if !sentryEnabled || err == nil {
+		return
+	}
+	sentry.WithScope(func(scope *sentry.Scope) {
+		scope.SetTag("kind", kind)
+		if c != nil {
+			if cmd := commandOf(c); cmd != "" {
+				scope.SetTag("command", cmd)
+			}
+			if chat := c.Chat(); chat != nil {
+				scope.SetTag("chat_id", fmt.Sprint(chat.ID))
+				scope.SetContext("chat", sentry.Context{"id": chat.ID, "type": string(chat.Type), "title": chat.Title})
+			}
+			if user := c.Sender(); user != nil {
+				scope.SetUser(sentry.User{ID: fmt.Sprint(user.ID)})
+			}
+		}
+		for i := 0; i+1 < len(attrs); i += 2 {
+			scope.SetExtra(fmt.Sprint(attrs[i]), attrs[i+1])
+		}
+		sentry.CaptureException(err)
+	})
+}
+
+func flushSentry() {
+	if sentryEnabled {
+		sentry.Flush(sentryFlushTimeout)
+	}
+}
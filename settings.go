@@ -0,0 +1,13 @@
+package main
+
+func chatSettings(chatID int64) *ChatSettings {
+	if data.Chats == nil {
+		data.Chats = map[int64]*ChatSettings{}
+	}
+	s, ok := data.Chats[chatID]
+	if !ok {
+		s = &ChatSettings{}
+		data.Chats[chatID] = s
+	}
+	return s
+}
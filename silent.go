@@ -0,0 +1,35 @@
+package main
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+func handleSilent(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		return c.Send(T(c, "silent.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	if len(args) > 1 {
+		switch strings.ToLower(args[1]) {
+		case "on":
+			tag.Silent = true
+		case "off":
+			tag.Silent = false
+		default:
+			return c.Send(T(c, "silent.usage"))
+		}
+	} else {
+		tag.Silent = !tag.Silent
+	}
+	saveTag("update", tag)
+	if tag.Silent {
+		return c.Send(T(c, "silent.on", esc(tag.Name)))
+	}
+	return c.Send(T(c, "silent.off", esc(tag.Name)))
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	snoozeMinutes = 60
+	maxSnoozeBtns = 3
+)
+
+var btnSnooze = tele.Btn{Unique: "snooze"}
+
+func snoozeRow(c tele.Context, markup *tele.ReplyMarkup, tags []*Tag) tele.Row {
+	var btns []tele.Btn
+	for _, tag := range tags {
+		if len(btns) == maxSnoozeBtns {
+			break
+		}
+		label := T(c, "snooze.button", snoozeMinutes/60)
+		if len(tags) > 1 {
+			label = T(c, "snooze.button_tag", tag.Name, snoozeMinutes/60)
+		}
+		btns = append(btns, markup.Data(label, btnSnooze.Unique, tag.Name+"|"+strconv.Itoa(snoozeMinutes)))
+	}
+	return markup.Row(btns...)
+}
+
+func snoozedUntil(userID int64, tag *Tag) time.Time {
+	if p := data.Users[userID]; p != nil {
+		return p.Snoozed[strings.ToLower(tag.Name)]
+	}
+	return time.Time{}
+}
+
+func onSnooze(c tele.Context) error {
+	name, minutes, _ := strings.Cut(c.Callback().Data, "|")
+	n, err := strconv.Atoi(minutes)
+	tag := tagService.Find(c.Chat().ID, name)
+	if err != nil || n <= 0 || tag == nil {
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "tag_not_found")})
+	}
+	userID := c.Sender().ID
+	if !isSubscribed(tag, userID) {
+		return c.Respond(&tele.CallbackResponse{Text: T(c, "ack.not_subscribed")})
+	}
+	p := userPrefs(userID)
+	now := time.Now()
+	for key, until := range p.Snoozed {
+		if until.Before(now) {
+			delete(p.Snoozed, key)
+		}
+	}
+	if p.Snoozed == nil {
+		p.Snoozed = map[string]time.Time{}
+	}
+	until := now.Add(time.Duration(n) * time.Minute)
+	p.Snoozed[strings.ToLower(tag.Name)] = until
+	saveData()
+	return c.Respond(&tele.CallbackResponse{Text: T(c, "snooze.done", tag.Name, until.In(userLocation(userID)).Format("15:04"))})
+}
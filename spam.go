@@ -0,0 +1,134 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	defaultSpamLimits = SpamLimits{Pings: 10, Window: 5, Mute: 30}
+
+	pingHistory = map[string][]time.Time{}
+)
+
+func spamLimits(chatID int64) SpamLimits {
+	if s := data.Chats[chatID]; s != nil && s.Spam != nil {
+		return *s.Spam
+	}
+	return defaultSpamLimits
+}
+
+func spamKey(c tele.Context) string {
+	return fmt.Sprintf("%d:%d", c.Chat().ID, c.Sender().ID)
+}
+
+func shadowMuted(c tele.Context) bool {
+	if c.Chat().Type == tele.ChatPrivate {
+		return false
+	}
+	_, ok, err := kv.Get("spam:" + spamKey(c))
+	return err == nil && ok
+}
+
+func notePings(c tele.Context, n int) {
+	limits := spamLimits(c.Chat().ID)
+	if n == 0 || limits.Off || limits.Pings <= 0 || c.Chat().Type == tele.ChatPrivate {
+		return
+	}
+	key := spamKey(c)
+	now := time.Now()
+	cutoff := now.Add(-time.Duration(limits.Window) * time.Minute)
+	recent := pingHistory[key][:0]
+	for _, at := range pingHistory[key] {
+		if at.After(cutoff) {
+			recent = append(recent, at)
+		}
+	}
+	for i := 0; i < n; i++ {
+		recent = append(recent, now)
+	}
+	if len(recent) <= limits.Pings {
+		pingHistory[key] = recent
+		return
+	}
+	delete(pingHistory, key)
+	mute := time.Duration(limits.Mute) * time.Minute
+	kv.Set("spam:"+key, []byte{1}, mute)
+	slog.Warn("🚯 Пользователь временно игнорируется за спам упоминаниями",
+		"chat_id", c.Chat().ID, "user_id", c.Sender().ID, "pings", len(recent), "mute", mute.String())
+	if limits.Report {
+		reportSpammer(c, len(recent), limits)
+	}
+}
+
+func reportSpammer(c tele.Context, pings int, limits SpamLimits) {
+	admins, err := chatAdmins(traceContext(c), c.Bot(), c.Chat())
+	if err != nil {
+		slog.Warn("Не удалось получить админов для отчёта о спаме", "chat_id", c.Chat().ID, "err", err)
+		return
+	}
+	sender := Subscriber{ID: c.Sender().ID, Username: c.Sender().Username}
+	text := T(c, "spam.report", mentionHTML(sender), esc(c.Chat().Title), pings, limits.Window, limits.Mute)
+	for id := range admins {
+		if id != c.Sender().ID {
+			sendQueue.send(traceContext(c), &tele.User{ID: id}, text)
+		}
+	}
+}
+
+func handleAntiSpam(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "spam.groups_only"))
+	}
+	args := strings.Fields(c.Text())[1:]
+	limits := spamLimits(c.Chat().ID)
+	if len(args) == 0 {
+		if limits.Off {
+			return c.Send(T(c, "spam.off"))
+		}
+		return c.Send(T(c, "spam.current", limits.Pings, limits.Window, limits.Mute, onOff(c, limits.Report)))
+	}
+	switch strings.ToLower(args[0]) {
+	case "off":
+		limits.Off = true
+	case "on":
+		limits.Off = false
+	case "report":
+		if len(args) < 2 || (args[1] != "on" && args[1] != "off") {
+			return c.Send(T(c, "spam.usage"))
+		}
+		limits.Report = args[1] == "on"
+	default:
+		if len(args) < 3 {
+			return c.Send(T(c, "spam.usage"))
+		}
+		var nums [3]int
+		for i := range nums {
+			n, err := strconv.Atoi(args[i])
+			if err != nil || n <= 0 {
+				return c.Send(T(c, "spam.usage"))
+			}
+			nums[i] = n
+		}
+		limits.Off = false
+		limits.Pings, limits.Window, limits.Mute = nums[0], nums[1], nums[2]
+	}
+	chatSettings(c.Chat().ID).Spam = &limits
+	saveData()
+	if limits.Off {
+		return c.Send(T(c, "spam.off"))
+	}
+	return c.Send(T(c, "spam.current", limits.Pings, limits.Window, limits.Mute, onOff(c, limits.Report)))
+}
+
+func onOff(c tele.Context, on bool) string {
+	if on {
+		return T(c, "spam.on_word")
+	}
+	return T(c, "spam.off_word")
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	staleAfter = 30 * 24 * time.Hour
+	staleGrace = 7 * 24 * time.Hour
+)
+
+func lastActivity(tag *Tag) time.Time {
+	if tag.Stats.LastUsed.After(tag.CreatedAt) {
+		return tag.Stats.LastUsed
+	}
+	return tag.CreatedAt
+}
+
+func notifyCreator(b *tele.Bot, tag *Tag, text string) {
+	if tag.ChatID != 0 && tag.CreatorName != "" {
+		text = "@" + esc(tag.CreatorName) + " " + text
+	}
+	name, creator := tag.Name, tag.CreatorID
+	direct := func() {
+		sendQueue.enqueue(context.Background(), &tele.User{ID: creator}, text, func(_ *tele.Message, err error) {
+			if err != nil {
+				slog.Warn("Не удалось уведомить создателя тега", "tag", name, "err", err)
+			}
+		})
+	}
+	if tag.ChatID == 0 {
+		direct()
+		return
+	}
+	sendQueue.enqueue(context.Background(), &tele.Chat{ID: tag.ChatID}, text, func(_ *tele.Message, err error) {
+		if err != nil {
+			direct()
+		}
+	})
+}
+
+func checkStaleTags(b *tele.Bot) {
+	now := time.Now()
+	changed := false
+	for _, tag := range data.Tags {
+		if !tag.Active() {
+			continue
+		}
+		if tag.StaleWarnedAt == nil {
+			idle := now.Sub(lastActivity(tag))
+			if idle < staleAfter {
+				continue
+			}
+			notifyCreator(b, tag, tr(chatLang(tag.ChatID), "stale.warning",
+				esc(tag.Name), int(idle.Hours()/24), int(staleGrace.Hours()/24)))
+			tag.StaleWarnedAt = &now
+			changed = true
+			continue
+		}
+		if now.Sub(*tag.StaleWarnedAt) >= staleGrace {
+			tagService.Remove(tag)
+			tag.ArchivedAt = &now
+			journalTag("archive", tag)
+			notifyCreator(b, tag, tr(chatLang(tag.ChatID), "stale.archived", esc(tag.Name)))
+			changed = true
+		}
+	}
+	if changed {
+		saveData()
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const mentionRetention = 30 * 24 * time.Hour
+
+func recordMention(tag *Tag, c tele.Context) {
+	now := time.Now()
+	sender := c.Sender()
+	tag.Stats.Mentions++
+	tag.Stats.LastUsed = now
+	tag.Stats.LastBy = sender.Username
+	if tag.Stats.ByUser == nil {
+		tag.Stats.ByUser = map[int64]int{}
+	}
+	tag.Stats.ByUser[sender.ID]++
+	tag.StaleWarnedAt = nil
+
+	cutoff := now.Add(-mentionRetention)
+	kept := data.Mentions[:0]
+	for _, ev := range data.Mentions {
+		if ev.At.After(cutoff) {
+			kept = append(kept, ev)
+		}
+	}
+	data.Mentions = append(kept, MentionEvent{
+		Tag:       tag.Name,
+		ChatID:    c.Chat().ID,
+		MessageID: c.Message().ID,
+		UserID:    sender.ID,
+		Username:  sender.Username,
+		At:        now,
+	})
+}
+
+func topPinger(tag *Tag) (int64, int) {
+	ids := make([]int64, 0, len(tag.Stats.ByUser))
+	for id := range tag.Stats.ByUser {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ci, cj := tag.Stats.ByUser[ids[i]], tag.Stats.ByUser[ids[j]]
+		if ci != cj {
+			return ci > cj
+		}
+		return ids[i] < ids[j]
+	})
+	if len(ids) == 0 {
+		return 0, 0
+	}
+	return ids[0], tag.Stats.ByUser[ids[0]]
+}
+
+func usernameOf(userID int64) string {
+	for i := len(data.Mentions) - 1; i >= 0; i-- {
+		if data.Mentions[i].UserID == userID && data.Mentions[i].Username != "" {
+			return data.Mentions[i].Username
+		}
+	}
+	for _, tag := range data.Tags {
+		for _, sub := range tag.Subscribers {
+			if sub.ID == userID {
+				return sub.Username
+			}
+		}
+		if tag.CreatorID == userID && tag.CreatorName != "" {
+			return tag.CreatorName
+		}
+	}
+	return ""
+}
+
+func handleStats(c tele.Context) error {
+	switch mode := strings.ToLower(strings.TrimSpace(c.Message().Payload)); mode {
+	case "":
+	case "subs", "week":
+		return c.Send(statsBars(c, mode))
+	case "chart":
+		if c.Chat().Type == tele.ChatPrivate {
+			return c.Send(T(c, "stats.chart_groups_only"))
+		}
+		return statsCharts(c)
+	default:
+		return c.Send(T(c, "stats.usage"))
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "stats.header"))
+	for _, tag := range tagService.InChat(c.Chat().ID) {
+		if !visibleTo(tag, c.Sender().ID) {
+			continue
+		}
+		b.WriteString(T(c, "stats.line", esc(tag.Name), len(tag.Subscribers), tag.Stats.Mentions))
+		if !tag.Stats.LastUsed.IsZero() {
+			b.WriteString(T(c, "stats.last", tag.Stats.LastUsed.In(zoneOf(c)).Format("02.01.2006 15:04")))
+			if tag.Stats.LastBy != "" {
+				b.WriteString(T(c, "stats.last_by", esc(tag.Stats.LastBy)))
+			}
+		}
+		if id, n := topPinger(tag); n > 0 {
+			if name := usernameOf(id); name != "" {
+				b.WriteString(T(c, "stats.top", esc(name), n))
+			}
+		}
+		b.WriteString("\n")
+	}
+	return c.Send(b.String())
+}
+
+const barWidth = 12
+
+var barParts = []string{"", "▏", "▎", "▍", "▌", "▋", "▊", "▉"}
+
+func bar(n, max int) string {
+	if max <= 0 || n <= 0 {
+		return ""
+	}
+	eighths := n * barWidth * 8 / max
+	if eighths == 0 {
+		eighths = 1
+	}
+	return strings.Repeat("█", eighths/8) + barParts[eighths%8]
+}
+
+func statsBars(c tele.Context, mode string) string {
+	counts := map[string]int{}
+	since := time.Now().Add(-7 * 24 * time.Hour)
+	for _, tag := range tagService.InChat(c.Chat().ID) {
+		if !visibleTo(tag, c.Sender().ID) {
+			continue
+		}
+		if mode == "subs" {
+			counts[tag.Name] = len(tag.Subscribers)
+		} else {
+			counts[tag.Name] = 0
+		}
+	}
+	if mode == "week" {
+		for _, ev := range data.Mentions {
+			if ev.ChatID != c.Chat().ID || ev.At.Before(since) {
+				continue
+			}
+			if tag := tagService.Find(ev.ChatID, ev.Tag); tag != nil {
+				if _, ok := counts[tag.Name]; ok {
+					counts[tag.Name]++
+				}
+			}
+		}
+	}
+	rows := topOf(counts, len(counts))
+	if len(rows) == 0 || rows[0].count == 0 {
+		return T(c, "stats.bars_empty")
+	}
+	width := 0
+	for _, r := range rows {
+		width = max(width, len([]rune(r.name)))
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "stats.bars_"+mode))
+	b.WriteString("<pre>")
+	for _, r := range rows {
+		pad := strings.Repeat(" ", width-len([]rune(r.name)))
+		b.WriteString(fmt.Sprintf("#%s%s %s %d\n", esc(r.name), pad, bar(r.count, rows[0].count), r.count))
+	}
+	b.WriteString("</pre>")
+	return b.String()
+}
+
+func handleTrending(c tele.Context) error {
+	chats := map[int64]bool{c.Chat().ID: true}
+	if c.Chat().Type == tele.ChatPrivate {
+		chats = map[int64]bool{}
+		for _, tag := range tagService.Subscriptions(c.Sender().ID) {
+			chats[tag.ChatID] = true
+		}
+	}
+	cutoff := time.Now().Add(-7 * 24 * time.Hour)
+	counts := map[string]int{}
+	for _, ev := range data.Mentions {
+		if !chats[ev.ChatID] || ev.At.Before(cutoff) {
+			continue
+		}
+		if tag := tagService.Find(ev.ChatID, ev.Tag); tag != nil && visibleTo(tag, c.Sender().ID) {
+			counts[tag.Name]++
+		}
+	}
+	if len(counts) == 0 {
+		return c.Send(T(c, "trending.empty"))
+	}
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return strings.ToLower(names[i]) < strings.ToLower(names[j])
+	})
+	if len(names) > 10 {
+		names = names[:10]
+	}
+	var b strings.Builder
+	b.WriteString(T(c, "trending.header"))
+	for i, name := range names {
+		b.WriteString(T(c, "trending.line", i+1, esc(name), counts[name]))
+	}
+	return c.Send(b.String())
+}
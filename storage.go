@@ -0,0 +1,143 @@
+package main
+
+import (
+	"log/slog"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+var (
+	store        Storage
+	instanceLock *storage.Lock
+)
+
+func openStorage() (Storage, error) {
+	if err := storage.UseKey(cfg.Storage.Key); err != nil {
+		return nil, err
+	}
+	if cfg.Storage.DatabaseURL == "" {
+		lock, err := storage.AcquireLock(dataPath())
+		if err != nil {
+			return nil, err
+		}
+		instanceLock = lock
+	}
+	if cfg.Storage.Dir != "" {
+		return storage.OpenShards(cfg.Storage.Dir, cfg.Storage.File, cfg.Storage.ShardCache)
+	}
+	if cfg.Storage.DatabaseURL == "" {
+		return storage.Open("", cfg.Storage.File)
+	}
+	pg, err := storage.OpenPostgres(cfg.Storage.DatabaseURL)
+	if err != nil {
+		return nil, err
+	}
+	if err := pg.Lock(true); err != nil {
+		pg.Close()
+		return nil, err
+	}
+	return pg, nil
+}
+
+func loadChats(ids ...int64) error {
+	lazy, ok := store.(storage.Lazy)
+	if !ok {
+		return nil
+	}
+	seen := map[int64]bool{}
+	var load func(id int64) error
+	load = func(id int64) error {
+		if seen[id] {
+			return nil
+		}
+		seen[id] = true
+		tags, err := lazy.LoadChat(&data, id)
+		if err != nil {
+			return err
+		}
+		for _, tag := range tags {
+			tagService.Add(tag)
+		}
+		for _, peer := range tagService.Linked(id) {
+			if err := load(peer); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, id := range ids {
+		if err := load(id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func chatsOf(userID int64) []int64 {
+	if lazy, ok := store.(storage.Lazy); ok {
+		return lazy.ChatsOf(userID)
+	}
+	return nil
+}
+
+func chatResident(chatID int64) bool {
+	if lazy, ok := store.(storage.Lazy); ok {
+		return lazy.Resident(chatID)
+	}
+	return true
+}
+
+func allData() (*Data, error) {
+	if lazy, ok := store.(storage.Lazy); ok {
+		return lazy.Full(&data)
+	}
+	return &data, nil
+}
+
+func evictChats() bool {
+	lazy, ok := store.(storage.Lazy)
+	if !ok {
+		return false
+	}
+	ids := lazy.Evict(&data)
+	if len(ids) == 0 {
+		return false
+	}
+	tagService.Rebuild()
+	slog.Debug("Чаты выгружены из памяти", "chats", len(ids))
+	return true
+}
+
+func ensureChat(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		var ids []int64
+		if chat := c.Chat(); chat != nil && chat.Type != tele.ChatPrivate {
+			ids = append(ids, chat.ID)
+		} else if c.Sender() != nil {
+			ids = chatsOf(c.Sender().ID)
+		}
+		if err := loadChats(ids...); err != nil {
+			slog.Error("Не удалось загрузить данные чата", "ids", ids, "err", err)
+			return err
+		}
+		return next(c)
+	}
+}
+
+func dataPath() string {
+	if cfg.Storage.Dir != "" {
+		return cfg.Storage.Dir
+	}
+	return cfg.Storage.File
+}
+
+func releaseInstanceLock() {
+	if instanceLock == nil {
+		return
+	}
+	if err := instanceLock.Release(); err != nil {
+		slog.Warn("Не удалось снять блокировку данных", "err", err)
+	}
+}
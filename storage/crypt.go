@@ -0,0 +1,101 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+const encryptedLine = "enc:"
+
+var (
+	encMagic = []byte("CTENC1\n")
+	dataKey  cipher.AEAD
+
+	ErrNoKey = errors.New("data is encrypted but DATA_KEY is not set")
+)
+
+func UseKey(secret string) error {
+	if secret == "" {
+		dataKey = nil
+		return nil
+	}
+	key := sha256.Sum256([]byte(secret))
+	block, err := aes.NewCipher(key[:])
+	if err != nil {
+		return err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return err
+	}
+	dataKey = aead
+	return nil
+}
+
+func Encrypting() bool {
+	return dataKey != nil
+}
+
+func Seal(plain []byte) ([]byte, error) {
+	if dataKey == nil {
+		return plain, nil
+	}
+	nonce := make([]byte, dataKey.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("cannot generate nonce: %w", err)
+	}
+	out := append(append([]byte{}, encMagic...), nonce...)
+	return dataKey.Seal(out, nonce, plain, encMagic), nil
+}
+
+func Sealed(file []byte) bool {
+	return bytes.HasPrefix(file, encMagic)
+}
+
+func Unseal(file []byte) ([]byte, error) {
+	body, ok := bytes.CutPrefix(file, encMagic)
+	if !ok {
+		return file, nil
+	}
+	if dataKey == nil {
+		return nil, ErrNoKey
+	}
+	if len(body) < dataKey.NonceSize() {
+		return nil, fmt.Errorf("encrypted data is truncated")
+	}
+	nonce, sealed := body[:dataKey.NonceSize()], body[dataKey.NonceSize():]
+	plain, err := dataKey.Open(nil, nonce, sealed, encMagic)
+	if err != nil {
+		return nil, fmt.Errorf("cannot decrypt data, wrong DATA_KEY? %w", err)
+	}
+	return plain, nil
+}
+
+func sealLine(line []byte) ([]byte, error) {
+	if dataKey == nil {
+		return line, nil
+	}
+	sealed, err := Seal(line)
+	if err != nil {
+		return nil, err
+	}
+	return []byte(encryptedLine + base64.StdEncoding.EncodeToString(sealed)), nil
+}
+
+func unsealLine(line []byte) ([]byte, error) {
+	encoded, ok := bytes.CutPrefix(line, []byte(encryptedLine))
+	if !ok {
+		return line, nil
+	}
+	sealed, err := base64.StdEncoding.DecodeString(string(encoded))
+	if err != nil {
+		return nil, err
+	}
+	return Unseal(sealed)
+}
@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func useKey(t *testing.T, secret string) {
+	t.Helper()
+	if err := UseKey(secret); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { UseKey("") })
+}
+
+func TestSealRoundTrip(t *testing.T) {
+	useKey(t, "correct horse")
+	plain := []byte(`{"tags":[]}`)
+	sealed, err := Seal(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !Sealed(sealed) || bytes.Contains(sealed, plain) {
+		t.Fatal("data was not encrypted")
+	}
+	again, err := Seal(plain)
+	if err != nil || bytes.Equal(again, sealed) {
+		t.Fatal("nonce reused between seals")
+	}
+	opened, err := Unseal(sealed)
+	if err != nil || !bytes.Equal(opened, plain) {
+		t.Fatalf("round trip returned %q, %v", opened, err)
+	}
+
+	line, err := sealLine(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if opened, err := unsealLine(line); err != nil || !bytes.Equal(opened, plain) {
+		t.Fatalf("journal line round trip returned %q, %v", opened, err)
+	}
+}
+
+func TestUnsealWithWrongKey(t *testing.T) {
+	useKey(t, "correct horse")
+	sealed, err := Seal([]byte("secret"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	useKey(t, "battery staple")
+	if _, err := Unseal(sealed); err == nil {
+		t.Fatal("data opened with the wrong key")
+	}
+	useKey(t, "")
+	if _, err := Unseal(sealed); !errors.Is(err, ErrNoKey) {
+		t.Fatalf("missing key reported as %v", err)
+	}
+	useKey(t, "correct horse")
+	if _, err := Unseal(sealed[:len(encMagic)+2]); err == nil {
+		t.Fatal("truncated data accepted")
+	}
+}
+
+func TestSealWithoutKeyIsPlain(t *testing.T) {
+	useKey(t, "")
+	plain := []byte("plain")
+	sealed, err := Seal(plain)
+	if err != nil || !bytes.Equal(sealed, plain) {
+		t.Fatalf("Seal without a key returned %q, %v", sealed, err)
+	}
+	if opened, err := Unseal(plain); err != nil || !bytes.Equal(opened, plain) {
+		t.Fatalf("Unseal of plain data returned %q, %v", opened, err)
+	}
+}
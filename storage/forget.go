@@ -0,0 +1,161 @@
+package storage
+
+type ForgetResult struct {
+	Tags          int
+	Subscriptions int
+	Transferred   int
+	Anonymized    int
+}
+
+func withoutUser(subs []Subscriber, userID int64) ([]Subscriber, bool) {
+	kept := subs[:0:0]
+	for _, sub := range subs {
+		if sub.ID != userID {
+			kept = append(kept, sub)
+		}
+	}
+	if len(kept) == len(subs) {
+		return subs, false
+	}
+	if len(kept) == 0 && subs != nil {
+		return nil, true
+	}
+	return kept, true
+}
+
+func usernameOf(d *Data, userID int64) string {
+	for _, tag := range d.Tags {
+		for _, list := range [][]Subscriber{tag.Subscribers, tag.Moderators, tag.Requests} {
+			for _, sub := range list {
+				if sub.ID == userID && sub.Username != "" {
+					return sub.Username
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func forgetInTag(tag *Tag, userID int64, res *ForgetResult) bool {
+	changed := false
+	var removed bool
+	if tag.Subscribers, removed = withoutUser(tag.Subscribers, userID); removed {
+		if tag.Subscribers == nil {
+			tag.Subscribers = []Subscriber{}
+		}
+		res.Subscriptions++
+		changed = true
+	}
+	if tag.Moderators, removed = withoutUser(tag.Moderators, userID); removed {
+		changed = true
+	}
+	if tag.Banned, removed = withoutUser(tag.Banned, userID); removed {
+		changed = true
+	}
+	if tag.Requests, removed = withoutUser(tag.Requests, userID); removed {
+		changed = true
+	}
+	if tag.CreatorID == userID {
+		if len(tag.Moderators) > 0 {
+			tag.CreatorID = tag.Moderators[0].ID
+			tag.CreatorName = tag.Moderators[0].Username
+			tag.Moderators = tag.Moderators[1:]
+			res.Transferred++
+		} else {
+			tag.CreatorID = 0
+			tag.CreatorName = ""
+			res.Anonymized++
+		}
+		changed = true
+	}
+	if tag.DeletedBy == userID {
+		tag.DeletedBy = 0
+		changed = true
+	}
+	if _, ok := tag.Stats.ByUser[userID]; ok {
+		delete(tag.Stats.ByUser, userID)
+		tag.Stats.LastBy = ""
+		changed = true
+	}
+	if p := tag.LastPing; p != nil {
+		if p.PingerID == userID {
+			p.PingerID = 0
+			p.PingerName = ""
+			changed = true
+		}
+		acks := p.Acks[:0:0]
+		for _, id := range p.Acks {
+			if id != userID {
+				acks = append(acks, id)
+			}
+		}
+		if len(acks) != len(p.Acks) {
+			p.Acks = acks
+			changed = true
+		}
+	}
+	return changed
+}
+
+func ForgetUser(d *Data, userID int64, username string, changed func(before, after *Tag)) ForgetResult {
+	var res ForgetResult
+	if username == "" {
+		username = usernameOf(d, userID)
+	}
+	for _, tag := range d.Tags {
+		before := *tag
+		if !forgetInTag(tag, userID, &res) {
+			continue
+		}
+		res.Tags++
+		if changed != nil {
+			changed(&before, tag)
+		}
+	}
+	mentions := d.Mentions[:0]
+	for _, ev := range d.Mentions {
+		if ev.UserID != userID {
+			mentions = append(mentions, ev)
+		}
+	}
+	d.Mentions = mentions
+	delete(d.Digests, userID)
+	delete(d.Users, userID)
+	for _, items := range d.Digests {
+		for i := range items {
+			if username != "" && items[i].From == username {
+				items[i].From = ""
+			}
+		}
+	}
+	for _, s := range d.Chats {
+		if s.APITokenBy == userID {
+			s.APITokenBy = 0
+		}
+		if s.Webhook != nil && s.Webhook.By == userID {
+			s.Webhook.By = 0
+		}
+		for _, ev := range s.Events {
+			responses := ev.Responses[:0:0]
+			for _, r := range ev.Responses {
+				if r.UserID != userID {
+					responses = append(responses, r)
+				}
+			}
+			ev.Responses = responses
+			if ev.CreatorID == userID {
+				ev.CreatorID = 0
+			}
+		}
+		for _, poll := range s.Polls {
+			votes := poll.Votes[:0:0]
+			for _, v := range poll.Votes {
+				if v.UserID != userID {
+					votes = append(votes, v)
+				}
+			}
+			poll.Votes = votes
+		}
+	}
+	return res
+}
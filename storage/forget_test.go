@@ -0,0 +1,58 @@
+package storage
+
+import "testing"
+
+func TestForgetUserClearsEveryTagField(t *testing.T) {
+	tag := &Tag{
+		Name:        "raid",
+		ChatID:      -1,
+		CreatorID:   7,
+		Subscribers: []Subscriber{{ID: 7, Username: "alice"}, {ID: 8}},
+		Moderators:  []Subscriber{{ID: 8, Username: "bob"}},
+		Banned:      []Subscriber{{ID: 7}},
+		Requests:    []Subscriber{{ID: 7}},
+		DeletedBy:   7,
+		Stats:       TagStats{ByUser: map[int64]int{7: 3}},
+		LastPing:    &Ping{PingerID: 7, PingerName: "alice", Acks: []int64{7, 8}},
+	}
+	d := &Data{
+		Tags:     []*Tag{tag},
+		Mentions: []MentionEvent{{UserID: 7}, {UserID: 8}},
+		Digests:  map[int64][]DigestItem{7: {{}}, 8: {{From: "alice"}}},
+		Users:    map[int64]*UserPrefs{7: {}},
+		Chats: map[int64]*ChatSettings{-1: {
+			APITokenBy: 7,
+			Webhook:    &Webhook{By: 7},
+			Events:     []*Event{{CreatorID: 7, Responses: []EventResponse{{UserID: 7, Username: "alice"}, {UserID: 8}}}},
+			Polls:      []*TrackedPoll{{Votes: []PollVote{{UserID: 7, Options: []int{0}}, {UserID: 8, Options: []int{1}}}}},
+		}},
+	}
+	var seen []*Tag
+	res := ForgetUser(d, 7, "", func(before, after *Tag) {
+		if before.CreatorID != 7 || after != tag {
+			t.Fatal("callback got the wrong tag state")
+		}
+		seen = append(seen, after)
+	})
+	if res.Tags != 1 || res.Subscriptions != 1 || res.Transferred != 1 || len(seen) != 1 {
+		t.Fatalf("unexpected result %+v", res)
+	}
+	if len(tag.Subscribers) != 1 || tag.Banned != nil || tag.Requests != nil || tag.CreatorID != 8 || tag.DeletedBy != 0 {
+		t.Fatalf("tag still references the user: %+v", tag)
+	}
+	if _, ok := tag.Stats.ByUser[7]; ok || tag.LastPing.PingerID != 0 || len(tag.LastPing.Acks) != 1 {
+		t.Fatal("counters still reference the user")
+	}
+	if len(d.Mentions) != 1 || d.Digests[7] != nil || d.Digests[8][0].From != "" || d.Users[7] != nil {
+		t.Fatal("user data left behind")
+	}
+	if d.Chats[-1].APITokenBy != 0 || d.Chats[-1].Webhook.By != 0 {
+		t.Fatal("chat settings still reference the user")
+	}
+	if ev := d.Chats[-1].Events[0]; ev.CreatorID != 0 || len(ev.Responses) != 1 || ev.Responses[0].UserID != 8 {
+		t.Fatalf("event still references the user: %+v", ev)
+	}
+	if votes := d.Chats[-1].Polls[0].Votes; len(votes) != 1 || votes[0].UserID != 8 {
+		t.Fatalf("poll still holds the user's vote: %+v", votes)
+	}
+}
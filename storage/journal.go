@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+)
+
+func SameTag(a, b *Tag) bool {
+	return a.CreatorID == b.CreatorID && a.CreatedAt.Equal(b.CreatedAt) && strings.EqualFold(a.Name, b.Name)
+}
+
+func ReadJournal(path string) ([]JournalEntry, error) {
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var entries []JournalEntry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line, err := unsealLine(scanner.Bytes())
+		if errors.Is(err, ErrNoKey) {
+			return nil, err
+		}
+		var entry JournalEntry
+		if err == nil {
+			err = json.Unmarshal(line, &entry)
+		}
+		if err != nil || entry.Tag == nil {
+			slog.Warn("Пропускаю повреждённую запись журнала", "err", err)
+			continue
+		}
+		entries = append(entries, entry)
+	}
+	return entries, scanner.Err()
+}
+
+func EncodeJournal(entry JournalEntry) ([]byte, error) {
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return nil, err
+	}
+	line, err = sealLine(line)
+	if err != nil {
+		return nil, err
+	}
+	return append(line, '\n'), nil
+}
+
+func ApplyJournal(d *Data, entry JournalEntry) {
+	for i, tag := range d.Tags {
+		if !SameTag(tag, entry.Tag) {
+			continue
+		}
+		if entry.Op == "purge" {
+			d.Tags = append(d.Tags[:i], d.Tags[i+1:]...)
+		} else {
+			*tag = *entry.Tag
+		}
+		return
+	}
+	if entry.Op != "purge" {
+		d.Tags = append(d.Tags, entry.Tag)
+	}
+}
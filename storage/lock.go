@@ -0,0 +1,38 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var ErrLocked = errors.New("data store is locked by another instance")
+
+type Lock struct {
+	file *os.File
+}
+
+func AcquireLock(path string) (*Lock, error) {
+	name := filepath.Clean(path) + ".lock"
+	file, err := os.OpenFile(name, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := flock(file); err != nil {
+		holder, _ := os.ReadFile(name)
+		file.Close()
+		if len(holder) > 0 {
+			return nil, fmt.Errorf("%w (%s, pid %s)", ErrLocked, name, holder)
+		}
+		return nil, fmt.Errorf("%w (%s)", ErrLocked, name)
+	}
+	file.Truncate(0)
+	fmt.Fprintf(file, "%d", os.Getpid())
+	return &Lock{file: file}, nil
+}
+
+func (l *Lock) Release() error {
+	l.file.Truncate(0)
+	return l.file.Close()
+}
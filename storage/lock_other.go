@@ -0,0 +1,13 @@
+//go:build !unix && !windows
+
+package storage
+
+import (
+	"log/slog"
+	"os"
+)
+
+func flock(file *os.File) error {
+	slog.Warn("⚠️ Блокировка файлов не поддерживается на этой платформе, второй экземпляр бота не будет остановлен", "file", file.Name())
+	return nil
+}
@@ -0,0 +1,13 @@
+//go:build windows
+
+package storage
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+func flock(file *os.File) error {
+	return windows.LockFileEx(windows.Handle(file.Fd()), windows.LOCKFILE_EXCLUSIVE_LOCK|windows.LOCKFILE_FAIL_IMMEDIATELY, 0, 1, 0, &windows.Overlapped{})
+}
@@ -0,0 +1,261 @@
+package storage
+
+import "time"
+
+type Subscriber struct {
+	ID       int64  `json:"id"`
+	Username string `json:"username"`
+	Delivery string `json:"delivery,omitempty"`
+}
+
+type Tag struct {
+	ID            int64         `json:"id,omitempty"`
+	Name          string        `json:"name"`
+	ChatID        int64         `json:"chat_id,omitempty"`
+	CreatorID     int64         `json:"creator_id"`
+	CreatorName   string        `json:"creator_name"`
+	Description   string        `json:"description"`
+	Category      string        `json:"category,omitempty"`
+	Subscribers   []Subscriber  `json:"subscribers"`
+	Moderators    []Subscriber  `json:"moderators,omitempty"`
+	Banned        []Subscriber  `json:"banned,omitempty"`
+	Access        string        `json:"access,omitempty"`
+	Requests      []Subscriber  `json:"requests,omitempty"`
+	Silent        bool          `json:"silent,omitempty"`
+	PingPolicy    string        `json:"ping_policy,omitempty"`
+	MemberNotify  string        `json:"member_notify,omitempty"`
+	Topics        []int         `json:"topics,omitempty"`
+	Global        bool          `json:"global,omitempty"`
+	Template      string        `json:"template,omitempty"`
+	Emoji         string        `json:"emoji,omitempty"`
+	Announcement  *Announcement `json:"announcement,omitempty"`
+	Welcome       string        `json:"welcome,omitempty"`
+	KeepEmpty     bool          `json:"keep_empty,omitempty"`
+	CreatedAt     time.Time     `json:"created_at"`
+	LastPing      *Ping         `json:"last_ping,omitempty"`
+	Stats         TagStats      `json:"stats"`
+	StaleWarnedAt *time.Time    `json:"stale_warned_at,omitempty"`
+	ArchivedAt    *time.Time    `json:"archived_at,omitempty"`
+	DeletedAt     *time.Time    `json:"deleted_at,omitempty"`
+	DeletedBy     int64         `json:"deleted_by,omitempty"`
+}
+
+type Data struct {
+	SchemaVersion int                     `json:"schema_version"`
+	Tags          []*Tag                  `json:"tags"`
+	Mentions      []MentionEvent          `json:"mentions,omitempty"`
+	Chats         map[int64]*ChatSettings `json:"chats,omitempty"`
+	Digests       map[int64][]DigestItem  `json:"digests,omitempty"`
+	DeniedChats   []int64                 `json:"denied_chats,omitempty"`
+	JournalSeq    int64                   `json:"journal_seq,omitempty"`
+	Users         map[int64]*UserPrefs    `json:"users,omitempty"`
+	TagSeq        int64                   `json:"tag_seq,omitempty"`
+}
+
+func AssignTagIDs(d *Data) {
+	for _, tag := range d.Tags {
+		if tag.ID > d.TagSeq {
+			d.TagSeq = tag.ID
+		}
+	}
+	for _, tag := range d.Tags {
+		if tag.ID == 0 {
+			d.TagSeq++
+			tag.ID = d.TagSeq
+		}
+	}
+}
+
+type Announcement struct {
+	MessageID int       `json:"message_id"`
+	At        time.Time `json:"at"`
+}
+
+type Ping struct {
+	ChatID     int64     `json:"chat_id"`
+	MessageID  int       `json:"message_id"`
+	PingerID   int64     `json:"pinger_id"`
+	PingerName string    `json:"pinger_name"`
+	At         time.Time `json:"at"`
+	Acks       []int64   `json:"acks"`
+	NudgeIDs   []int     `json:"nudge_ids,omitempty"`
+	NudgedAt   time.Time `json:"nudged_at"`
+}
+
+type TagStats struct {
+	Mentions int           `json:"mentions"`
+	LastUsed time.Time     `json:"last_used"`
+	LastBy   string        `json:"last_by"`
+	ByUser   map[int64]int `json:"by_user,omitempty"`
+}
+
+type MentionEvent struct {
+	Tag       string    `json:"tag"`
+	ChatID    int64     `json:"chat_id"`
+	MessageID int       `json:"message_id,omitempty"`
+	UserID    int64     `json:"user_id"`
+	Username  string    `json:"username"`
+	At        time.Time `json:"at"`
+}
+
+type ChatSettings struct {
+	Lang           string         `json:"lang,omitempty"`
+	Title          string         `json:"title,omitempty"`
+	LastSeen       time.Time      `json:"last_seen"`
+	APITokenHash   string         `json:"api_token_hash,omitempty"`
+	APITokenBy     int64          `json:"api_token_by,omitempty"`
+	Webhook        *Webhook       `json:"webhook,omitempty"`
+	Timezone       string         `json:"timezone,omitempty"`
+	Spam           *SpamLimits    `json:"spam,omitempty"`
+	BlockedWords   []string       `json:"blocked_words,omitempty"`
+	DailyTagCap    int            `json:"daily_tag_cap,omitempty"`
+	RemovedAt      *time.Time     `json:"removed_at,omitempty"`
+	Weekly         *Weekly        `json:"weekly,omitempty"`
+	SubsHistory    []SubsPoint    `json:"subs_history,omitempty"`
+	Community      string         `json:"community,omitempty"`
+	Hashtags       *HashtagFilter `json:"hashtags,omitempty"`
+	DeleteTriggers bool           `json:"delete_triggers,omitempty"`
+	Directory      int            `json:"directory,omitempty"`
+	Events         []*Event       `json:"events,omitempty"`
+	Polls          []*TrackedPoll `json:"polls,omitempty"`
+	Rules          []*TagRule     `json:"rules,omitempty"`
+}
+
+type TagRule struct {
+	ID        int       `json:"id"`
+	Keyword   string    `json:"keyword"`
+	Regex     bool      `json:"regex,omitempty"`
+	Tag       string    `json:"tag"`
+	Cooldown  int       `json:"cooldown"`
+	CreatorID int64     `json:"creator_id"`
+	LastFired time.Time `json:"last_fired"`
+}
+
+type TrackedPoll struct {
+	ID        string     `json:"id"`
+	MessageID int        `json:"message_id"`
+	Question  string     `json:"question"`
+	Options   []string   `json:"options"`
+	Votes     []PollVote `json:"votes,omitempty"`
+	CreatedAt time.Time  `json:"created_at"`
+}
+
+type PollVote struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Options  []int  `json:"options"`
+}
+
+type Event struct {
+	ID        int             `json:"id"`
+	Tag       string          `json:"tag"`
+	Title     string          `json:"title"`
+	Start     time.Time       `json:"start"`
+	CreatorID int64           `json:"creator_id"`
+	MessageID int             `json:"message_id"`
+	Responses []EventResponse `json:"responses,omitempty"`
+	Reminded  bool            `json:"reminded,omitempty"`
+	Closed    bool            `json:"closed,omitempty"`
+}
+
+type EventResponse struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username,omitempty"`
+	Answer   string `json:"answer"`
+}
+
+type HashtagFilter struct {
+	Forwarded bool `json:"forwarded,omitempty"`
+	Bots      bool `json:"bots,omitempty"`
+	Commands  bool `json:"commands,omitempty"`
+}
+
+type SubsPoint struct {
+	Day   string `json:"day"`
+	Count int    `json:"count"`
+}
+
+type Weekly struct {
+	Weekday  time.Weekday `json:"weekday"`
+	Hour     int          `json:"hour"`
+	LastSent time.Time    `json:"last_sent"`
+	LastSubs int          `json:"last_subs"`
+}
+
+type SpamLimits struct {
+	Off    bool `json:"off,omitempty"`
+	Pings  int  `json:"pings"`
+	Window int  `json:"window_minutes"`
+	Mute   int  `json:"mute_minutes"`
+	Report bool `json:"report,omitempty"`
+}
+
+type Webhook struct {
+	URL    string `json:"url"`
+	Secret string `json:"secret"`
+	By     int64  `json:"by"`
+}
+
+type UserPrefs struct {
+	Delivery  string               `json:"delivery,omitempty"`
+	Quiet     bool                 `json:"quiet,omitempty"`
+	QuietFrom int                  `json:"quiet_from,omitempty"`
+	QuietTo   int                  `json:"quiet_to,omitempty"`
+	Timezone  string               `json:"timezone,omitempty"`
+	Muted     []string             `json:"muted,omitempty"`
+	Snoozed   map[string]time.Time `json:"snoozed,omitempty"`
+	Summary   *DailySummary        `json:"summary,omitempty"`
+}
+
+type DailySummary struct {
+	Hour     int          `json:"hour"`
+	LastSent time.Time    `json:"last_sent"`
+	Items    []DigestItem `json:"items,omitempty"`
+}
+
+type DigestItem struct {
+	Tag       string    `json:"tag"`
+	ChatID    int64     `json:"chat_id"`
+	ChatTitle string    `json:"chat_title"`
+	Link      string    `json:"link,omitempty"`
+	From      string    `json:"from"`
+	At        time.Time `json:"at"`
+}
+
+func (t *Tag) Active() bool {
+	return t.ArchivedAt == nil && t.DeletedAt == nil
+}
+
+func (p *Ping) Acked(userID int64) bool {
+	for _, id := range p.Acks {
+		if id == userID {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Ping) Matches(chatID int64, messageID int) bool {
+	if p.ChatID != chatID {
+		return false
+	}
+	if p.MessageID == messageID {
+		return true
+	}
+	for _, id := range p.NudgeIDs {
+		if id == messageID {
+			return true
+		}
+	}
+	return false
+}
+
+type JournalEntry struct {
+	Seq  int64       `json:"seq"`
+	At   time.Time   `json:"at"`
+	Op   string      `json:"op"`
+	Tag  *Tag        `json:"tag"`
+	By   int64       `json:"by,omitempty"`
+	Sub  *Subscriber `json:"sub,omitempty"`
+	Undo int64       `json:"undo,omitempty"`
+}
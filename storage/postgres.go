@@ -0,0 +1,772 @@
+package storage
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	postgresTimeout   = 30 * time.Second
+	postgresLockKey   = 0x43685461
+	postgresLockRetry = 5 * time.Second
+)
+
+const postgresSchema = `
+CREATE TABLE IF NOT EXISTS tags (
+	id              BIGINT PRIMARY KEY,
+	chat_id         BIGINT NOT NULL DEFAULT 0,
+	name            TEXT NOT NULL,
+	creator_id      BIGINT NOT NULL,
+	creator_name    TEXT NOT NULL DEFAULT '',
+	description     TEXT NOT NULL DEFAULT '',
+	category        TEXT NOT NULL DEFAULT '',
+	access          TEXT NOT NULL DEFAULT '',
+	silent          BOOLEAN NOT NULL DEFAULT FALSE,
+	topics          JSONB,
+	moderators      JSONB,
+	banned          JSONB,
+	requests        JSONB,
+	last_ping       JSONB,
+	created_at      TIMESTAMPTZ NOT NULL,
+	stale_warned_at TIMESTAMPTZ,
+	archived_at     TIMESTAMPTZ,
+	deleted_at      TIMESTAMPTZ,
+	deleted_by      BIGINT NOT NULL DEFAULT 0
+);
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS ping_policy TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS member_notify TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS global BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS template TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS emoji TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS announcement JSONB;
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS welcome TEXT NOT NULL DEFAULT '';
+ALTER TABLE tags ADD COLUMN IF NOT EXISTS keep_empty BOOLEAN NOT NULL DEFAULT FALSE;
+CREATE INDEX IF NOT EXISTS tags_chat_name ON tags (chat_id, lower(name));
+CREATE TABLE IF NOT EXISTS subscribers (
+	tag_id   BIGINT NOT NULL REFERENCES tags (id) ON DELETE CASCADE,
+	pos      INT NOT NULL,
+	user_id  BIGINT NOT NULL,
+	username TEXT NOT NULL DEFAULT '',
+	delivery TEXT NOT NULL DEFAULT '',
+	PRIMARY KEY (tag_id, user_id)
+);
+CREATE INDEX IF NOT EXISTS subscribers_user ON subscribers (user_id);
+CREATE TABLE IF NOT EXISTS tag_stats (
+	tag_id    BIGINT PRIMARY KEY REFERENCES tags (id) ON DELETE CASCADE,
+	mentions  INT NOT NULL DEFAULT 0,
+	last_used TIMESTAMPTZ,
+	last_by   TEXT NOT NULL DEFAULT '',
+	by_user   JSONB
+);
+ALTER TABLE tag_stats ADD COLUMN IF NOT EXISTS last_ping JSONB;
+INSERT INTO tag_stats (tag_id, last_ping) SELECT id, last_ping FROM tags WHERE last_ping IS NOT NULL
+	ON CONFLICT (tag_id) DO UPDATE SET last_ping = EXCLUDED.last_ping WHERE tag_stats.last_ping IS NULL;
+UPDATE tags SET last_ping = NULL WHERE last_ping IS NOT NULL;
+CREATE TABLE IF NOT EXISTS chat_settings (
+	chat_id  BIGINT PRIMARY KEY,
+	settings JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS bot_state (
+	key   TEXT PRIMARY KEY,
+	value JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS user_prefs (
+	user_id BIGINT PRIMARY KEY,
+	prefs   JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS digests (
+	user_id BIGINT PRIMARY KEY,
+	items   JSONB NOT NULL
+);
+CREATE TABLE IF NOT EXISTS summaries (
+	user_id BIGINT PRIMARY KEY,
+	items   JSONB NOT NULL
+);
+INSERT INTO summaries (user_id, items) SELECT user_id, prefs->'summary'->'items' FROM user_prefs
+	WHERE jsonb_typeof(prefs->'summary'->'items') = 'array' ON CONFLICT (user_id) DO NOTHING;
+UPDATE user_prefs SET prefs = prefs #- '{summary,items}' WHERE prefs->'summary' ? 'items';
+CREATE TABLE IF NOT EXISTS denied_chats (
+	chat_id BIGINT PRIMARY KEY
+);
+CREATE TABLE IF NOT EXISTS mentions (
+	key   BYTEA PRIMARY KEY,
+	at    TIMESTAMPTZ NOT NULL,
+	event JSONB NOT NULL
+);
+CREATE INDEX IF NOT EXISTS mentions_at ON mentions (at);
+`
+
+type Postgres struct {
+	pool   *pgxpool.Pool
+	lock   *pgxpool.Conn
+	mu     sync.Mutex
+	saved  savedRows
+	legacy map[string]bool
+}
+
+func OpenPostgres(url string) (*Postgres, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+	pool, err := pgxpool.New(ctx, url)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := pool.Exec(ctx, postgresSchema); err != nil {
+		pool.Close()
+		return nil, err
+	}
+	return &Postgres{pool: pool}, nil
+}
+
+func jsonValue(v interface{}) []byte {
+	b, _ := json.Marshal(v)
+	return b
+}
+
+func unmarshalColumns(pairs ...interface{}) error {
+	for i := 0; i+1 < len(pairs); i += 2 {
+		if raw := pairs[i].([]byte); len(raw) > 0 {
+			if err := json.Unmarshal(raw, pairs[i+1]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+var tagColumns = []string{"id", "chat_id", "name", "creator_id", "creator_name", "description", "category",
+	"access", "silent", "topics", "moderators", "banned", "requests", "created_at",
+	"stale_warned_at", "archived_at", "deleted_at", "deleted_by", "ping_policy", "member_notify",
+	"global", "template", "emoji", "announcement", "welcome", "keep_empty"}
+
+var upsertTag = func() string {
+	params := make([]string, len(tagColumns))
+	updates := make([]string, 0, len(tagColumns)-1)
+	for i, col := range tagColumns {
+		params[i] = fmt.Sprintf("$%d", i+1)
+		if col != "id" {
+			updates = append(updates, col+" = EXCLUDED."+col)
+		}
+	}
+	return "INSERT INTO tags (" + strings.Join(tagColumns, ", ") + ") VALUES (" + strings.Join(params, ", ") +
+		") ON CONFLICT (id) DO UPDATE SET " + strings.Join(updates, ", ")
+}()
+
+func tagValues(tag *Tag) []interface{} {
+	return []interface{}{tag.ID, tag.ChatID, tag.Name, tag.CreatorID, tag.CreatorName, tag.Description, tag.Category,
+		tag.Access, tag.Silent, jsonValue(tag.Topics), jsonValue(tag.Moderators), jsonValue(tag.Banned),
+		jsonValue(tag.Requests), tag.CreatedAt,
+		tag.StaleWarnedAt, tag.ArchivedAt, tag.DeletedAt, tag.DeletedBy, tag.PingPolicy, tag.MemberNotify,
+		tag.Global, tag.Template, tag.Emoji, jsonValue(tag.Announcement), tag.Welcome, tag.KeepEmpty}
+}
+
+func rowSum(v interface{}) [sha256.Size]byte {
+	return sha256.Sum256(jsonValue(v))
+}
+
+func (s *Postgres) Load(d *Data) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+	*d = Data{SchemaVersion: SchemaVersion, Tags: []*Tag{}, Chats: map[int64]*ChatSettings{}}
+
+	rows, err := s.pool.Query(ctx, `SELECT `+strings.Join(tagColumns, ", ")+` FROM tags ORDER BY id`)
+	if err != nil {
+		return err
+	}
+	byID := map[int64]*Tag{}
+	for rows.Next() {
+		var (
+			tag                                                Tag
+			topics, moderators, banned, requests, announcement []byte
+		)
+		if err := rows.Scan(&tag.ID, &tag.ChatID, &tag.Name, &tag.CreatorID, &tag.CreatorName, &tag.Description,
+			&tag.Category, &tag.Access, &tag.Silent, &topics, &moderators, &banned, &requests,
+			&tag.CreatedAt, &tag.StaleWarnedAt, &tag.ArchivedAt, &tag.DeletedAt, &tag.DeletedBy, &tag.PingPolicy,
+			&tag.MemberNotify, &tag.Global, &tag.Template, &tag.Emoji, &announcement, &tag.Welcome,
+			&tag.KeepEmpty); err != nil {
+			rows.Close()
+			return err
+		}
+		if err := unmarshalColumns(topics, &tag.Topics, moderators, &tag.Moderators, banned, &tag.Banned,
+			requests, &tag.Requests, announcement, &tag.Announcement); err != nil {
+			rows.Close()
+			return err
+		}
+		tag.Subscribers = []Subscriber{}
+		d.Tags = append(d.Tags, &tag)
+		byID[tag.ID] = &tag
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT tag_id, user_id, username, delivery FROM subscribers ORDER BY tag_id, pos`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var tagID int64
+		var sub Subscriber
+		if err := rows.Scan(&tagID, &sub.ID, &sub.Username, &sub.Delivery); err != nil {
+			rows.Close()
+			return err
+		}
+		if tag := byID[tagID]; tag != nil {
+			tag.Subscribers = append(tag.Subscribers, sub)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT tag_id, mentions, last_used, last_by, by_user, last_ping FROM tag_stats`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var tagID int64
+		var stats TagStats
+		var lastUsed *time.Time
+		var byUser, ping []byte
+		if err := rows.Scan(&tagID, &stats.Mentions, &lastUsed, &stats.LastBy, &byUser, &ping); err != nil {
+			rows.Close()
+			return err
+		}
+		if lastUsed != nil {
+			stats.LastUsed = *lastUsed
+		}
+		tag := byID[tagID]
+		if tag == nil {
+			continue
+		}
+		if err := unmarshalColumns(byUser, &stats.ByUser, ping, &tag.LastPing); err != nil {
+			rows.Close()
+			return err
+		}
+		tag.Stats = stats
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT chat_id, settings FROM chat_settings`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var chatID int64
+		var raw []byte
+		if err := rows.Scan(&chatID, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		settings := &ChatSettings{}
+		if err := json.Unmarshal(raw, settings); err != nil {
+			rows.Close()
+			return err
+		}
+		d.Chats[chatID] = settings
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	if err := s.loadRows(ctx, d); err != nil {
+		return err
+	}
+
+	state := map[string]interface{}{
+		"journal_seq": &d.JournalSeq,
+		"tag_seq":     &d.TagSeq,
+	}
+	legacy := map[string]interface{}{
+		"mentions":     &d.Mentions,
+		"digests":      &d.Digests,
+		"denied_chats": &d.DeniedChats,
+		"users":        &d.Users,
+	}
+	rows, err = s.pool.Query(ctx, `SELECT key, value FROM bot_state`)
+	if err != nil {
+		return err
+	}
+	found := map[string]bool{}
+	for rows.Next() {
+		var key string
+		var raw []byte
+		if err := rows.Scan(&key, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		dst, ok := state[key]
+		if !ok {
+			if dst, ok = legacy[key]; ok {
+				found[key] = true
+			}
+		}
+		if ok {
+			if err := json.Unmarshal(raw, dst); err != nil {
+				rows.Close()
+				return err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	s.remember(d)
+	if len(found) > 0 {
+		s.mu.Lock()
+		s.legacy = found
+		s.saved.users, s.saved.digests, s.saved.summaries, s.saved.denied = nil, nil, nil, nil
+		s.saved.mentions = nil
+		s.mu.Unlock()
+	}
+	return nil
+}
+
+func (s *Postgres) loadRows(ctx context.Context, d *Data) error {
+	rows, err := s.pool.Query(ctx, `SELECT user_id, prefs FROM user_prefs`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var userID int64
+		var raw []byte
+		if err := rows.Scan(&userID, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		var prefs *UserPrefs
+		if err := json.Unmarshal(raw, &prefs); err != nil {
+			rows.Close()
+			return err
+		}
+		if d.Users == nil {
+			d.Users = map[int64]*UserPrefs{}
+		}
+		d.Users[userID] = prefs
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT user_id, items FROM summaries`)
+	if err != nil {
+		return err
+	}
+	summaries := map[int64][]DigestItem{}
+	for rows.Next() {
+		var userID int64
+		var raw []byte
+		if err := rows.Scan(&userID, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		var items []DigestItem
+		if err := json.Unmarshal(raw, &items); err != nil {
+			rows.Close()
+			return err
+		}
+		summaries[userID] = items
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	applySummaries(d, summaries)
+
+	rows, err = s.pool.Query(ctx, `SELECT user_id, items FROM digests`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var userID int64
+		var raw []byte
+		if err := rows.Scan(&userID, &raw); err != nil {
+			rows.Close()
+			return err
+		}
+		var items []DigestItem
+		if err := json.Unmarshal(raw, &items); err != nil {
+			rows.Close()
+			return err
+		}
+		if d.Digests == nil {
+			d.Digests = map[int64][]DigestItem{}
+		}
+		d.Digests[userID] = items
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT chat_id FROM denied_chats ORDER BY chat_id`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var chatID int64
+		if err := rows.Scan(&chatID); err != nil {
+			rows.Close()
+			return err
+		}
+		d.DeniedChats = append(d.DeniedChats, chatID)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	rows, err = s.pool.Query(ctx, `SELECT event FROM mentions ORDER BY at, key`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var raw []byte
+		if err := rows.Scan(&raw); err != nil {
+			rows.Close()
+			return err
+		}
+		var ev MentionEvent
+		if err := json.Unmarshal(raw, &ev); err != nil {
+			rows.Close()
+			return err
+		}
+		d.Mentions = append(d.Mentions, ev)
+	}
+	return rows.Err()
+}
+
+func stateValues(d *Data) map[string]interface{} {
+	return map[string]interface{}{
+		"journal_seq": d.JournalSeq,
+		"tag_seq":     d.TagSeq,
+	}
+}
+
+type savedRows struct {
+	tags      map[int64][sha256.Size]byte
+	chats     map[int64][sha256.Size]byte
+	users     map[int64][sha256.Size]byte
+	digests   map[int64][sha256.Size]byte
+	summaries map[int64][sha256.Size]byte
+	denied    map[int64][sha256.Size]byte
+	state     map[string][sha256.Size]byte
+	counters  map[int64][sha256.Size]byte
+	mentions  map[[sha256.Size]byte]MentionEvent
+}
+
+func currentRows(d *Data) savedRows {
+	rows := savedRows{
+		tags:   map[int64][sha256.Size]byte{},
+		chats:  map[int64][sha256.Size]byte{},
+		users:  map[int64][sha256.Size]byte{},
+		denied: map[int64][sha256.Size]byte{},
+		state:  map[string][sha256.Size]byte{},
+	}
+	d = withoutStats(d)
+	for _, tag := range d.Tags {
+		rows.tags[tag.ID] = rowSum(tag)
+	}
+	for chatID, settings := range d.Chats {
+		rows.chats[chatID] = rowSum(settings)
+	}
+	for userID, prefs := range d.Users {
+		rows.users[userID] = rowSum(prefs)
+	}
+	for _, chatID := range d.DeniedChats {
+		rows.denied[chatID] = rowSum(chatID)
+	}
+	for key, value := range stateValues(d) {
+		rows.state[key] = rowSum(value)
+	}
+	return rows
+}
+
+func currentCounters(st *Stats) (map[int64][sha256.Size]byte, map[[sha256.Size]byte]MentionEvent) {
+	counters := map[int64][sha256.Size]byte{}
+	for id, c := range st.Tags {
+		counters[id] = rowSum(c)
+	}
+	mentions := map[[sha256.Size]byte]MentionEvent{}
+	for _, ev := range st.Mentions {
+		mentions[rowSum(ev)] = ev
+	}
+	return counters, mentions
+}
+
+func itemSums(lists map[int64][]DigestItem) map[int64][sha256.Size]byte {
+	sums := map[int64][sha256.Size]byte{}
+	for userID, items := range lists {
+		sums[userID] = rowSum(items)
+	}
+	return sums
+}
+
+func changedRows(saved, next map[int64][sha256.Size]byte) (changed, gone []int64) {
+	for id, sum := range next {
+		if old, ok := saved[id]; !ok || old != sum {
+			changed = append(changed, id)
+		}
+	}
+	for id := range saved {
+		if _, ok := next[id]; !ok {
+			gone = append(gone, id)
+		}
+	}
+	return changed, gone
+}
+
+func (s *Postgres) remember(d *Data) {
+	s.mu.Lock()
+	s.saved = currentRows(d)
+	st := StatsOf(d)
+	s.saved.counters, s.saved.mentions = currentCounters(st)
+	s.saved.digests, s.saved.summaries = itemSums(st.Digests), itemSums(st.Summaries)
+	s.mu.Unlock()
+}
+
+func (s *Postgres) Save(d *Data) error {
+	AssignTagIDs(d)
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	next := currentRows(d)
+	next.counters, next.mentions = s.saved.counters, s.saved.mentions
+	next.digests, next.summaries = s.saved.digests, s.saved.summaries
+	d = withoutStats(d)
+	batch := &pgx.Batch{}
+	var gone []int64
+	for id := range s.saved.tags {
+		if _, ok := next.tags[id]; !ok {
+			gone = append(gone, id)
+		}
+	}
+	if len(gone) > 0 {
+		batch.Queue(`DELETE FROM tags WHERE id = ANY($1)`, gone)
+	}
+	for _, tag := range d.Tags {
+		if sum, ok := s.saved.tags[tag.ID]; ok && sum == next.tags[tag.ID] {
+			continue
+		}
+		batch.Queue(upsertTag, tagValues(tag)...)
+		batch.Queue(`DELETE FROM subscribers WHERE tag_id = $1`, tag.ID)
+		for pos, sub := range tag.Subscribers {
+			batch.Queue(`INSERT INTO subscribers (tag_id, pos, user_id, username, delivery)
+				VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING`,
+				tag.ID, pos, sub.ID, sub.Username, sub.Delivery)
+		}
+	}
+	changed, gone := changedRows(s.saved.chats, next.chats)
+	if len(gone) > 0 {
+		batch.Queue(`DELETE FROM chat_settings WHERE chat_id = ANY($1)`, gone)
+	}
+	for _, chatID := range changed {
+		batch.Queue(`INSERT INTO chat_settings (chat_id, settings) VALUES ($1, $2)
+			ON CONFLICT (chat_id) DO UPDATE SET settings = EXCLUDED.settings`, chatID, jsonValue(d.Chats[chatID]))
+	}
+	changed, gone = changedRows(s.saved.users, next.users)
+	if len(gone) > 0 {
+		batch.Queue(`DELETE FROM user_prefs WHERE user_id = ANY($1)`, gone)
+	}
+	for _, userID := range changed {
+		batch.Queue(`INSERT INTO user_prefs (user_id, prefs) VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE SET prefs = EXCLUDED.prefs`, userID, jsonValue(d.Users[userID]))
+	}
+	changed, gone = changedRows(s.saved.denied, next.denied)
+	if len(gone) > 0 {
+		batch.Queue(`DELETE FROM denied_chats WHERE chat_id = ANY($1)`, gone)
+	}
+	for _, chatID := range changed {
+		batch.Queue(`INSERT INTO denied_chats (chat_id) VALUES ($1) ON CONFLICT DO NOTHING`, chatID)
+	}
+	var legacy []string
+	for key := range s.legacy {
+		if key != "mentions" && key != "digests" {
+			legacy = append(legacy, key)
+		}
+	}
+	if len(legacy) > 0 {
+		batch.Queue(`DELETE FROM bot_state WHERE key = ANY($1)`, legacy)
+	}
+	for key, value := range stateValues(d) {
+		if sum, ok := s.saved.state[key]; ok && sum == next.state[key] {
+			continue
+		}
+		batch.Queue(`INSERT INTO bot_state (key, value) VALUES ($1, $2)
+			ON CONFLICT (key) DO UPDATE SET value = EXCLUDED.value`, key, jsonValue(value))
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	err := s.exec(ctx, batch)
+	if err == nil {
+		s.saved = next
+		for _, key := range legacy {
+			delete(s.legacy, key)
+		}
+	}
+	return err
+}
+
+// exec runs the batch on the connection that holds the advisory lock, so an
+// instance that lost the lock to a standby can no longer write.
+func (s *Postgres) exec(ctx context.Context, batch *pgx.Batch) error {
+	var db interface {
+		Begin(context.Context) (pgx.Tx, error)
+	} = s.pool
+	if s.lock != nil {
+		db = s.lock
+	}
+	err := pgx.BeginFunc(ctx, db, func(tx pgx.Tx) error {
+		return tx.SendBatch(ctx, batch).Close()
+	})
+	if err != nil && s.lock != nil && s.lock.Conn().IsClosed() {
+		return fmt.Errorf("%w: PostgreSQL advisory lock %d lost: %v", ErrLocked, postgresLockKey, err)
+	}
+	return err
+}
+
+func (s *Postgres) SaveStats(st *Stats) error {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counters, mentions := currentCounters(st)
+	batch := &pgx.Batch{}
+	for id, c := range st.Tags {
+		if _, ok := s.saved.tags[id]; !ok {
+			delete(counters, id)
+			continue
+		}
+		if sum, ok := s.saved.counters[id]; ok && sum == counters[id] {
+			continue
+		}
+		var lastUsed *time.Time
+		if !c.Stats.LastUsed.IsZero() {
+			lastUsed = &c.Stats.LastUsed
+		}
+		batch.Queue(`INSERT INTO tag_stats (tag_id, mentions, last_used, last_by, by_user, last_ping)
+			VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (tag_id) DO UPDATE SET mentions = EXCLUDED.mentions,
+			last_used = EXCLUDED.last_used, last_by = EXCLUDED.last_by, by_user = EXCLUDED.by_user,
+			last_ping = EXCLUDED.last_ping`,
+			id, c.Stats.Mentions, lastUsed, c.Stats.LastBy, jsonValue(c.Stats.ByUser), jsonValue(c.LastPing))
+	}
+	var gone [][]byte
+	for key := range s.saved.mentions {
+		if _, ok := mentions[key]; !ok {
+			gone = append(gone, key[:])
+		}
+	}
+	if len(gone) > 0 {
+		batch.Queue(`DELETE FROM mentions WHERE key = ANY($1)`, gone)
+	}
+	for key, ev := range mentions {
+		if _, ok := s.saved.mentions[key]; !ok {
+			batch.Queue(`INSERT INTO mentions (key, at, event) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING`,
+				key[:], ev.At, jsonValue(ev))
+		}
+	}
+	digests, summaries := itemSums(st.Digests), itemSums(st.Summaries)
+	changed, dropped := changedRows(s.saved.digests, digests)
+	if len(dropped) > 0 {
+		batch.Queue(`DELETE FROM digests WHERE user_id = ANY($1)`, dropped)
+	}
+	for _, userID := range changed {
+		batch.Queue(`INSERT INTO digests (user_id, items) VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE SET items = EXCLUDED.items`, userID, jsonValue(st.Digests[userID]))
+	}
+	changed, dropped = changedRows(s.saved.summaries, summaries)
+	if len(dropped) > 0 {
+		batch.Queue(`DELETE FROM summaries WHERE user_id = ANY($1)`, dropped)
+	}
+	for _, userID := range changed {
+		batch.Queue(`INSERT INTO summaries (user_id, items) VALUES ($1, $2)
+			ON CONFLICT (user_id) DO UPDATE SET items = EXCLUDED.items`, userID, jsonValue(st.Summaries[userID]))
+	}
+	var legacy []string
+	for _, key := range []string{"mentions", "digests"} {
+		if s.legacy[key] {
+			legacy = append(legacy, key)
+		}
+	}
+	if len(legacy) > 0 {
+		batch.Queue(`DELETE FROM bot_state WHERE key = ANY($1)`, legacy)
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+	err := s.exec(ctx, batch)
+	if err == nil {
+		s.saved.counters, s.saved.mentions = counters, mentions
+		s.saved.digests, s.saved.summaries = digests, summaries
+		for _, key := range legacy {
+			delete(s.legacy, key)
+		}
+	}
+	return err
+}
+
+// Lock takes the advisory lock that makes this instance the one writing to the
+// database. With wait it stands by until the current holder stops.
+func (s *Postgres) Lock(wait bool) error {
+	for waiting := false; ; waiting = true {
+		locked, err := s.tryLock()
+		if err != nil || locked {
+			return err
+		}
+		if !wait {
+			return fmt.Errorf("%w (PostgreSQL advisory lock %d)", ErrLocked, postgresLockKey)
+		}
+		if !waiting {
+			slog.Info("⏳ Базу данных использует другой экземпляр бота, ждём своей очереди", "lock", postgresLockKey)
+		}
+		time.Sleep(postgresLockRetry)
+	}
+}
+
+func (s *Postgres) tryLock() (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), postgresTimeout)
+	defer cancel()
+	conn, err := s.pool.Acquire(ctx)
+	if err != nil {
+		return false, err
+	}
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1)`, postgresLockKey).Scan(&locked); err != nil {
+		conn.Release()
+		return false, err
+	}
+	if !locked {
+		conn.Release()
+		return false, nil
+	}
+	s.lock = conn
+	return true, nil
+}
+
+func (s *Postgres) Check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	return s.pool.Ping(ctx)
+}
+
+func (s *Postgres) Close() error {
+	if s.lock != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		s.lock.Exec(ctx, `SELECT pg_advisory_unlock($1)`, postgresLockKey)
+		cancel()
+		s.lock.Release()
+		s.lock = nil
+	}
+	s.pool.Close()
+	return nil
+}
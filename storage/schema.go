@@ -0,0 +1,83 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+)
+
+const SchemaVersion = 1
+
+type migration struct {
+	version int
+	name    string
+	apply   func(raw map[string]interface{}) error
+}
+
+var migrations = []migration{
+	{version: 1, name: "fill missing tag fields", apply: migrateV1},
+}
+
+func migrateV1(raw map[string]interface{}) error {
+	tags, _ := raw["tags"].([]interface{})
+	if tags == nil {
+		tags = []interface{}{}
+	}
+	for _, t := range tags {
+		tag, ok := t.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("unexpected tag entry %v", t)
+		}
+		if tag["subscribers"] == nil {
+			tag["subscribers"] = []interface{}{}
+		}
+	}
+	raw["tags"] = tags
+	return nil
+}
+
+func Migrate(path string, file []byte) ([]byte, bool, error) {
+	var decoded interface{}
+	if err := json.Unmarshal(file, &decoded); err != nil {
+		return nil, false, err
+	}
+	raw, ok := decoded.(map[string]interface{})
+	if !ok {
+		list, isList := decoded.([]interface{})
+		if !isList {
+			return nil, false, fmt.Errorf("unexpected data file format")
+		}
+		raw = map[string]interface{}{"tags": list}
+	}
+	version := 0
+	if v, ok := raw["schema_version"].(float64); ok {
+		version = int(v)
+	}
+	if version > SchemaVersion {
+		return nil, false, fmt.Errorf("data file schema %d is newer than supported %d", version, SchemaVersion)
+	}
+	if version == SchemaVersion {
+		return file, false, nil
+	}
+	backup := fmt.Sprintf("%s.v%d.bak", path, version)
+	sealed, err := Seal(file)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := os.WriteFile(backup, sealed, 0644); err != nil {
+		return nil, false, err
+	}
+	for _, m := range migrations {
+		if m.version <= version {
+			continue
+		}
+		if err := m.apply(raw); err != nil {
+			return nil, false, fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		raw["schema_version"] = m.version
+		slog.Info("📦 Применена миграция данных", "version", m.version, "name", m.name)
+	}
+	out, err := json.Marshal(raw)
+	return out, true, err
+}
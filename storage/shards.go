@@ -0,0 +1,647 @@
+package storage
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	globalShard = "global.json"
+	statsShard  = "stats.json"
+)
+
+type globalState struct {
+	SchemaVersion int                     `json:"schema_version"`
+	Mentions      []MentionEvent          `json:"mentions,omitempty"`
+	Digests       map[int64][]DigestItem  `json:"digests,omitempty"`
+	DeniedChats   []int64                 `json:"denied_chats,omitempty"`
+	JournalSeq    int64                   `json:"journal_seq,omitempty"`
+	Users         map[int64]*UserPrefs    `json:"users,omitempty"`
+	TagSeq        int64                   `json:"tag_seq,omitempty"`
+	Chats         map[int64]*ChatSettings `json:"chats"`
+	Members       map[int64][]int64       `json:"members"`
+}
+
+type chatShard struct {
+	ChatID   int64         `json:"chat_id"`
+	Settings *ChatSettings `json:"settings,omitempty"`
+	Tags     []*Tag        `json:"tags"`
+}
+
+const shardIdle = 10 * time.Minute
+
+// Shards keeps global state and chat settings in memory and loads a chat's tags
+// on first use; chats beyond the cache size are written out once they go idle.
+type Shards struct {
+	dir      string
+	legacy   string
+	cache    int
+	saving   sync.Mutex
+	mu       sync.Mutex
+	sums     map[int64][sha256.Size]byte
+	used     map[int64]time.Time
+	covered  map[int64]bool
+	members  map[int64][]int64
+	counters map[int64]TagCounters
+	evicted  map[int64][]byte
+}
+
+func OpenShards(dir, legacy string, cache int) (*Shards, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Shards{
+		dir:      dir,
+		legacy:   legacy,
+		cache:    cache,
+		sums:     map[int64][sha256.Size]byte{},
+		used:     map[int64]time.Time{},
+		members:  map[int64][]int64{},
+		counters: map[int64]TagCounters{},
+		evicted:  map[int64][]byte{},
+	}, nil
+}
+
+func (s *Shards) shardPath(chatID int64) string {
+	return filepath.Join(s.dir, strconv.FormatInt(chatID, 10)+".json")
+}
+
+func readSealed(path string) ([]byte, bool, error) {
+	file, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false, err
+	}
+	current := Sealed(file) == Encrypting()
+	file, err = Unseal(file)
+	return file, current, err
+}
+
+func writeAtomic(path string, file []byte) error {
+	sealed, err := Seal(file)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+func (s *Shards) shardIDs() ([]int64, error) {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, err
+	}
+	var ids []int64
+	for _, e := range entries {
+		name, ok := strings.CutSuffix(e.Name(), ".json")
+		if !ok || e.IsDir() {
+			continue
+		}
+		if id, err := strconv.ParseInt(name, 10, 64); err == nil {
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (s *Shards) Load(d *Data) error {
+	global := filepath.Join(s.dir, globalShard)
+	if _, err := os.Stat(global); os.IsNotExist(err) {
+		return s.split(d)
+	}
+	file, current, err := readSealed(global)
+	if err != nil {
+		return err
+	}
+	var g globalState
+	if err := json.Unmarshal(file, &g); err != nil {
+		return fmt.Errorf("%s: %w", global, err)
+	}
+	if g.SchemaVersion > SchemaVersion {
+		return fmt.Errorf("data schema %d is newer than supported %d", g.SchemaVersion, SchemaVersion)
+	}
+	*d = Data{
+		SchemaVersion: SchemaVersion,
+		Tags:          []*Tag{},
+		Chats:         g.Chats,
+		Digests:       g.Digests,
+		DeniedChats:   g.DeniedChats,
+		JournalSeq:    g.JournalSeq,
+		Users:         g.Users,
+		TagSeq:        g.TagSeq,
+	}
+	st, err := readStatsFile(filepath.Join(s.dir, statsShard))
+	if err != nil {
+		return err
+	}
+	if st != nil {
+		st.applyQueues(d)
+	}
+	if g.Chats == nil || g.Members == nil || !current {
+		return s.loadEverything(d, st)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.members = g.Members
+	if st != nil {
+		s.counters = st.Tags
+	}
+	return nil
+}
+
+func (s *Shards) loadEverything(d *Data, st *Stats) error {
+	if d.Chats == nil {
+		d.Chats = map[int64]*ChatSettings{}
+	}
+	ids, err := s.shardIDs()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	if st != nil {
+		s.counters = st.Tags
+	}
+	now := time.Now()
+	for _, id := range ids {
+		shard, sum, err := s.readShard(id)
+		if errors.Is(err, ErrNoKey) {
+			s.mu.Unlock()
+			return err
+		}
+		if err != nil {
+			s.quarantine(id, err)
+			continue
+		}
+		s.attach(d, shard.Tags)
+		if shard.Settings != nil && d.Chats[id] == nil {
+			d.Chats[id] = shard.Settings
+		}
+		s.sums[id] = sum
+		s.used[id] = now
+	}
+	s.counters = map[int64]TagCounters{}
+	s.mu.Unlock()
+	if err := s.saveStats(StatsOf(d)); err != nil {
+		return err
+	}
+	return s.save(d)
+}
+
+func (s *Shards) attach(d *Data, tags []*Tag) {
+	for _, tag := range tags {
+		if tag.Subscribers == nil {
+			tag.Subscribers = []Subscriber{}
+		}
+		if c, ok := s.counters[tag.ID]; ok {
+			tag.Stats, tag.LastPing = c.Stats, c.LastPing
+		}
+	}
+	d.Tags = append(d.Tags, tags...)
+}
+
+func (s *Shards) readChat(chatID int64) (*chatShard, error) {
+	file, ok := s.evicted[chatID]
+	if !ok {
+		shard, sum, err := s.readShard(chatID)
+		if errors.Is(err, os.ErrNotExist) {
+			return &chatShard{ChatID: chatID}, nil
+		}
+		if err == nil {
+			s.sums[chatID] = sum
+		}
+		return shard, err
+	}
+	shard := &chatShard{ChatID: chatID}
+	if file == nil {
+		return shard, nil
+	}
+	return shard, json.Unmarshal(file, shard)
+}
+
+// LoadChat makes a chat resident and returns the tags it added to d, if any.
+func (s *Shards) LoadChat(d *Data, chatID int64) ([]*Tag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.used[chatID]; ok {
+		s.used[chatID] = time.Now()
+		return nil, nil
+	}
+	shard, err := s.readChat(chatID)
+	if errors.Is(err, ErrNoKey) {
+		return nil, err
+	}
+	if err != nil {
+		s.quarantine(chatID, err)
+		shard = &chatShard{ChatID: chatID}
+	}
+	s.attach(d, shard.Tags)
+	s.used[chatID] = time.Now()
+	return shard.Tags, nil
+}
+
+func (s *Shards) LoadAll(d *Data) error {
+	ids, err := s.shardIDs()
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	for id := range s.evicted {
+		ids = append(ids, id)
+	}
+	s.mu.Unlock()
+	for _, id := range ids {
+		if _, err := s.LoadChat(d, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Shards) Resident(chatID int64) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.used[chatID]
+	return ok
+}
+
+// ChatsOf lists the chats where userID subscribes, moderates or created a tag.
+func (s *Shards) ChatsOf(userID int64) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var ids []int64
+	for chatID, users := range s.members {
+		for _, id := range users {
+			if id == userID {
+				ids = append(ids, chatID)
+				break
+			}
+		}
+	}
+	return ids
+}
+
+func membersOf(tags []*Tag) []int64 {
+	seen := map[int64]bool{}
+	var ids []int64
+	add := func(id int64) {
+		if id != 0 && !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for _, tag := range tags {
+		add(tag.CreatorID)
+		for _, list := range [][]Subscriber{tag.Subscribers, tag.Moderators} {
+			for _, sub := range list {
+				add(sub.ID)
+			}
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Evict removes the least recently used chats beyond the cache size from d
+// once they have been idle for a while; their tags are written on the next Save.
+func (s *Shards) Evict(d *Data) []int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cache <= 0 || len(s.used) <= s.cache {
+		return nil
+	}
+	ids := make([]int64, 0, len(s.used))
+	for id := range s.used {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return s.used[ids[i]].Before(s.used[ids[j]]) })
+	cutoff := time.Now().Add(-shardIdle)
+	evict := map[int64][]*Tag{}
+	for _, id := range ids[:len(ids)-s.cache] {
+		if s.used[id].After(cutoff) {
+			break
+		}
+		evict[id] = nil
+	}
+	if len(evict) == 0 {
+		return nil
+	}
+	AssignTagIDs(d)
+	for _, tag := range d.Tags {
+		if tags, ok := evict[tag.ChatID]; ok {
+			evict[tag.ChatID] = append(tags, tag)
+		}
+	}
+	var evicted []int64
+	for id, tags := range evict {
+		var file []byte
+		if len(tags) > 0 {
+			var err error
+			file, err = json.MarshalIndent(chatShard{ChatID: id, Tags: withoutStats(&Data{Tags: tags}).Tags}, "", "  ")
+			if err != nil {
+				slog.Warn("Не удалось выгрузить чат из памяти", "chat_id", id, "err", err)
+				delete(evict, id)
+				continue
+			}
+		}
+		for tagID, c := range s.counters {
+			if c.ChatID == id {
+				delete(s.counters, tagID)
+			}
+		}
+		for _, tag := range tags {
+			s.counters[tag.ID] = countersOf(tag)
+		}
+		if members := membersOf(tags); len(members) > 0 {
+			s.members[id] = members
+		} else {
+			delete(s.members, id)
+		}
+		s.evicted[id] = file
+		delete(s.used, id)
+		evicted = append(evicted, id)
+	}
+	kept := make([]*Tag, 0, len(d.Tags))
+	for _, tag := range d.Tags {
+		if _, ok := evict[tag.ChatID]; !ok {
+			kept = append(kept, tag)
+		}
+	}
+	d.Tags = kept
+	return evicted
+}
+
+// Snapshot records which chats are resident; the next Save and SaveStats
+// treat exactly these chats as covered by the data they are given.
+func (s *Shards) Snapshot() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.covered = s.residentSet()
+}
+
+func (s *Shards) residentSet() map[int64]bool {
+	set := make(map[int64]bool, len(s.used))
+	for id := range s.used {
+		set[id] = true
+	}
+	return set
+}
+
+func (s *Shards) coveredSet() map[int64]bool {
+	if s.covered != nil {
+		return s.covered
+	}
+	return s.residentSet()
+}
+
+// Full returns d with the tags of every chat that is not resident read from disk.
+func (s *Shards) Full(d *Data) (*Data, error) {
+	ids, err := s.shardIDs()
+	if err != nil {
+		return nil, err
+	}
+	out := *d
+	out.Tags = append([]*Tag{}, d.Tags...)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id := range s.evicted {
+		ids = append(ids, id)
+	}
+	seen := map[int64]bool{}
+	for _, id := range ids {
+		if _, ok := s.used[id]; ok || seen[id] {
+			continue
+		}
+		seen[id] = true
+		shard, err := s.readChat(id)
+		if errors.Is(err, ErrNoKey) {
+			return nil, err
+		}
+		if err != nil {
+			slog.Warn("Не удалось прочитать файл чата", "chat_id", id, "err", err)
+			continue
+		}
+		s.attach(&out, shard.Tags)
+	}
+	return &out, nil
+}
+
+func (s *Shards) readShard(chatID int64) (*chatShard, [sha256.Size]byte, error) {
+	file, current, err := readSealed(s.shardPath(chatID))
+	if err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	var shard chatShard
+	if err := json.Unmarshal(file, &shard); err != nil {
+		return nil, [sha256.Size]byte{}, err
+	}
+	if shard.ChatID != chatID {
+		return nil, [sha256.Size]byte{}, fmt.Errorf("shard belongs to chat %d", shard.ChatID)
+	}
+	if !current {
+		return &shard, [sha256.Size]byte{}, nil
+	}
+	return &shard, sha256.Sum256(file), nil
+}
+
+func (s *Shards) quarantine(chatID int64, cause error) {
+	path := s.shardPath(chatID)
+	bad := path + ".corrupt"
+	slog.Error("Повреждённый файл чата отложен, чат пропущен", "chat_id", chatID, "file", bad, "err", cause)
+	if err := os.Rename(path, bad); err != nil {
+		slog.Warn("Не удалось переименовать повреждённый файл чата", "file", path, "err", err)
+	}
+}
+
+func (s *Shards) split(d *Data) error {
+	if _, err := os.Stat(s.legacy); err == nil {
+		if err := NewFile(s.legacy).Load(d); err != nil {
+			return err
+		}
+		slog.Info("📦 Данные разложены по файлам чатов", "from", s.legacy, "dir", s.dir)
+	} else {
+		*d = Data{SchemaVersion: SchemaVersion, Tags: []*Tag{}}
+	}
+	s.mu.Lock()
+	now := time.Now()
+	for _, tag := range d.Tags {
+		s.used[tag.ChatID] = now
+	}
+	s.mu.Unlock()
+	if err := s.saveStats(StatsOf(d)); err != nil {
+		return err
+	}
+	return s.save(d)
+}
+
+func (s *Shards) Save(d *Data) error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	return s.save(d)
+}
+
+func (s *Shards) SaveStats(st *Stats) error {
+	s.saving.Lock()
+	defer s.saving.Unlock()
+	return s.saveStats(st)
+}
+
+func (s *Shards) saveStats(st *Stats) error {
+	s.mu.Lock()
+	covered := s.coveredSet()
+	for id, c := range s.counters {
+		if covered[c.ChatID] {
+			delete(s.counters, id)
+		}
+	}
+	for id, c := range st.Tags {
+		s.counters[id] = c
+	}
+	merged := &Stats{Tags: make(map[int64]TagCounters, len(s.counters)), Mentions: st.Mentions, Digests: st.Digests, Summaries: st.Summaries}
+	for id, c := range s.counters {
+		merged.Tags[id] = c
+	}
+	s.mu.Unlock()
+	return saveStatsFile(filepath.Join(s.dir, statsShard), merged)
+}
+
+func (s *Shards) writeShard(chatID int64, file []byte) error {
+	sum := sha256.Sum256(file)
+	s.mu.Lock()
+	cached, ok := s.sums[chatID]
+	s.mu.Unlock()
+	if ok && cached == sum {
+		return nil
+	}
+	if err := writeAtomic(s.shardPath(chatID), file); err != nil {
+		return err
+	}
+	s.mu.Lock()
+	s.sums[chatID] = sum
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Shards) removeShard(chatID int64) error {
+	if err := os.Remove(s.shardPath(chatID)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	s.mu.Lock()
+	delete(s.sums, chatID)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *Shards) save(d *Data) error {
+	d = withoutStats(d)
+	shards := map[int64]*chatShard{}
+	for _, tag := range d.Tags {
+		shard, ok := shards[tag.ChatID]
+		if !ok {
+			shard = &chatShard{ChatID: tag.ChatID, Tags: []*Tag{}}
+			shards[tag.ChatID] = shard
+		}
+		shard.Tags = append(shard.Tags, tag)
+	}
+
+	s.mu.Lock()
+	covered := s.coveredSet()
+	evicted := make(map[int64][]byte, len(s.evicted))
+	for id, file := range s.evicted {
+		evicted[id] = file
+	}
+	s.mu.Unlock()
+
+	for chatID, shard := range shards {
+		file, err := json.MarshalIndent(shard, "", "  ")
+		if err != nil {
+			return err
+		}
+		if err := s.writeShard(chatID, file); err != nil {
+			return err
+		}
+	}
+	for chatID, file := range evicted {
+		if _, ok := shards[chatID]; !ok {
+			var err error
+			if file == nil {
+				err = s.removeShard(chatID)
+			} else {
+				err = s.writeShard(chatID, file)
+			}
+			if err != nil {
+				return err
+			}
+		}
+		s.mu.Lock()
+		if current, ok := s.evicted[chatID]; ok && bytes.Equal(current, file) {
+			delete(s.evicted, chatID)
+		}
+		s.mu.Unlock()
+	}
+	for chatID := range covered {
+		if _, ok := shards[chatID]; ok {
+			continue
+		}
+		if err := s.removeShard(chatID); err != nil {
+			return err
+		}
+	}
+
+	s.mu.Lock()
+	for chatID := range covered {
+		if shard, ok := shards[chatID]; ok {
+			s.members[chatID] = membersOf(shard.Tags)
+		} else {
+			delete(s.members, chatID)
+		}
+	}
+	members := make(map[int64][]int64, len(s.members))
+	for chatID, users := range s.members {
+		members[chatID] = users
+	}
+	s.mu.Unlock()
+	chats := d.Chats
+	if chats == nil {
+		chats = map[int64]*ChatSettings{}
+	}
+	file, err := json.MarshalIndent(globalState{
+		SchemaVersion: d.SchemaVersion,
+		Digests:       d.Digests,
+		DeniedChats:   d.DeniedChats,
+		JournalSeq:    d.JournalSeq,
+		Users:         d.Users,
+		TagSeq:        d.TagSeq,
+		Chats:         chats,
+		Members:       members,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(filepath.Join(s.dir, globalShard), file)
+}
+
+func (s *Shards) Check() error {
+	file, _, err := readSealed(filepath.Join(s.dir, globalShard))
+	if err != nil {
+		return err
+	}
+	var g globalState
+	return json.Unmarshal(file, &g)
+}
+
+func (s *Shards) Close() error {
+	return nil
+}
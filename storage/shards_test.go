@@ -0,0 +1,195 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShardsLoadChatsLazily(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenShards(dir, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Data
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	d.Tags = []*Tag{
+		{Name: "raid", ChatID: -1, Subscribers: []Subscriber{{ID: 7}}, Stats: TagStats{Mentions: 3}},
+		{Name: "dota", ChatID: -2, CreatorID: 8, Subscribers: []Subscriber{}},
+	}
+	d.Chats = map[int64]*ChatSettings{-1: {Title: "one"}, -2: {Title: "two"}}
+	s.LoadChat(&d, -1)
+	s.LoadChat(&d, -2)
+	AssignTagIDs(&d)
+	s.Snapshot()
+	if err := s.Save(&d); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveStats(StatsOf(&d)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ = OpenShards(dir, "", 1)
+	d = Data{}
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Tags) != 0 || d.Chats[-2].Title != "two" {
+		t.Fatalf("Load read tags eagerly or lost settings: %d tags, %+v", len(d.Tags), d.Chats)
+	}
+	if ids := s.ChatsOf(7); len(ids) != 1 || ids[0] != -1 {
+		t.Fatalf("ChatsOf(7) = %v", ids)
+	}
+	tags, err := s.LoadChat(&d, -1)
+	if err != nil || len(tags) != 1 || tags[0].Stats.Mentions != 3 {
+		t.Fatalf("LoadChat returned %v, %v", tags, err)
+	}
+	if again, _ := s.LoadChat(&d, -1); again != nil || len(d.Tags) != 1 {
+		t.Fatal("resident chat loaded twice")
+	}
+	full, err := s.Full(&d)
+	if err != nil || len(full.Tags) != 2 || len(d.Tags) != 1 {
+		t.Fatalf("Full returned %d tags, %v", len(full.Tags), err)
+	}
+}
+
+func TestShardsEvictIdleChats(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenShards(dir, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Data
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	s.LoadChat(&d, -1)
+	s.LoadChat(&d, -2)
+	d.Tags = append(d.Tags,
+		&Tag{Name: "raid", ChatID: -1, Subscribers: []Subscriber{{ID: 7}}, Stats: TagStats{Mentions: 5}},
+		&Tag{Name: "dota", ChatID: -2, Subscribers: []Subscriber{}})
+	if ids := s.Evict(&d); ids != nil {
+		t.Fatalf("evicted chats in use: %v", ids)
+	}
+	s.used[-1] = time.Now().Add(-2 * shardIdle)
+	if ids := s.Evict(&d); len(ids) != 1 || ids[0] != -1 {
+		t.Fatalf("Evict = %v", ids)
+	}
+	if len(d.Tags) != 1 || s.Resident(-1) {
+		t.Fatal("evicted chat is still in memory")
+	}
+	if ids := s.ChatsOf(7); len(ids) != 1 || ids[0] != -1 {
+		t.Fatalf("ChatsOf(7) after eviction = %v", ids)
+	}
+
+	s.Snapshot()
+	if err := s.Save(&d); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.SaveStats(StatsOf(&d)); err != nil {
+		t.Fatal(err)
+	}
+	tags, err := s.LoadChat(&d, -1)
+	if err != nil || len(tags) != 1 || tags[0].Name != "raid" || tags[0].Stats.Mentions != 5 {
+		t.Fatalf("evicted chat came back as %v, %v", tags, err)
+	}
+
+	s, _ = OpenShards(dir, "", 1)
+	d = Data{}
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.LoadAll(&d); err != nil || len(d.Tags) != 2 {
+		t.Fatalf("LoadAll returned %d tags, %v", len(d.Tags), err)
+	}
+}
+
+func TestShardsMoveSettingsOutOfChatFiles(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenShards(dir, "", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := writeAtomic(s.shardPath(-1), []byte(`{"chat_id":-1,"settings":{"title":"one"},"tags":[{"id":1,"name":"raid","chat_id":-1}]}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeAtomic(filepath.Join(dir, globalShard), []byte(`{"schema_version":1,"tag_seq":1}`)); err != nil {
+		t.Fatal(err)
+	}
+	var d Data
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Tags) != 1 || d.Chats[-1] == nil || d.Chats[-1].Title != "one" {
+		t.Fatalf("old layout loaded as %d tags, %+v", len(d.Tags), d.Chats)
+	}
+
+	s, _ = OpenShards(dir, "", 1)
+	d = Data{}
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	if len(d.Tags) != 0 || d.Chats[-1] == nil || d.Chats[-1].Title != "one" {
+		t.Fatalf("settings were not moved to global state: %d tags, %+v", len(d.Tags), d.Chats)
+	}
+}
+
+func TestShardsKeepDigestsWithStats(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenShards(dir, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Data
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	d.Digests = map[int64][]DigestItem{7: {{Tag: "raid", ChatID: -1}}}
+	if err := s.SaveStats(StatsOf(&d)); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ = OpenShards(dir, "", 0)
+	d = Data{}
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	if items := d.Digests[7]; len(items) != 1 || items[0].Tag != "raid" {
+		t.Fatalf("digest saved with the counters was lost: %+v", d.Digests)
+	}
+}
+
+func TestShardsKeepSummaryItemsWithStats(t *testing.T) {
+	dir := t.TempDir()
+	s, err := OpenShards(dir, "", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var d Data
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	d.Users = map[int64]*UserPrefs{7: {Summary: &DailySummary{Hour: 21}}}
+	if err := s.Save(&d); err != nil {
+		t.Fatal(err)
+	}
+	d.Users[7].Summary.Items = []DigestItem{{Tag: "raid", ChatID: -1}}
+	if err := s.SaveStats(StatsOf(&d)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Save(&d); err != nil {
+		t.Fatal(err)
+	}
+
+	s, _ = OpenShards(dir, "", 0)
+	d = Data{}
+	if err := s.Load(&d); err != nil {
+		t.Fatal(err)
+	}
+	if p := d.Users[7]; p == nil || p.Summary == nil || len(p.Summary.Items) != 1 || p.Summary.Hour != 21 {
+		t.Fatalf("summary items saved with the counters were lost: %+v", d.Users[7])
+	}
+}
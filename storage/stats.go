@@ -0,0 +1,123 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type TagCounters struct {
+	ChatID   int64    `json:"chat_id,omitempty"`
+	Stats    TagStats `json:"stats"`
+	LastPing *Ping    `json:"last_ping,omitempty"`
+}
+
+type Stats struct {
+	Tags      map[int64]TagCounters  `json:"tags"`
+	Mentions  []MentionEvent         `json:"mentions,omitempty"`
+	Digests   map[int64][]DigestItem `json:"digests,omitempty"`
+	Summaries map[int64][]DigestItem `json:"summaries,omitempty"`
+}
+
+func StatsOf(d *Data) *Stats {
+	AssignTagIDs(d)
+	s := &Stats{Tags: map[int64]TagCounters{}, Mentions: d.Mentions, Digests: d.Digests}
+	for _, tag := range d.Tags {
+		s.Tags[tag.ID] = countersOf(tag)
+	}
+	for userID, p := range d.Users {
+		if p.Summary != nil && len(p.Summary.Items) > 0 {
+			if s.Summaries == nil {
+				s.Summaries = map[int64][]DigestItem{}
+			}
+			s.Summaries[userID] = p.Summary.Items
+		}
+	}
+	return s
+}
+
+func countersOf(tag *Tag) TagCounters {
+	return TagCounters{ChatID: tag.ChatID, Stats: tag.Stats, LastPing: tag.LastPing}
+}
+
+func (s *Stats) apply(d *Data) {
+	for _, tag := range d.Tags {
+		if c, ok := s.Tags[tag.ID]; ok {
+			tag.Stats, tag.LastPing = c.Stats, c.LastPing
+		}
+	}
+	s.applyQueues(d)
+}
+
+func (s *Stats) applyQueues(d *Data) {
+	d.Mentions = s.Mentions
+	if s.Digests != nil {
+		d.Digests = s.Digests
+	}
+	applySummaries(d, s.Summaries)
+}
+
+func applySummaries(d *Data, summaries map[int64][]DigestItem) {
+	for userID, items := range summaries {
+		if p := d.Users[userID]; p != nil && p.Summary != nil {
+			p.Summary.Items = items
+		}
+	}
+}
+
+func withoutStats(d *Data) *Data {
+	out := *d
+	out.Mentions = nil
+	out.Digests = nil
+	if d.Users != nil {
+		out.Users = make(map[int64]*UserPrefs, len(d.Users))
+		for userID, p := range d.Users {
+			if p.Summary != nil && p.Summary.Items != nil {
+				copied, summary := *p, *p.Summary
+				summary.Items = nil
+				copied.Summary = &summary
+				p = &copied
+			}
+			out.Users[userID] = p
+		}
+	}
+	out.Tags = make([]*Tag, len(d.Tags))
+	for i, tag := range d.Tags {
+		copied := *tag
+		copied.Stats, copied.LastPing = TagStats{}, nil
+		out.Tags[i] = &copied
+	}
+	return &out
+}
+
+func readStatsFile(path string) (*Stats, error) {
+	file, _, err := readSealed(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var s Stats
+	if err := json.Unmarshal(file, &s); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return &s, nil
+}
+
+func loadStatsFile(path string, d *Data) (bool, error) {
+	s, err := readStatsFile(path)
+	if s == nil || err != nil {
+		return false, err
+	}
+	s.apply(d)
+	return true, nil
+}
+
+func saveStatsFile(path string, s *Stats) error {
+	file, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeAtomic(path, file)
+}
@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+type Storage interface {
+	Load(d *Data) error
+	Save(d *Data) error
+	SaveStats(s *Stats) error
+	Check() error
+	Close() error
+}
+
+// Lazy is implemented by stores that keep only recently used chats in memory.
+type Lazy interface {
+	LoadChat(d *Data, chatID int64) ([]*Tag, error)
+	LoadAll(d *Data) error
+	Resident(chatID int64) bool
+	ChatsOf(userID int64) []int64
+	Evict(d *Data) []int64
+	Snapshot()
+	Full(d *Data) (*Data, error)
+}
+
+func Open(databaseURL, path string) (Storage, error) {
+	if databaseURL != "" {
+		return OpenPostgres(databaseURL)
+	}
+	if info, err := os.Stat(path); err == nil && info.IsDir() {
+		return OpenShards(path, "", 0)
+	}
+	return NewFile(path), nil
+}
+
+type File struct {
+	path string
+	mu   sync.Mutex
+}
+
+func NewFile(path string) *File {
+	return &File{path: path}
+}
+
+func (s *File) statsPath() string {
+	ext := filepath.Ext(s.path)
+	return strings.TrimSuffix(s.path, ext) + ".stats" + ext
+}
+
+func (s *File) Load(d *Data) error {
+	if _, err := os.Stat(s.path); os.IsNotExist(err) {
+		*d = Data{SchemaVersion: SchemaVersion, Tags: []*Tag{}}
+		return s.Save(d)
+	}
+	file, err := os.ReadFile(s.path)
+	if err != nil {
+		return err
+	}
+	resave := Sealed(file) != Encrypting()
+	if file, err = Unseal(file); err != nil {
+		return err
+	}
+	file, migrated, err := Migrate(s.path, file)
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(file, d); err != nil {
+		return err
+	}
+	found, err := loadStatsFile(s.statsPath(), d)
+	if err != nil {
+		return err
+	}
+	if !found {
+		if err := s.SaveStats(StatsOf(d)); err != nil {
+			return err
+		}
+		migrated = true
+	}
+	if migrated || resave {
+		return s.Save(d)
+	}
+	return nil
+}
+
+func (s *File) Save(d *Data) error {
+	file, err := json.MarshalIndent(withoutStats(d), "", "  ")
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sealed, err := Seal(file)
+	if err != nil {
+		return err
+	}
+	tmp := s.path + ".tmp"
+	if err := os.WriteFile(tmp, sealed, 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path)
+}
+
+func (s *File) SaveStats(st *Stats) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return saveStatsFile(s.statsPath(), st)
+}
+
+func (s *File) Check() error {
+	file, err := os.ReadFile(s.path)
+	if err == nil {
+		file, err = Unseal(file)
+	}
+	if err != nil {
+		return err
+	}
+	var d Data
+	return json.Unmarshal(file, &d)
+}
+
+func (s *File) Close() error {
+	return nil
+}
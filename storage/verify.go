@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const futureSkew = 24 * time.Hour
+
+func uniqueSubscribers(subs []Subscriber) ([]Subscriber, int) {
+	seen := map[int64]bool{}
+	kept := subs[:0]
+	for _, sub := range subs {
+		if sub.ID != 0 && !seen[sub.ID] {
+			seen[sub.ID] = true
+			kept = append(kept, sub)
+		}
+	}
+	return kept, len(subs) - len(kept)
+}
+
+func badTime(t time.Time, now time.Time) bool {
+	return t.IsZero() || t.After(now.Add(futureSkew))
+}
+
+func Verify(d *Data, repair bool) (fixed, problems []string) {
+	now := time.Now()
+	note := func(canFix bool, format string, args ...interface{}) {
+		msg := fmt.Sprintf(format, args...)
+		if repair && canFix {
+			fixed = append(fixed, msg)
+		} else {
+			problems = append(problems, msg)
+		}
+	}
+	if d.SchemaVersion != SchemaVersion {
+		problems = append(problems, fmt.Sprintf("schema version %d, expected %d", d.SchemaVersion, SchemaVersion))
+	}
+
+	kept := d.Tags[:0:0]
+	names := map[string]bool{}
+	for i, tag := range d.Tags {
+		if tag == nil {
+			note(true, "tag %d is null", i)
+			if !repair {
+				kept = append(kept, tag)
+			}
+			continue
+		}
+		kept = append(kept, tag)
+		if strings.TrimSpace(tag.Name) == "" {
+			note(false, "tag %d in chat %d has an empty name", i, tag.ChatID)
+		}
+		for _, list := range []struct {
+			name string
+			subs *[]Subscriber
+		}{{"subscriber", &tag.Subscribers}, {"moderator", &tag.Moderators}, {"request", &tag.Requests}, {"ban", &tag.Banned}} {
+			subs := append([]Subscriber(nil), *list.subs...)
+			if unique, dropped := uniqueSubscribers(subs); dropped > 0 {
+				note(true, "#%s in chat %d: %d duplicate or empty %s entries", tag.Name, tag.ChatID, dropped, list.name)
+				if repair {
+					*list.subs = unique
+				}
+			}
+		}
+		if badTime(tag.CreatedAt, now) {
+			note(true, "#%s in chat %d has an invalid creation time %s", tag.Name, tag.ChatID, tag.CreatedAt.Format(time.RFC3339))
+			if repair {
+				tag.CreatedAt = now
+			}
+		}
+		if badTime(tag.Stats.LastUsed, now) && !tag.Stats.LastUsed.IsZero() {
+			note(true, "#%s in chat %d was last used in the future", tag.Name, tag.ChatID)
+			if repair {
+				tag.Stats.LastUsed = now
+			}
+		}
+		if !tag.Active() {
+			continue
+		}
+		key := fmt.Sprintf("%d/%s", tag.ChatID, strings.ToLower(tag.Name))
+		if names[key] {
+			note(true, "#%s is active twice in chat %d, archiving the later copy", tag.Name, tag.ChatID)
+			if repair {
+				at := now
+				tag.ArchivedAt = &at
+			}
+			continue
+		}
+		names[key] = true
+	}
+	d.Tags = kept
+	return fixed, problems
+}
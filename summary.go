@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	defaultSummaryHour = 21
+	maxSummaryItems    = 200
+)
+
+func noteSummary(c tele.Context, sub Subscriber, tag *Tag) {
+	p := data.Users[sub.ID]
+	if p == nil || p.Summary == nil || sub.ID == c.Sender().ID {
+		return
+	}
+	if !isMuted(sub.ID, tag) && deliveryFor(sub) != deliveryDigest {
+		return
+	}
+	if len(p.Summary.Items) >= maxSummaryItems {
+		p.Summary.Items = p.Summary.Items[1:]
+	}
+	p.Summary.Items = append(p.Summary.Items, DigestItem{
+		Tag:       tag.Name,
+		ChatID:    c.Chat().ID,
+		ChatTitle: c.Chat().Title,
+		Link:      messageLink(c.Chat(), c.Message().ID),
+		From:      c.Sender().Username,
+		At:        time.Now(),
+	})
+	saveStats()
+}
+
+func summaryText(userID int64, items []DigestItem) string {
+	lang := chatLang(items[0].ChatID)
+	loc := userLocation(userID)
+	sort.SliceStable(items, func(i, j int) bool {
+		if items[i].ChatID != items[j].ChatID {
+			return items[i].ChatID < items[j].ChatID
+		}
+		return strings.ToLower(items[i].Tag) < strings.ToLower(items[j].Tag)
+	})
+	var sb strings.Builder
+	sb.WriteString(tr(lang, "summary.header", len(items)))
+	for i, item := range items {
+		if i == 0 || item.ChatID != items[i-1].ChatID {
+			title := item.ChatTitle
+			if title == "" {
+				title = strconv.FormatInt(item.ChatID, 10)
+			}
+			sb.WriteString(fmt.Sprintf("\n💬 <b>%s</b>\n", esc(title)))
+		}
+		if i == 0 || item.ChatID != items[i-1].ChatID || !strings.EqualFold(item.Tag, items[i-1].Tag) {
+			sb.WriteString(fmt.Sprintf("<code>#%s</code>\n", esc(item.Tag)))
+		}
+		line := fmt.Sprintf("  %s — %s", item.At.In(loc).Format("15:04"), esc(item.From))
+		if item.Link != "" {
+			line += fmt.Sprintf(` <a href="%s">%s</a>`, item.Link, tr(lang, "digest.open"))
+		}
+		sb.WriteString(line + "\n")
+	}
+	return sb.String()
+}
+
+func sendSummaries(b *tele.Bot) {
+	now := time.Now()
+	for userID, p := range data.Users {
+		s := p.Summary
+		if s == nil || now.In(userLocation(userID)).Hour() != s.Hour || now.Sub(s.LastSent) < 20*time.Hour {
+			continue
+		}
+		s.LastSent = now
+		if len(s.Items) > 0 {
+			id := userID
+			sendQueue.enqueue(context.Background(), &tele.User{ID: id}, summaryText(id, s.Items), func(_ *tele.Message, err error) {
+				if err != nil {
+					slog.Warn("Не удалось отправить дневную сводку", "user_id", id, "err", err)
+				}
+			}, tele.NoPreview)
+			s.Items = nil
+		}
+		saveData()
+	}
+}
+
+func handleSummary(c tele.Context) error {
+	if c.Chat().Type != tele.ChatPrivate {
+		return c.Send(T(c, "prefs.private_only"))
+	}
+	args := strings.Fields(c.Message().Payload)
+	p := userPrefs(c.Sender().ID)
+	if len(args) == 0 {
+		if p.Summary != nil {
+			return c.Send(T(c, "summary.status_on", p.Summary.Hour, len(p.Summary.Items)))
+		}
+		return c.Send(T(c, "summary.status_off"))
+	}
+	switch strings.ToLower(args[0]) {
+	case "on":
+		hour := defaultSummaryHour
+		if len(args) > 1 {
+			h, err := strconv.Atoi(args[1])
+			if err != nil || h < 0 || h > 23 {
+				return c.Send(T(c, "summary.usage"))
+			}
+			hour = h
+		}
+		if p.Summary == nil {
+			p.Summary = &DailySummary{LastSent: time.Now()}
+		}
+		p.Summary.Hour = hour
+		saveData()
+		return c.Send(T(c, "summary.enabled", hour))
+	case "off":
+		p.Summary = nil
+		saveData()
+		return c.Send(T(c, "summary.disabled"))
+	case "now":
+		if p.Summary == nil || len(p.Summary.Items) == 0 {
+			return c.Send(T(c, "summary.empty"))
+		}
+		text := summaryText(c.Sender().ID, p.Summary.Items)
+		p.Summary.Items = nil
+		saveData()
+		return c.Send(text, tele.NoPreview)
+	}
+	return c.Send(T(c, "summary.usage"))
+}
@@ -0,0 +1,76 @@
+package tagindex
+
+import (
+	"strings"
+
+	"tagger/storage"
+)
+
+type Index struct {
+	byChat map[int64]map[string]*storage.Tag
+	byUser map[int64][]*storage.Tag
+}
+
+func New() *Index {
+	return &Index{byChat: map[int64]map[string]*storage.Tag{}, byUser: map[int64][]*storage.Tag{}}
+}
+
+func (x *Index) Add(tag *storage.Tag) {
+	if !tag.Active() {
+		return
+	}
+	byName := x.byChat[tag.ChatID]
+	if byName == nil {
+		byName = map[string]*storage.Tag{}
+		x.byChat[tag.ChatID] = byName
+	}
+	byName[strings.ToLower(tag.Name)] = tag
+	for _, sub := range tag.Subscribers {
+		x.byUser[sub.ID] = append(x.byUser[sub.ID], tag)
+	}
+}
+
+func (x *Index) Remove(tag *storage.Tag) {
+	name := strings.ToLower(tag.Name)
+	if byName := x.byChat[tag.ChatID]; byName[name] == tag {
+		delete(byName, name)
+	}
+	for _, sub := range tag.Subscribers {
+		x.Unsubscribe(tag, sub.ID)
+	}
+}
+
+func (x *Index) Subscribe(tag *storage.Tag, userID int64) {
+	if tag.Active() {
+		x.byUser[userID] = append(x.byUser[userID], tag)
+	}
+}
+
+func (x *Index) Unsubscribe(tag *storage.Tag, userID int64) {
+	tags := x.byUser[userID]
+	for i, t := range tags {
+		if t == tag {
+			x.byUser[userID] = append(tags[:i:i], tags[i+1:]...)
+			break
+		}
+	}
+	if len(x.byUser[userID]) == 0 {
+		delete(x.byUser, userID)
+	}
+}
+
+func (x *Index) Get(chatID int64, name string) *storage.Tag {
+	return x.byChat[chatID][strings.ToLower(name)]
+}
+
+func (x *Index) Chat(chatID int64) map[string]*storage.Tag {
+	return x.byChat[chatID]
+}
+
+func (x *Index) DropChat(chatID int64) {
+	delete(x.byChat, chatID)
+}
+
+func (x *Index) Subscriptions(userID int64) []*storage.Tag {
+	return x.byUser[userID]
+}
@@ -0,0 +1,59 @@
+package tagindex
+
+import (
+	"testing"
+	"time"
+
+	"tagger/storage"
+)
+
+func TestIndexLookupIsCaseInsensitive(t *testing.T) {
+	x := New()
+	tag := &storage.Tag{Name: "Raid", ChatID: -1}
+	x.Add(tag)
+	if x.Get(-1, "raid") != tag || x.Get(-1, "RAID") != tag {
+		t.Fatal("lookup should ignore case")
+	}
+	if x.Get(-2, "raid") != nil {
+		t.Fatal("tag leaked into another chat")
+	}
+}
+
+func TestIndexSkipsInactiveTags(t *testing.T) {
+	x := New()
+	now := time.Now()
+	x.Add(&storage.Tag{Name: "old", ChatID: -1, ArchivedAt: &now, Subscribers: []storage.Subscriber{{ID: 7}}})
+	if x.Get(-1, "old") != nil || len(x.Subscriptions(7)) != 0 {
+		t.Fatal("archived tag was indexed")
+	}
+}
+
+func TestIndexSubscriptions(t *testing.T) {
+	x := New()
+	a := &storage.Tag{Name: "a", ChatID: -1, Subscribers: []storage.Subscriber{{ID: 7}}}
+	b := &storage.Tag{Name: "b", ChatID: -1}
+	x.Add(a)
+	x.Add(b)
+	b.Subscribers = append(b.Subscribers, storage.Subscriber{ID: 7})
+	x.Subscribe(b, 7)
+	if got := x.Subscriptions(7); len(got) != 2 {
+		t.Fatalf("got %d subscriptions, want 2", len(got))
+	}
+	x.Unsubscribe(a, 7)
+	if got := x.Subscriptions(7); len(got) != 1 || got[0] != b {
+		t.Fatalf("unexpected subscriptions after unsubscribe: %v", got)
+	}
+	x.Remove(b)
+	if x.Get(-1, "b") != nil || len(x.Subscriptions(7)) != 0 {
+		t.Fatal("removed tag is still indexed")
+	}
+}
+
+func TestIndexDropChat(t *testing.T) {
+	x := New()
+	x.Add(&storage.Tag{Name: "a", ChatID: -1})
+	x.DropChat(-1)
+	if len(x.Chat(-1)) != 0 {
+		t.Fatal("chat was not dropped")
+	}
+}
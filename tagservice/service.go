@@ -0,0 +1,193 @@
+package tagservice
+
+import (
+	"sort"
+	"strings"
+
+	"tagger/storage"
+	"tagger/tagindex"
+)
+
+type Service interface {
+	Find(chatID int64, name string) *storage.Tag
+	Local(chatID int64, name string) *storage.Tag
+	InChat(chatID int64) []*storage.Tag
+	Chat(chatID int64) map[string]*storage.Tag
+	Subscriptions(userID int64) []*storage.Tag
+	Matches(userID int64, name string) []*storage.Tag
+	Linked(chatID int64) []int64
+	Add(tag *storage.Tag)
+	Remove(tag *storage.Tag)
+	DropChat(chatID int64)
+	Subscribe(tag *storage.Tag, sub storage.Subscriber)
+	Unsubscribe(tag *storage.Tag, userID int64) bool
+	Relink()
+	Rebuild()
+}
+
+type Tags struct {
+	data   *storage.Data
+	index  *tagindex.Index
+	linked map[int64][]int64
+}
+
+func New(d *storage.Data) *Tags {
+	t := &Tags{data: d}
+	t.Rebuild()
+	return t
+}
+
+func (t *Tags) Rebuild() {
+	t.index = tagindex.New()
+	for _, tag := range t.data.Tags {
+		t.index.Add(tag)
+	}
+	t.Relink()
+}
+
+func (t *Tags) Relink() {
+	members := map[string][]int64{}
+	for id, s := range t.data.Chats {
+		if s.Community != "" {
+			members[s.Community] = append(members[s.Community], id)
+		}
+	}
+	t.linked = map[int64][]int64{}
+	for _, ids := range members {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+		for _, id := range ids {
+			for _, peer := range ids {
+				if peer != id {
+					t.linked[id] = append(t.linked[id], peer)
+				}
+			}
+		}
+	}
+}
+
+func (t *Tags) Linked(chatID int64) []int64 {
+	return t.linked[chatID]
+}
+
+func (t *Tags) global(chatID int64, name string) *storage.Tag {
+	for _, peer := range t.linked[chatID] {
+		if tag := t.index.Get(peer, name); tag != nil && tag.Global {
+			return tag
+		}
+	}
+	return nil
+}
+
+func (t *Tags) globals(chatID int64) []*storage.Tag {
+	var tags []*storage.Tag
+	for _, peer := range t.linked[chatID] {
+		for _, tag := range t.index.Chat(peer) {
+			if tag.Global {
+				tags = append(tags, tag)
+			}
+		}
+	}
+	return tags
+}
+
+func (t *Tags) Find(chatID int64, name string) *storage.Tag {
+	name = strings.ToLower(name)
+	if tag := t.index.Get(chatID, name); tag != nil {
+		return tag
+	}
+	if tag := t.index.Get(0, name); tag != nil {
+		return tag
+	}
+	if tag := t.global(chatID, name); tag != nil {
+		return tag
+	}
+	if chatID > 0 {
+		if found := t.Matches(chatID, name); len(found) == 1 {
+			return found[0]
+		}
+	}
+	return nil
+}
+
+func (t *Tags) memberChats(userID int64) []int64 {
+	var chats []int64
+	seen := map[int64]bool{0: true, userID: true}
+	for _, tag := range t.index.Subscriptions(userID) {
+		if !seen[tag.ChatID] {
+			seen[tag.ChatID] = true
+			chats = append(chats, tag.ChatID)
+		}
+	}
+	return chats
+}
+
+func (t *Tags) Matches(userID int64, name string) []*storage.Tag {
+	var found []*storage.Tag
+	for _, chatID := range t.memberChats(userID) {
+		if tag := t.index.Get(chatID, name); tag != nil {
+			found = append(found, tag)
+		}
+	}
+	return found
+}
+
+func (t *Tags) Local(chatID int64, name string) *storage.Tag {
+	return t.index.Get(chatID, name)
+}
+
+func (t *Tags) InChat(chatID int64) []*storage.Tag {
+	var tags []*storage.Tag
+	chats := []int64{chatID, 0}
+	if chatID > 0 {
+		chats = append(chats, t.memberChats(chatID)...)
+	} else {
+		tags = t.globals(chatID)
+	}
+	for _, id := range chats {
+		for _, tag := range t.index.Chat(id) {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].CreatedAt.Before(tags[j].CreatedAt) })
+	return tags
+}
+
+func (t *Tags) Chat(chatID int64) map[string]*storage.Tag {
+	return t.index.Chat(chatID)
+}
+
+func (t *Tags) Subscriptions(userID int64) []*storage.Tag {
+	return t.index.Subscriptions(userID)
+}
+
+func (t *Tags) Add(tag *storage.Tag) {
+	t.index.Add(tag)
+}
+
+func (t *Tags) Remove(tag *storage.Tag) {
+	t.index.Remove(tag)
+}
+
+func (t *Tags) DropChat(chatID int64) {
+	t.index.DropChat(chatID)
+}
+
+func (t *Tags) Subscribe(tag *storage.Tag, sub storage.Subscriber) {
+	tag.Subscribers = append(tag.Subscribers, sub)
+	t.index.Subscribe(tag, sub.ID)
+}
+
+func (t *Tags) Unsubscribe(tag *storage.Tag, userID int64) bool {
+	subs := []storage.Subscriber{}
+	for _, sub := range tag.Subscribers {
+		if sub.ID != userID {
+			subs = append(subs, sub)
+		}
+	}
+	removed := len(subs) != len(tag.Subscribers)
+	tag.Subscribers = subs
+	if removed {
+		t.index.Unsubscribe(tag, userID)
+	}
+	return removed
+}
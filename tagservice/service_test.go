@@ -0,0 +1,69 @@
+package tagservice
+
+import (
+	"testing"
+	"time"
+
+	"tagger/storage"
+)
+
+func TestFindPrefersChatThenChatlessThenCommunity(t *testing.T) {
+	local := &storage.Tag{Name: "raid", ChatID: -1}
+	chatless := &storage.Tag{Name: "news"}
+	shared := &storage.Tag{Name: "event", ChatID: -2, Global: true}
+	private := &storage.Tag{Name: "secret", ChatID: -2}
+	d := &storage.Data{
+		Tags: []*storage.Tag{local, chatless, shared, private},
+		Chats: map[int64]*storage.ChatSettings{
+			-1: {Community: "guild"},
+			-2: {Community: "guild"},
+		},
+	}
+	s := New(d)
+	if s.Find(-1, "RAID") != local || s.Find(-1, "news") != chatless || s.Find(-1, "event") != shared {
+		t.Fatal("lookup order broken")
+	}
+	if s.Find(-1, "secret") != nil {
+		t.Fatal("non-global tag leaked into a linked chat")
+	}
+	if got := s.InChat(-1); len(got) != 3 {
+		t.Fatalf("InChat returned %d tags, want 3", len(got))
+	}
+}
+
+func TestSubscribeKeepsIndexInSync(t *testing.T) {
+	tag := &storage.Tag{Name: "raid", ChatID: -1, Subscribers: []storage.Subscriber{}, CreatedAt: time.Now()}
+	s := New(&storage.Data{Tags: []*storage.Tag{tag}})
+	s.Subscribe(tag, storage.Subscriber{ID: 7})
+	if subs := s.Subscriptions(7); len(subs) != 1 || subs[0] != tag || len(tag.Subscribers) != 1 {
+		t.Fatal("subscription not recorded")
+	}
+	if !s.Unsubscribe(tag, 7) || len(s.Subscriptions(7)) != 0 || len(tag.Subscribers) != 0 {
+		t.Fatal("unsubscribe left the user behind")
+	}
+	if s.Unsubscribe(tag, 7) {
+		t.Fatal("second unsubscribe reported a removal")
+	}
+}
+
+func TestPrivateLookupOnlySeesMemberChats(t *testing.T) {
+	mine := &storage.Tag{Name: "raid", ChatID: -1, Subscribers: []storage.Subscriber{{ID: 7}}}
+	other := &storage.Tag{Name: "raid", ChatID: -2}
+	hidden := &storage.Tag{Name: "secret", ChatID: -3}
+	s := New(&storage.Data{Tags: []*storage.Tag{mine, other, hidden}})
+	if s.Find(7, "raid") != mine {
+		t.Fatal("tag from the user's chat not found")
+	}
+	if s.Find(7, "secret") != nil || s.Find(8, "raid") != nil {
+		t.Fatal("private lookup reached a chat the user is not in")
+	}
+	if got := s.InChat(7); len(got) != 1 || got[0] != mine {
+		t.Fatalf("InChat(7) = %v", got)
+	}
+
+	twin := &storage.Tag{Name: "raid", ChatID: -4, Subscribers: []storage.Subscriber{{ID: 7}}}
+	s = New(&storage.Data{Tags: []*storage.Tag{mine, twin}})
+	if s.Find(7, "raid") != nil || len(s.Matches(7, "raid")) != 2 {
+		t.Fatal("duplicate names must not resolve silently")
+	}
+}
@@ -0,0 +1,124 @@
+package telefake
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var Bot = offlineBot()
+
+type offline struct{}
+
+func (offline) RoundTrip(*http.Request) (*http.Response, error) {
+	return nil, errors.New("telefake: no network")
+}
+
+func offlineBot() *tele.Bot {
+	b, err := tele.NewBot(tele.Settings{
+		Token:     "telefake",
+		Offline:   true,
+		ParseMode: tele.ModeHTML,
+		Client:    &http.Client{Transport: offline{}},
+	})
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type Sent struct {
+	What interface{}
+	Opts []interface{}
+}
+
+func (s Sent) Text() string {
+	text, _ := s.What.(string)
+	return text
+}
+
+type Context struct {
+	tele.Context
+	Sent      []Sent
+	Edits     []Sent
+	Responses []*tele.CallbackResponse
+	Deleted   bool
+}
+
+func New(u tele.Update) *Context {
+	return &Context{Context: Bot.NewContext(u)}
+}
+
+func Message(chat *tele.Chat, from *tele.User, text string) *Context {
+	msg := &tele.Message{ID: 1, Sender: from, Chat: chat, Text: text, Unixtime: time.Now().Unix()}
+	if strings.HasPrefix(text, "/") {
+		_, msg.Payload, _ = strings.Cut(text, " ")
+		msg.Payload = strings.TrimSpace(msg.Payload)
+	}
+	return New(tele.Update{Message: msg})
+}
+
+func Callback(chat *tele.Chat, from *tele.User, unique, data string) *Context {
+	msg := &tele.Message{ID: 1, Chat: chat, Unixtime: time.Now().Unix()}
+	return New(tele.Update{Callback: &tele.Callback{ID: "1", Sender: from, Message: msg, Unique: unique, Data: data}})
+}
+
+func (c *Context) Send(what interface{}, opts ...interface{}) error {
+	c.Sent = append(c.Sent, Sent{What: what, Opts: opts})
+	return nil
+}
+
+func (c *Context) Reply(what interface{}, opts ...interface{}) error {
+	return c.Send(what, opts...)
+}
+
+func (c *Context) Edit(what interface{}, opts ...interface{}) error {
+	c.Edits = append(c.Edits, Sent{What: what, Opts: opts})
+	return nil
+}
+
+func (c *Context) EditOrSend(what interface{}, opts ...interface{}) error {
+	if c.Callback() != nil {
+		return c.Edit(what, opts...)
+	}
+	return c.Send(what, opts...)
+}
+
+func (c *Context) EditOrReply(what interface{}, opts ...interface{}) error {
+	return c.EditOrSend(what, opts...)
+}
+
+func (c *Context) Delete() error {
+	c.Deleted = true
+	return nil
+}
+
+func (c *Context) Notify(tele.ChatAction) error {
+	return nil
+}
+
+func (c *Context) Respond(resp ...*tele.CallbackResponse) error {
+	if len(resp) == 0 {
+		resp = []*tele.CallbackResponse{{}}
+	}
+	c.Responses = append(c.Responses, resp...)
+	return nil
+}
+
+func (c *Context) RespondText(text string) error {
+	return c.Respond(&tele.CallbackResponse{Text: text})
+}
+
+func (c *Context) RespondAlert(text string) error {
+	return c.Respond(&tele.CallbackResponse{Text: text, ShowAlert: true})
+}
+
+func (c *Context) LastText() string {
+	if len(c.Sent) == 0 {
+		return ""
+	}
+	return c.Sent[len(c.Sent)-1].Text()
+}
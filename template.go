@@ -0,0 +1,57 @@
+package main
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const maxTemplateLen = 500
+
+func renderTemplate(c tele.Context, tag *Tag, mentions []string, text string) string {
+	joined := strings.Join(mentions, " ")
+	out := strings.NewReplacer(
+		"{mentions}", joined,
+		"{tag}", esc(tag.Name),
+		"{emoji}", tag.Emoji,
+		"{sender}", esc(c.Sender().Username),
+		"{text}", esc(text),
+		"{link}", triggerLink(c),
+	).Replace(esc(tag.Template))
+	if !strings.Contains(tag.Template, "{mentions}") {
+		out = joined + "\n" + out
+	}
+	return out
+}
+
+func handleTemplate(c tele.Context) error {
+	name, template, _ := strings.Cut(strings.TrimSpace(c.Message().Payload), " ")
+	if name == "" {
+		return c.Send(T(c, "template.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, strings.TrimPrefix(name, "#"))
+	if tag == nil {
+		return tagNotFound(c, strings.TrimPrefix(name, "#"))
+	}
+	template = strings.TrimSpace(template)
+	if template == "" {
+		if tag.Template == "" {
+			return c.Send(T(c, "template.none", esc(tag.Name)))
+		}
+		return c.Send(T(c, "template.current", esc(tag.Name), esc(tag.Template)))
+	}
+	if strings.EqualFold(template, "off") {
+		tag.Template = ""
+		saveTag("update", tag)
+		return c.Send(T(c, "template.reset", esc(tag.Name)))
+	}
+	if len([]rune(template)) > maxTemplateLen {
+		return c.Send(T(c, "template.too_long", maxTemplateLen))
+	}
+	if word := blockedWord(c.Chat().ID, template); word != "" {
+		return c.Send(T(c, "blockword.rejected"))
+	}
+	tag.Template = template
+	saveTag("update", tag)
+	return c.Send(T(c, "template.set", esc(tag.Name), esc(template)))
+}
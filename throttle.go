@@ -0,0 +1,71 @@
+package main
+
+import (
+	"log/slog"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const throttleWindow = time.Minute
+
+var userRateLimit = 20
+
+type userWindow struct {
+	start  time.Time
+	count  int
+	warned bool
+}
+
+var userWindows = map[int64]*userWindow{}
+
+func throttledUpdate(c tele.Context) bool {
+	if c.Callback() != nil {
+		return true
+	}
+	return c.Message() != nil && strings.HasPrefix(c.Text(), "/")
+}
+
+func allowUser(userID int64, now time.Time) (allowed, warn bool) {
+	w := userWindows[userID]
+	if w == nil || now.Sub(w.start) >= throttleWindow {
+		if len(userWindows) > 10000 {
+			for id, old := range userWindows {
+				if now.Sub(old.start) >= throttleWindow {
+					delete(userWindows, id)
+				}
+			}
+		}
+		userWindows[userID] = &userWindow{start: now, count: 1}
+		return true, false
+	}
+	w.count++
+	if w.count <= userRateLimit {
+		return true, false
+	}
+	warn = !w.warned
+	w.warned = true
+	return false, warn
+}
+
+func throttled(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		user := c.Sender()
+		if user == nil || !throttledUpdate(c) || isBotOwner(c) {
+			return next(c)
+		}
+		allowed, warn := allowUser(user.ID, time.Now())
+		if allowed {
+			return next(c)
+		}
+		if c.Callback() != nil {
+			return c.Respond(&tele.CallbackResponse{Text: T(c, "throttle.slow_down")})
+		}
+		if warn {
+			slog.Warn("Пользователь упёрся в лимит команд", contextAttrs(c)...)
+			return c.Reply(T(c, "throttle.slow_down"))
+		}
+		return nil
+	}
+}
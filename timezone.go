@@ -0,0 +1,111 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	_ "time/tzdata"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+var locations sync.Map
+
+func normalizeTimezone(name string) (string, bool) {
+	upper := strings.ToUpper(strings.TrimSpace(name))
+	for _, prefix := range []string{"UTC", "GMT"} {
+		offset, ok := strings.CutPrefix(upper, prefix)
+		if !ok {
+			continue
+		}
+		if offset == "" || offset == "+0" || offset == "-0" {
+			return "UTC", true
+		}
+		hours, err := strconv.Atoi(offset)
+		if err != nil || hours < -12 || hours > 14 {
+			return "", false
+		}
+		if hours > 0 {
+			return "Etc/GMT-" + strconv.Itoa(hours), true
+		}
+		return "Etc/GMT+" + strconv.Itoa(-hours), true
+	}
+	loc, err := time.LoadLocation(strings.TrimSpace(name))
+	if err != nil || loc.String() == "Local" {
+		return "", false
+	}
+	return loc.String(), true
+}
+
+func location(name string) *time.Location {
+	if name == "" {
+		return time.Local
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return time.Local
+	}
+	locations.Store(name, loc)
+	return loc
+}
+
+func chatLocation(chatID int64) *time.Location {
+	if s := data.Chats[chatID]; s != nil {
+		return location(s.Timezone)
+	}
+	return time.Local
+}
+
+func userLocation(userID int64) *time.Location {
+	if p := data.Users[userID]; p != nil && p.Timezone != "" {
+		return location(p.Timezone)
+	}
+	return time.Local
+}
+
+func zoneOf(c tele.Context) *time.Location {
+	chat := c.Chat()
+	if chat != nil && chat.Type != tele.ChatPrivate {
+		return chatLocation(chat.ID)
+	}
+	if c.Sender() != nil {
+		return userLocation(c.Sender().ID)
+	}
+	return time.Local
+}
+
+func handleTimezone(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	private := c.Chat().Type == tele.ChatPrivate
+	if len(args) == 0 {
+		zone := zoneOf(c).String()
+		if private {
+			return c.Send(T(c, "tz.user_current", esc(zone)))
+		}
+		return c.Send(T(c, "tz.chat_current", esc(zone)))
+	}
+	if !private && !isChatAdmin(c, c.Chat().ID) {
+		return c.Send(T(c, "tz.denied"))
+	}
+	zone := ""
+	if strings.ToLower(args[0]) != "reset" {
+		var ok bool
+		if zone, ok = normalizeTimezone(args[0]); !ok {
+			return c.Send(T(c, "tz.unknown"))
+		}
+	}
+	if private {
+		userPrefs(c.Sender().ID).Timezone = zone
+	} else {
+		chatSettings(c.Chat().ID).Timezone = zone
+	}
+	saveData()
+	if zone == "" {
+		return c.Send(T(c, "tz.reset"))
+	}
+	return c.Send(T(c, "tz.set", esc(zone), time.Now().In(location(zone)).Format("15:04")))
+}
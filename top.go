@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const topSize = 5
+
+var topPeriods = map[string]time.Duration{
+	"week":  7 * 24 * time.Hour,
+	"month": mentionRetention,
+	"all":   0,
+}
+
+var medals = []string{"🥇", "🥈", "🥉"}
+
+func rankPrefix(i int) string {
+	if i < len(medals) {
+		return medals[i]
+	}
+	return fmt.Sprintf("%d.", i+1)
+}
+
+func writeRanking(b *strings.Builder, c tele.Context, header string, rows []ranked, unit string) {
+	b.WriteString(T(c, header))
+	if len(rows) == 0 {
+		b.WriteString(T(c, "top.none"))
+		return
+	}
+	for i, r := range rows {
+		b.WriteString(fmt.Sprintf("%s %s — %s\n", rankPrefix(i), esc(r.name), T(c, unit, r.count)))
+	}
+}
+
+func displayName(userID int64, fallback string) string {
+	if fallback != "" {
+		return fallback
+	}
+	if name := usernameOf(userID); name != "" {
+		return name
+	}
+	return fmt.Sprintf("User%d", userID)
+}
+
+func handleTop(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "top.groups_only"))
+	}
+	period := "week"
+	if args := strings.Fields(c.Text())[1:]; len(args) > 0 {
+		period = strings.ToLower(args[0])
+	}
+	window, ok := topPeriods[period]
+	if !ok {
+		return c.Send(T(c, "top.usage"))
+	}
+	var since time.Time
+	if window > 0 {
+		since = time.Now().Add(-window)
+	}
+	chatID := c.Chat().ID
+
+	subscribed, creators, pingers := map[string]int{}, map[string]int{}, map[string]int{}
+	visible := map[string]bool{}
+	for _, tag := range tagService.InChat(chatID) {
+		if !visibleTo(tag, c.Sender().ID) {
+			continue
+		}
+		visible[strings.ToLower(tag.Name)] = true
+		subscribed["#"+tag.Name] = len(tag.Subscribers)
+		if tag.CreatedAt.After(since) {
+			creators[displayName(tag.CreatorID, tag.CreatorName)]++
+		}
+		if window == 0 {
+			for userID, n := range tag.Stats.ByUser {
+				pingers[displayName(userID, "")] += n
+			}
+		}
+	}
+	if window > 0 {
+		for _, ev := range data.Mentions {
+			if ev.ChatID == chatID && ev.At.After(since) && visible[strings.ToLower(ev.Tag)] {
+				pingers[displayName(ev.UserID, ev.Username)]++
+			}
+		}
+	}
+	if len(subscribed) == 0 {
+		return c.Send(T(c, "top.empty"))
+	}
+
+	var b strings.Builder
+	b.WriteString(T(c, "top.header", T(c, "top.period_"+period)))
+	writeRanking(&b, c, "top.subscribed", topOf(subscribed, topSize), "top.subs_unit")
+	writeRanking(&b, c, "top.creators", topOf(creators, topSize), "top.tags_unit")
+	writeRanking(&b, c, "top.pingers", topOf(pingers, topSize), "top.pings_unit")
+	return c.Send(b.String())
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"strings"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+type topicContext struct {
+	tele.Context
+	threadID int
+}
+
+func (c topicContext) Send(what interface{}, opts ...interface{}) error {
+	return c.Context.Send(what, withThread(c.threadID, opts)...)
+}
+
+func withThread(threadID int, opts []interface{}) []interface{} {
+	for i, opt := range opts {
+		if so, ok := opt.(*tele.SendOptions); ok {
+			copied := *so
+			copied.ThreadID = threadID
+			out := append([]interface{}{}, opts...)
+			out[i] = &copied
+			return out
+		}
+	}
+	return append([]interface{}{&tele.SendOptions{ParseMode: tele.ModeHTML, ThreadID: threadID}}, opts...)
+}
+
+func threadOf(c tele.Context) int {
+	if m := c.Message(); m != nil && m.TopicMessage {
+		return m.ThreadID
+	}
+	return 0
+}
+
+func sendOptions(c tele.Context) *tele.SendOptions {
+	return &tele.SendOptions{ParseMode: tele.ModeHTML, ThreadID: threadOf(c)}
+}
+
+func topicAware(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		if thread := threadOf(c); thread != 0 {
+			c = topicContext{Context: c, threadID: thread}
+		}
+		return next(c)
+	}
+}
+
+func allowedInTopic(tag *Tag, threadID int) bool {
+	if len(tag.Topics) == 0 {
+		return true
+	}
+	for _, t := range tag.Topics {
+		if t == threadID {
+			return true
+		}
+	}
+	return false
+}
+
+func handleTopic(c tele.Context) error {
+	args := strings.Fields(c.Text())[1:]
+	if len(args) < 2 {
+		return c.Send(T(c, "topic.usage"))
+	}
+	tag := tagService.Find(c.Chat().ID, args[0])
+	if tag == nil {
+		return tagNotFound(c, args[0])
+	}
+	switch strings.ToLower(args[1]) {
+	case "here":
+		thread := threadOf(c)
+		if len(tag.Topics) == 0 || !allowedInTopic(tag, thread) {
+			tag.Topics = append(tag.Topics, thread)
+		}
+		saveTag("update", tag)
+		return c.Send(T(c, "topic.added", esc(tag.Name)))
+	case "all":
+		tag.Topics = nil
+		saveTag("update", tag)
+		return c.Send(T(c, "topic.cleared", esc(tag.Name)))
+	}
+	return c.Send(T(c, "topic.usage"))
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	traceContextKey = "trace_ctx"
+	otlpTimeout     = 10 * time.Second
+)
+
+var (
+	tracer         = otel.Tracer("chinatagger")
+	tracerProvider *sdktrace.TracerProvider
+)
+
+func otlpHeaders(raw string) map[string]string {
+	headers := map[string]string{}
+	for _, pair := range strings.Split(raw, ",") {
+		if k, v, ok := strings.Cut(pair, "="); ok {
+			headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return headers
+}
+
+func setupTracing() {
+	endpoint := cfg.Telemetry.OTLPEndpoint
+	if endpoint == "" {
+		return
+	}
+	service := cfg.Telemetry.ServiceName
+	url := strings.TrimRight(endpoint, "/") + "/v1/traces"
+	exporter, err := otlptracehttp.New(context.Background(),
+		otlptracehttp.WithEndpointURL(url),
+		otlptracehttp.WithHeaders(otlpHeaders(cfg.Telemetry.OTLPHeaders)),
+		otlptracehttp.WithTimeout(otlpTimeout))
+	if err != nil {
+		slog.Error("Не удалось настроить трассировку", "endpoint", url, "err", err)
+		return
+	}
+	tracerProvider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(resource.NewSchemaless(attribute.String("service.name", service))))
+	otel.SetTracerProvider(tracerProvider)
+	slog.Info("🔭 Трассировка включена", "endpoint", url, "service", service)
+}
+
+func shutdownTracing() {
+	if tracerProvider == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), otlpTimeout)
+	defer cancel()
+	if err := tracerProvider.Shutdown(ctx); err != nil {
+		slog.Warn("Не удалось выгрузить трассировки", "err", err)
+	}
+}
+
+func traceContext(c tele.Context) context.Context {
+	if ctx, ok := c.Get(traceContextKey).(context.Context); ok {
+		return ctx
+	}
+	return context.Background()
+}
+
+func traceUpdate(next tele.HandlerFunc) tele.HandlerFunc {
+	return func(c tele.Context) error {
+		name := commandOf(c)
+		if name == "" {
+			name = "message"
+		}
+		attrs := []attribute.KeyValue{attribute.String("command", commandOf(c))}
+		if chat := c.Chat(); chat != nil {
+			attrs = append(attrs, attribute.Int64("chat_id", chat.ID))
+		}
+		if user := c.Sender(); user != nil {
+			attrs = append(attrs, attribute.Int64("user_id", user.ID))
+		}
+		ctx, span := tracer.Start(context.Background(), "update "+name,
+			trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attrs...))
+		defer span.End()
+		c.Set(traceContextKey, ctx)
+		err := next(c)
+		endSpan(span, err)
+		return err
+	}
+}
+
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+const undoWindow = 5 * time.Minute
+
+var undoable = map[string]bool{"delete": true, "unsubscribe": true, "kick": true}
+
+func lastUndoable(userID, chatID int64) (*storage.JournalEntry, error) {
+	journalMu.Lock()
+	entries, err := storage.ReadJournal(journalFile)
+	journalMu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	undone := map[int64]bool{}
+	for _, entry := range entries {
+		if entry.Undo != 0 {
+			undone[entry.Undo] = true
+		}
+	}
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if time.Since(entry.At) > undoWindow {
+			break
+		}
+		if entry.By != userID || !undoable[entry.Op] || undone[entry.Seq] {
+			continue
+		}
+		if chatID < 0 && entry.Tag.ChatID != chatID {
+			continue
+		}
+		return &entry, nil
+	}
+	return nil, nil
+}
+
+func liveTag(snapshot *Tag) *Tag {
+	for _, tag := range data.Tags {
+		if storage.SameTag(tag, snapshot) {
+			return tag
+		}
+	}
+	return nil
+}
+
+func handleUndo(c tele.Context) error {
+	entry, err := lastUndoable(c.Sender().ID, c.Chat().ID)
+	if err != nil {
+		slog.Warn("Не удалось прочитать журнал для отмены", "err", err)
+		return c.Send(T(c, "undo.failed"))
+	}
+	if entry == nil {
+		return c.Send(T(c, "undo.nothing", int(undoWindow.Minutes())))
+	}
+	tag := liveTag(entry.Tag)
+	if tag == nil {
+		return c.Send(T(c, "undo.gone", esc(entry.Tag.Name)))
+	}
+	undo := storage.JournalEntry{Tag: tag, By: c.Sender().ID, Undo: entry.Seq}
+	switch entry.Op {
+	case "delete":
+		if tag.DeletedAt == nil {
+			return c.Send(T(c, "undo.gone", esc(tag.Name)))
+		}
+		if tagService.Find(tag.ChatID, tag.Name) != nil {
+			return c.Send(T(c, "restore.conflict"))
+		}
+		tag.DeletedAt = nil
+		tag.DeletedBy = 0
+		tagService.Add(tag)
+		undo.Op = "restore"
+		journalEntry(undo)
+		saveData()
+		return c.Send(T(c, "undo.restored", esc(tag.Name), len(tag.Subscribers)))
+	default:
+		sub := *entry.Sub
+		if !tag.Active() || isBanned(tag, sub.ID) {
+			return c.Send(T(c, "undo.gone", esc(tag.Name)))
+		}
+		if !isSubscribed(tag, sub.ID) {
+			tagService.Subscribe(tag, sub)
+			undo.Op, undo.Sub = "subscribe", &sub
+			journalEntry(undo)
+			saveData()
+			notifyMembership(traceContext(c), tag, sub, true)
+		}
+		return c.Send(T(c, "undo.resubscribed", esc(sub.Username), esc(tag.Name)))
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"runtime/debug"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+
+	"tagger/storage"
+)
+
+// Set at build time:
+//
+//	go build -ldflags "-X main.version=v1.2.0 -X main.commit=$(git rev-parse --short HEAD)"
+var (
+	version = "dev"
+	commit  = ""
+)
+
+var startedAt = time.Now()
+
+func buildCommit() string {
+	if commit != "" {
+		return commit
+	}
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			if s.Key == "vcs.revision" && len(s.Value) >= 7 {
+				return s.Value[:7]
+			}
+		}
+	}
+	return "unknown"
+}
+
+func storageBackend() string {
+	var name string
+	switch store.(type) {
+	case *storage.Postgres:
+		name = "postgres"
+	case *storage.Shards:
+		name = "shards " + cfg.Storage.Dir
+	default:
+		name = "file " + cfg.Storage.File
+	}
+	if storage.Encrypting() {
+		name += ", encrypted"
+	}
+	return name
+}
+
+func formatUptime(d time.Duration) string {
+	d = d.Round(time.Second)
+	days := int(d / (24 * time.Hour))
+	d -= time.Duration(days) * 24 * time.Hour
+	if days > 0 {
+		return fmt.Sprintf("%dd %s", days, d)
+	}
+	return d.String()
+}
+
+func handleVersion(c tele.Context) error {
+	full, err := allData()
+	if err != nil {
+		return err
+	}
+	active, archived, deleted := 0, 0, 0
+	for _, tag := range full.Tags {
+		switch {
+		case tag.DeletedAt != nil:
+			deleted++
+		case tag.ArchivedAt != nil:
+			archived++
+		default:
+			active++
+		}
+	}
+	return c.Send(T(c, "version.text", esc(version), esc(buildCommit()), formatUptime(time.Since(startedAt)),
+		runtime.Version(), active, archived, deleted, esc(storageBackend())))
+}
+
+func handleUptime(c tele.Context) error {
+	return c.Send(T(c, "version.uptime", formatUptime(time.Since(startedAt)), startedAt.In(zoneOf(c)).Format("02.01.2006 15:04")))
+}
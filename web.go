@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/hex"
+	"encoding/json"
+	"html/template"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+//go:embed web/index.html
+var dashboardHTML string
+
+var dashboardTmpl = template.Must(template.New("dashboard").Parse(dashboardHTML))
+
+const (
+	sessionCookie = "ct_session"
+	sessionTTL    = 24 * time.Hour
+	loginMaxAge   = 24 * time.Hour
+)
+
+var (
+	webBot   *tele.Bot
+	botToken string
+)
+
+type webSession struct {
+	UserID   int64  `json:"user_id"`
+	Username string `json:"username"`
+}
+
+type apiSubscription struct {
+	ChatID      int64  `json:"chat_id"`
+	ChatTitle   string `json:"chat_title,omitempty"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Delivery    string `json:"delivery,omitempty"`
+}
+
+type apiChat struct {
+	ID    int64  `json:"id"`
+	Title string `json:"title"`
+}
+
+func checkTelegramLogin(q url.Values) (webSession, bool) {
+	hash := q.Get("hash")
+	if hash == "" || botToken == "" {
+		return webSession{}, false
+	}
+	var lines []string
+	for key := range q {
+		if key != "hash" {
+			lines = append(lines, key+"="+q.Get(key))
+		}
+	}
+	sort.Strings(lines)
+	secret := sha256.Sum256([]byte(botToken))
+	mac := hmac.New(sha256.New, secret[:])
+	mac.Write([]byte(strings.Join(lines, "\n")))
+	if !hmac.Equal([]byte(hex.EncodeToString(mac.Sum(nil))), []byte(hash)) {
+		return webSession{}, false
+	}
+	authDate, err := strconv.ParseInt(q.Get("auth_date"), 10, 64)
+	if err != nil || time.Since(time.Unix(authDate, 0)) > loginMaxAge {
+		return webSession{}, false
+	}
+	id, err := strconv.ParseInt(q.Get("id"), 10, 64)
+	if err != nil {
+		return webSession{}, false
+	}
+	username := q.Get("username")
+	if username == "" {
+		username = "User" + q.Get("id")
+	}
+	return webSession{UserID: id, Username: username}, true
+}
+
+func sessionOf(r *http.Request) (webSession, bool) {
+	var s webSession
+	cookie, err := r.Cookie(sessionCookie)
+	if err != nil {
+		return s, false
+	}
+	raw, ok, err := kv.Get("session:" + cookie.Value)
+	if err != nil || !ok || json.Unmarshal(raw, &s) != nil {
+		return s, false
+	}
+	return s, true
+}
+
+func isWebAdmin(ctx context.Context, s webSession, chatID int64) bool {
+	if chatID == s.UserID || (ownerID != 0 && s.UserID == ownerID) {
+		return true
+	}
+	if chatID >= 0 || webBot == nil {
+		return false
+	}
+	admins, err := chatAdmins(ctx, webBot, &tele.Chat{ID: chatID})
+	return err == nil && admins[s.UserID]
+}
+
+// knownChats lists the chats where the user subscribes to, moderates or created
+// a tag; only these are worth asking Telegram about the user's admin rights.
+func knownChats(userID int64) map[int64]bool {
+	known := map[int64]bool{}
+	for _, tag := range data.Tags {
+		if tag.CreatorID == userID || isSubscribed(tag, userID) || isModerator(tag, userID) {
+			known[tag.ChatID] = true
+		}
+	}
+	return known
+}
+
+func adminChatsOf(ctx context.Context, s webSession, chats []apiChat) []apiChat {
+	admin := []apiChat{}
+	for _, chat := range chats {
+		if isWebAdmin(ctx, s, chat.ID) {
+			admin = append(admin, chat)
+		}
+	}
+	return admin
+}
+
+func handleLogin(w http.ResponseWriter, r *http.Request) {
+	s, ok := checkTelegramLogin(r.URL.Query())
+	if !ok {
+		http.Error(w, "invalid login", http.StatusUnauthorized)
+		return
+	}
+	token := newToken()
+	raw, _ := json.Marshal(s)
+	if err := kv.Set("session:"+token, raw, sessionTTL); err != nil {
+		http.Error(w, "session unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     sessionCookie,
+		Value:    token,
+		Path:     "/",
+		MaxAge:   int(sessionTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   r.TLS != nil,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, "/", http.StatusFound)
+}
+
+func handleLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(sessionCookie); err == nil {
+		kv.Delete("session:" + cookie.Value)
+	}
+	http.SetCookie(w, &http.Cookie{Name: sessionCookie, Path: "/", MaxAge: -1})
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	username := ""
+	if webBot != nil {
+		username = webBot.Me.Username
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	dashboardTmpl.Execute(w, struct{ Bot string }{username})
+}
+
+func apiMe(w http.ResponseWriter, r *http.Request) {
+	s, ok := sessionOf(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	dataMu.Lock()
+	if err := loadChats(chatsOf(s.UserID)...); err != nil {
+		dataMu.Unlock()
+		writeError(w, http.StatusInternalServerError, "storage error")
+		return
+	}
+	subs := []apiSubscription{}
+	for _, tag := range tagService.Subscriptions(s.UserID) {
+		sub := apiSubscription{ChatID: tag.ChatID, Name: tag.Name, Description: tag.Description}
+		if settings := data.Chats[tag.ChatID]; settings != nil {
+			sub.ChatTitle = settings.Title
+		}
+		for _, one := range tag.Subscribers {
+			if one.ID == s.UserID {
+				sub.Delivery = one.Delivery
+			}
+		}
+		subs = append(subs, sub)
+	}
+	chats := []apiChat{}
+	known := knownChats(s.UserID)
+	for _, id := range groupChats() {
+		if known[id] || (ownerID != 0 && s.UserID == ownerID) {
+			chats = append(chats, apiChat{ID: id, Title: data.Chats[id].Title})
+		}
+	}
+	langs := languages()
+	dataMu.Unlock()
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"id":            s.UserID,
+		"username":      s.Username,
+		"subscriptions": subs,
+		"chats":         adminChatsOf(r.Context(), s, chats),
+		"languages":     langs,
+	})
+}
+
+func apiUnsubscribeMe(w http.ResponseWriter, r *http.Request) {
+	s, ok := sessionOf(r)
+	if !ok {
+		writeError(w, http.StatusUnauthorized, "unauthorized")
+		return
+	}
+	chatID, err := strconv.ParseInt(r.PathValue("chat"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "bad chat id")
+		return
+	}
+	dataMu.Lock()
+	defer dataMu.Unlock()
+	if readOnly {
+		writeError(w, http.StatusServiceUnavailable, "read-only mode")
+		return
+	}
+	if err := loadChats(chatID); err != nil {
+		writeError(w, http.StatusInternalServerError, "storage error")
+		return
+	}
+	tag := tagService.Local(chatID, r.PathValue("name"))
+	if tag == nil {
+		writeError(w, http.StatusNotFound, "not subscribed")
+		return
+	}
+	sub, ok := subscriberOf(tag, s.UserID)
+	if !ok || !tagService.Unsubscribe(tag, s.UserID) {
+		writeError(w, http.StatusNotFound, "not subscribed")
+		return
+	}
+	saveAction("unsubscribe", tag, s.UserID, &sub)
+	notifyMembership(r.Context(), tag, sub, false)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func apiGetSettings(w http.ResponseWriter, r *http.Request, chatID int64) {
+	title := ""
+	if s := data.Chats[chatID]; s != nil {
+		title = s.Title
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"lang":  chatLang(chatID),
+		"title": title,
+	})
+}
+
+func apiPutSettings(w http.ResponseWriter, r *http.Request, chatID int64) {
+	var req struct {
+		Lang string `json:"lang"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "bad settings")
+		return
+	}
+	if _, ok := catalogs[req.Lang]; !ok {
+		writeError(w, http.StatusBadRequest, "unknown language")
+		return
+	}
+	chatSettings(chatID).Lang = req.Lang
+	saveData()
+	apiGetSettings(w, r, chatID)
+}
+
+func webRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /{$}", handleDashboard)
+	mux.HandleFunc("GET /auth/telegram", handleLogin)
+	mux.HandleFunc("POST /auth/logout", handleLogout)
+	mux.HandleFunc("GET /api/me", apiMe)
+	mux.HandleFunc("DELETE /api/me/subscriptions/{chat}/{name}", apiUnsubscribeMe)
+	mux.HandleFunc("GET /api/chats/{chat}/settings", apiRoute(apiGetSettings))
+	mux.HandleFunc("PUT /api/chats/{chat}/settings", apiRoute(apiPutSettings))
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	eventTagMentioned   = "tag.mentioned"
+	eventTagCreated     = "tag.created"
+	eventSubscriberJoin = "subscriber.joined"
+)
+
+var (
+	webhookAttempts = 3
+	webhookClient   = &http.Client{Timeout: 10 * time.Second, Transport: publicOnlyTransport()}
+
+	errPrivateAddress = errors.New("webhook points to a private address")
+)
+
+func publicIP(ip net.IP) bool {
+	return !ip.IsLoopback() && !ip.IsPrivate() && !ip.IsUnspecified() &&
+		!ip.IsLinkLocalUnicast() && !ip.IsLinkLocalMulticast() && !ip.IsMulticast()
+}
+
+func publicOnlyTransport() *http.Transport {
+	dialer := &net.Dialer{
+		Timeout: 10 * time.Second,
+		Control: func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+				return errPrivateAddress
+			}
+			return nil
+		},
+	}
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = nil
+	t.DialContext = dialer.DialContext
+	return t
+}
+
+func checkWebhookHost(ctx context.Context, host string) error {
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, host)
+	if err != nil {
+		return err
+	}
+	for _, addr := range addrs {
+		if !publicIP(addr.IP) {
+			return errPrivateAddress
+		}
+	}
+	return nil
+}
+
+type webhookEvent struct {
+	Event  string      `json:"event"`
+	ChatID int64       `json:"chat_id"`
+	Tag    string      `json:"tag"`
+	User   *Subscriber `json:"user,omitempty"`
+	Text   string      `json:"text,omitempty"`
+	At     time.Time   `json:"at"`
+}
+
+func signPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func emitEvent(chatID int64, event string, tag *Tag, user *Subscriber, text string) {
+	s := data.Chats[chatID]
+	if s == nil || s.Webhook == nil {
+		return
+	}
+	hook := *s.Webhook
+	body, _ := json.Marshal(webhookEvent{
+		Event:  event,
+		ChatID: chatID,
+		Tag:    tag.Name,
+		User:   user,
+		Text:   text,
+		At:     time.Now(),
+	})
+	go deliverWebhook(hook, event, body)
+}
+
+func deliverWebhook(hook Webhook, event string, body []byte) {
+	var err error
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt*attempt) * time.Second)
+		}
+		if err = postWebhook(hook, event, body); err == nil {
+			return
+		}
+	}
+	slog.Warn("Не удалось доставить вебхук", "url", hook.URL, "event", event, "err", err)
+}
+
+func postWebhook(hook Webhook, event string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-ChinaTagger-Event", event)
+	req.Header.Set("X-ChinaTagger-Signature", signPayload(hook.Secret, body))
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func handleWebhook(c tele.Context) error {
+	if !canAdminChat(c) {
+		return c.Send(T(c, "webhook.denied"))
+	}
+	args := strings.Fields(c.Text())[1:]
+	s := chatSettings(c.Chat().ID)
+	if len(args) == 0 {
+		if s.Webhook == nil {
+			return c.Send(T(c, "webhook.none"))
+		}
+		return c.Send(T(c, "webhook.current", esc(s.Webhook.URL)))
+	}
+	if strings.ToLower(args[0]) == "off" {
+		if s.Webhook == nil {
+			return c.Send(T(c, "webhook.none"))
+		}
+		s.Webhook = nil
+		saveData()
+		return c.Send(T(c, "webhook.removed"))
+	}
+	u, err := url.Parse(args[0])
+	if err != nil || u.Scheme != "https" || u.Host == "" {
+		return c.Send(T(c, "webhook.usage"))
+	}
+	hook := &Webhook{URL: u.String(), Secret: newToken(), By: c.Sender().ID}
+	ctx, chat, lang := traceContext(c), c.Chat(), langOf(c)
+	go func() {
+		err := checkWebhookHost(ctx, u.Hostname())
+		if errors.Is(err, errPrivateAddress) {
+			sendQueue.send(ctx, chat, tr(lang, "webhook.private"))
+			return
+		}
+		if err != nil {
+			sendQueue.send(ctx, chat, tr(lang, "webhook.unresolved", esc(u.Hostname())))
+			return
+		}
+		sendQueue.sendThen(c, c.Sender(), tr(lang, "webhook.secret_dm", esc(hook.URL), hook.Secret), func(_ *tele.Message, err error) error {
+			if err != nil {
+				return c.Send(T(c, "dm_failed"))
+			}
+			chatSettings(chat.ID).Webhook = hook
+			saveData()
+			return c.Send(T(c, "webhook.set", esc(hook.URL)))
+		})
+	}()
+	return nil
+}
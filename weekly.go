@@ -0,0 +1,217 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	tele "gopkg.in/telebot.v3"
+)
+
+const (
+	weeklyTop         = 5
+	weeklyDefaultDay  = time.Monday
+	weeklyDefaultHour = 10
+)
+
+var weekdayNames = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "wed": time.Wednesday,
+	"thu": time.Thursday, "fri": time.Friday, "sat": time.Saturday,
+	"вс": time.Sunday, "пн": time.Monday, "вт": time.Tuesday, "ср": time.Wednesday,
+	"чт": time.Thursday, "пт": time.Friday, "сб": time.Saturday,
+}
+
+func parseWeekday(s string) (time.Weekday, bool) {
+	s = strings.ToLower(s)
+	if day, ok := weekdayNames[s]; ok {
+		return day, true
+	}
+	if len([]rune(s)) > 3 {
+		if day, ok := weekdayNames[string([]rune(s)[:3])]; ok {
+			return day, true
+		}
+	}
+	if n, err := strconv.Atoi(s); err == nil && n >= 1 && n <= 7 {
+		return time.Weekday(n % 7), true
+	}
+	return 0, false
+}
+
+func weekdayName(lang string, day time.Weekday) string {
+	return tr(lang, fmt.Sprintf("weekday.%d", day))
+}
+
+type ranked struct {
+	name  string
+	count int
+}
+
+func topOf(counts map[string]int, n int) []ranked {
+	out := make([]ranked, 0, len(counts))
+	for name, count := range counts {
+		out = append(out, ranked{name, count})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].count != out[j].count {
+			return out[i].count > out[j].count
+		}
+		return out[i].name < out[j].name
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
+
+func weeklyOf(chatID int64) *Weekly {
+	if s := data.Chats[chatID]; s != nil {
+		return s.Weekly
+	}
+	return nil
+}
+
+func chatSubscriptions(chatID int64) int {
+	subs := 0
+	for _, tag := range tagService.InChat(chatID) {
+		subs += len(tag.Subscribers)
+	}
+	return subs
+}
+
+func weeklyReport(chatID int64, now time.Time) (string, bool) {
+	lang := chatLang(chatID)
+	since := now.Add(-7 * 24 * time.Hour)
+	tags, pingers := map[string]int{}, map[string]int{}
+	for _, ev := range data.Mentions {
+		if ev.ChatID != chatID || !ev.At.After(since) {
+			continue
+		}
+		tags["#"+ev.Tag]++
+		name := ev.Username
+		if name == "" {
+			name = fmt.Sprintf("User%d", ev.UserID)
+		}
+		pingers[name]++
+	}
+	var fresh []string
+	for _, tag := range tagService.InChat(chatID) {
+		if tag.CreatedAt.After(since) {
+			fresh = append(fresh, "#"+tag.Name)
+		}
+	}
+	sort.Strings(fresh)
+	if len(tags) == 0 && len(fresh) == 0 {
+		return "", false
+	}
+
+	var b strings.Builder
+	b.WriteString(tr(lang, "weekly.header", since.In(chatLocation(chatID)).Format("02.01"), now.In(chatLocation(chatID)).Format("02.01")))
+	if len(tags) > 0 {
+		b.WriteString(tr(lang, "weekly.top_tags"))
+		for i, r := range topOf(tags, weeklyTop) {
+			b.WriteString(fmt.Sprintf("%d. <code>%s</code> — %d\n", i+1, esc(r.name), r.count))
+		}
+	}
+	if len(fresh) > 0 {
+		b.WriteString(tr(lang, "weekly.new_tags", esc(strings.Join(fresh, ", "))))
+	}
+	subs := chatSubscriptions(chatID)
+	if w := weeklyOf(chatID); w != nil && !w.LastSent.IsZero() {
+		b.WriteString(tr(lang, "weekly.subs_delta", subs, fmt.Sprintf("%+d", subs-w.LastSubs)))
+	} else {
+		b.WriteString(tr(lang, "weekly.subs", subs))
+	}
+	if len(pingers) > 0 {
+		b.WriteString(tr(lang, "weekly.top_pingers"))
+		for i, r := range topOf(pingers, 3) {
+			b.WriteString(fmt.Sprintf("%d. %s — %d\n", i+1, esc(r.name), r.count))
+		}
+	}
+	return b.String(), true
+}
+
+func sendWeeklyReports(b *tele.Bot) {
+	now := time.Now()
+	for chatID, s := range data.Chats {
+		w := s.Weekly
+		if w == nil || s.RemovedAt != nil {
+			continue
+		}
+		local := now.In(chatLocation(chatID))
+		if local.Weekday() != w.Weekday || local.Hour() != w.Hour || now.Sub(w.LastSent) < 24*time.Hour {
+			continue
+		}
+		if err := loadChats(chatID); err != nil {
+			slog.Warn("Не удалось отправить недельную сводку", "chat_id", chatID, "err", err)
+			continue
+		}
+		if text, ok := weeklyReport(chatID, now); ok {
+			id := chatID
+			sendQueue.enqueue(context.Background(), &tele.Chat{ID: id}, text, func(_ *tele.Message, err error) {
+				if err != nil {
+					slog.Warn("Не удалось отправить недельную сводку", "chat_id", id, "err", err)
+				}
+			})
+		}
+		w.LastSent = now
+		w.LastSubs = chatSubscriptions(chatID)
+		saveData()
+	}
+}
+
+func handleWeekly(c tele.Context) error {
+	if c.Chat().Type == tele.ChatPrivate {
+		return c.Send(T(c, "weekly.groups_only"))
+	}
+	chatID := c.Chat().ID
+	args := strings.Fields(c.Text())[1:]
+	if len(args) == 0 {
+		if w := weeklyOf(chatID); w != nil {
+			return c.Send(T(c, "weekly.status_on", weekdayName(langOf(c), w.Weekday), w.Hour))
+		}
+		return c.Send(T(c, "weekly.status_off"))
+	}
+	if !isChatAdmin(c, chatID) {
+		return c.Send(T(c, "weekly.denied"))
+	}
+	switch strings.ToLower(args[0]) {
+	case "off":
+		chatSettings(chatID).Weekly = nil
+		saveData()
+		return c.Send(T(c, "weekly.disabled"))
+	case "now":
+		text, ok := weeklyReport(chatID, time.Now())
+		if !ok {
+			return c.Send(T(c, "weekly.empty"))
+		}
+		return c.Send(text)
+	case "on":
+	default:
+		return c.Send(T(c, "weekly.usage"))
+	}
+	day, hour := weeklyDefaultDay, weeklyDefaultHour
+	if len(args) > 1 {
+		var ok bool
+		if day, ok = parseWeekday(args[1]); !ok {
+			return c.Send(T(c, "weekly.usage"))
+		}
+	}
+	if len(args) > 2 {
+		h, err := strconv.Atoi(strings.TrimSuffix(args[2], ":00"))
+		if err != nil || h < 0 || h > 23 {
+			return c.Send(T(c, "weekly.usage"))
+		}
+		hour = h
+	}
+	s := chatSettings(chatID)
+	if s.Weekly == nil {
+		s.Weekly = &Weekly{LastSubs: chatSubscriptions(chatID)}
+	}
+	s.Weekly.Weekday, s.Weekly.Hour = day, hour
+	saveData()
+	return c.Send(T(c, "weekly.enabled", weekdayName(langOf(c), day), hour, esc(chatLocation(chatID).String())))
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	tele "gopkg.in/telebot.v3"
+)
+
+var (
+	btnWelcomeCreate   = tele.Btn{Unique: "welcome_create"}
+	btnWelcomeSettings = tele.Btn{Unique: "welcome_settings"}
+)
+
+func initChat(c tele.Context) {
+	s := chatSettings(c.Chat().ID)
+	if s.Lang == "" && c.Sender() != nil {
+		if _, ok := catalogs[c.Sender().LanguageCode]; ok {
+			s.Lang = c.Sender().LanguageCode
+			c.Set(langKey, s.Lang)
+		}
+	}
+	saveData()
+}
+
+func sendWelcome(c tele.Context) error {
+	initChat(c)
+	markup := &tele.ReplyMarkup{}
+	markup.Inline(markup.Row(
+		markup.Data(T(c, "welcome.create"), btnWelcomeCreate.Unique),
+		markup.Data(T(c, "welcome.settings"), btnWelcomeSettings.Unique),
+	))
+	return c.Send(T(c, "welcome.text"), markup)
+}
+
+func onWelcomeCreate(c tele.Context) error {
+	c.Respond()
+	return c.Send(T(c, "welcome.create_hint", noSubscribeFlag))
+}
+
+func onWelcomeSettings(c tele.Context) error {
+	c.Respond()
+	chatID := c.Chat().ID
+	spam := T(c, "spam.off_word")
+	if limits := spamLimits(chatID); !limits.Off {
+		spam = T(c, "welcome.spam_limit", limits.Pings, limits.Window)
+	}
+	webhook := T(c, "spam.off_word")
+	if s := data.Chats[chatID]; s != nil && s.Webhook != nil {
+		webhook = T(c, "spam.on_word")
+	}
+	return c.Send(T(c, "welcome.settings_text", T(c, "lang.name"), esc(chatLocation(chatID).String()),
+		spam, capText(c, dailyTagCap(chatID)), webhook))
+}